@@ -2,9 +2,15 @@
 package bangs
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
@@ -15,6 +21,10 @@ import (
 type Bangs struct {
 	Bangs []Bang `mapstructure:"bang"`
 	Suggester
+	mu       sync.RWMutex
+	base     []Bang // ours, before the operator's bangs are added
+	custom   []Bang // the user's, which aren't in the Suggester
+	modified map[string]time.Time
 }
 
 // Bang holds a single !bang
@@ -23,7 +33,7 @@ type Bang struct {
 	FavIcon   string            `json:"favicon"`
 	Triggers  []string          `json:"triggers"`
 	Regions   map[string]string `json:"regions"`
-	Functions []string          `json:"-"`
+	Functions []string          `json:"functions,omitempty"`
 	Funcs     []fn              `json:"-"`
 }
 
@@ -72,6 +82,211 @@ func viperStructTag() viper.DecoderConfigOption {
 	}
 }
 
+// All is every !bang, including any custom ones
+func (b *Bangs) All() []Bang {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.Bangs
+}
+
+// MaxCustom is the most custom !bangs a user can have
+const MaxCustom = 25
+
+// maxEncoded keeps encoded custom !bangs small enough for a cookie
+const maxEncoded = 3000
+
+// Override puts the custom !bangs ahead of the others.
+// A custom !bang takes its triggers from the !bangs that had them
+// and a !bang left without triggers is dropped.
+func Override(bngs, custom []Bang) []Bang {
+	taken := map[string]bool{}
+	for _, c := range custom {
+		for _, t := range c.Triggers {
+			taken[t] = true
+		}
+	}
+
+	merged := append([]Bang{}, custom...)
+	for _, bng := range bngs {
+		triggers := []string{}
+		for _, t := range bng.Triggers {
+			if !taken[t] {
+				triggers = append(triggers, t)
+			}
+		}
+
+		if len(triggers) == 0 {
+			continue
+		}
+
+		bng.Triggers = triggers
+		merged = append(merged, bng)
+	}
+
+	return merged
+}
+
+// With is a copy of the !bangs with a user's custom !bangs
+func (b *Bangs) With(custom []Bang) *Bangs {
+	return &Bangs{
+		Bangs:     Override(b.All(), custom),
+		Suggester: b.Suggester,
+		custom:    custom,
+	}
+}
+
+// Validate checks a custom !bang
+func (bng Bang) Validate() error {
+	if p := bng.problems(); len(p) > 0 {
+		return fmt.Errorf("%q %v", bng.Name, p[0])
+	}
+
+	return nil
+}
+
+// validate checks custom !bangs and that they don't share a trigger
+func validate(custom []Bang) error {
+	seen := map[string]bool{}
+	for _, c := range custom {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		for _, t := range c.Triggers {
+			if seen[t] {
+				return fmt.Errorf("duplicate trigger %q", t)
+			}
+			seen[t] = true
+		}
+	}
+
+	return nil
+}
+
+// Encode validates a user's custom !bangs and encodes them for a url or cookie
+func Encode(custom []Bang) (string, error) {
+	if len(custom) > MaxCustom {
+		return "", fmt.Errorf("at most %d custom !bangs", MaxCustom)
+	}
+
+	if err := validate(custom); err != nil {
+		return "", err
+	}
+
+	j, err := json.Marshal(custom)
+	if err != nil {
+		return "", err
+	}
+
+	s := base64.RawURLEncoding.EncodeToString(j)
+	if len(s) > maxEncoded {
+		return "", fmt.Errorf("custom !bangs are too long")
+	}
+
+	return s, nil
+}
+
+// Decode decodes and validates a user's custom !bangs
+func Decode(s string) ([]Bang, error) {
+	if len(s) > maxEncoded {
+		return nil, fmt.Errorf("custom !bangs are too long")
+	}
+
+	j, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := []Bang{}
+	if err := json.Unmarshal(j, &custom); err != nil {
+		return nil, err
+	}
+
+	if len(custom) > MaxCustom {
+		return nil, fmt.Errorf("at most %d custom !bangs", MaxCustom)
+	}
+
+	if err := validate(custom); err != nil {
+		return nil, err
+	}
+
+	// the functions are only names until now
+	b := &Bangs{Bangs: custom}
+	return custom, b.CreateFunctions()
+}
+
+// Load adds the !bangs of the operator's toml files in dir, overriding ours,
+// and tells us if the files changed since the last Load.
+// We keep the old !bangs if the new ones have an error.
+func (b *Bangs) Load(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	modified := map[string]time.Time{}
+	names := []string{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".toml" {
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			return false, err
+		}
+
+		modified[e.Name()] = fi.ModTime()
+		names = append(names, e.Name())
+	}
+
+	b.mu.RLock()
+	changed := len(modified) != len(b.modified)
+	for name, t := range modified {
+		if !b.modified[name].Equal(t) {
+			changed = true
+		}
+	}
+	b.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	sort.Strings(names)
+
+	custom := []Bang{}
+	for _, name := range names {
+		vb := viper.New()
+		vb.SetConfigFile(filepath.Join(dir, name))
+
+		c, err := New(vb)
+		if err != nil {
+			return false, fmt.Errorf("%v: %v", name, err)
+		}
+
+		if err := c.CreateFunctions(); err != nil {
+			return false, fmt.Errorf("%v: %v", name, err)
+		}
+
+		custom = append(custom, c.Bangs...)
+	}
+
+	if err := validate(custom); err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.base == nil {
+		b.base = b.Bangs
+	}
+	b.Bangs, b.modified = Override(b.base, custom), modified
+
+	return true, nil
+}
+
 // Suggest is an autocomplete for !bangs
 func (b *Bangs) Suggest(term string, size int) (Results, error) {
 	res, err := b.Suggester.SuggestResults(term, size)
@@ -79,9 +294,36 @@ func (b *Bangs) Suggest(term string, size int) (Results, error) {
 		return res, err
 	}
 
+	// the user's !bangs aren't in the index
+	if len(b.custom) > 0 {
+		t := strings.ToLower(strings.TrimPrefix(term, "!"))
+		suggestions := []Suggestion{}
+		seen := map[string]bool{}
+		for _, c := range b.custom {
+			for _, trigger := range c.Triggers {
+				if strings.HasPrefix(trigger, t) && !seen[trigger] {
+					suggestions = append(suggestions, Suggestion{Trigger: trigger})
+					seen[trigger] = true
+				}
+			}
+		}
+
+		for _, s := range res.Suggestions {
+			if !seen[s.Trigger] {
+				suggestions = append(suggestions, s)
+				seen[s.Trigger] = true
+			}
+		}
+
+		if len(suggestions) > size {
+			suggestions = suggestions[:size]
+		}
+		res.Suggestions = suggestions
+	}
+
 	// fill in the rest of the suggestion
 	for i, s := range res.Suggestions {
-		for _, bng := range b.Bangs {
+		for _, bng := range b.All() {
 			for _, trigger := range bng.Triggers {
 				if trigger == s.Trigger {
 					s.Name = bng.Name
@@ -105,7 +347,7 @@ func (b *Bangs) Detect(q string, region language.Region, l language.Tag) (Bang,
 		}
 
 		k := strings.ToLower(strings.Trim(field, "!"))
-		for _, bng := range b.Bangs {
+		for _, bng := range b.All() {
 			if triggered := trigger(k, bng.Triggers); !triggered {
 				continue
 			}
@@ -118,8 +360,7 @@ func (b *Bangs) Detect(q string, region language.Region, l language.Tag) (Bang,
 
 			for _, reg := range []string{strings.ToLower(region.String()), def} { // use default region if no region specified
 				if u, ok := bng.Regions[reg]; ok {
-					u = strings.Replace(u, "{{{term}}}", url.QueryEscape(remainder), -1)
-					return bng, strings.Replace(u, "{{{lang}}}", l.String(), -1), true
+					return bng, expand(u, remainder, region, l), true
 				}
 			}
 		}
@@ -150,12 +391,8 @@ func trigger(k string, triggers []string) bool {
 func (b *Bangs) CreateFunctions() error {
 	for i, bng := range b.Bangs {
 		for _, f := range bng.Functions {
-			var ff fn
-
-			switch f {
-			case "wikipediaCanonical":
-				ff = wikipediaCanonical
-			default:
+			ff, ok := functions[f]
+			if !ok {
 				return fmt.Errorf("unknown function string %v", f)
 			}
 			b.Bangs[i].Funcs = append(b.Bangs[i].Funcs, ff)
@@ -1,8 +1,11 @@
 package bangs
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"golang.org/x/text/language"
@@ -204,6 +207,21 @@ func TestDetect(t *testing.T) {
 				ok:  true,
 			},
 		},
+		{
+			q: "!sbb zürich bern", r: "CH", l: language.German,
+			want: data{
+				b: Bang{
+					Name:     "SBB",
+					FavIcon:  "http://fahrplan.sbb.ch/hafas-res/fahrplan/img/sbb/favicon.ico",
+					Triggers: []string{"sbb"},
+					Regions: map[string]string{
+						"default": "http://fahrplan.sbb.ch/bin/query.exe/dn?S={{{term1}}}&Z={{{term2}}}",
+					},
+				},
+				loc: "http://fahrplan.sbb.ch/bin/query.exe/dn?S=z%C3%BCrich&Z=bern",
+				ok:  true,
+			},
+		},
 		{
 			q: "nonexistent! some query", r: "US", l: language.French,
 			want: data{
@@ -261,6 +279,229 @@ func TestDetect(t *testing.T) {
 	}
 }
 
+var mdn = Bang{
+	Name:     "MDN",
+	FavIcon:  "https://developer.mozilla.org/favicon.ico",
+	Triggers: []string{"mdn", "g"},
+	Regions: map[string]string{
+		"default": "https://developer.mozilla.org/search?q={{{term}}}",
+	},
+}
+
+func TestOverride(t *testing.T) {
+	bngs := []Bang{
+		{Name: "Google", Triggers: []string{"g", "google"}},
+		{Name: "Google Images", Triggers: []string{"gi"}},
+		{Name: "Gmail", Triggers: []string{"gmail"}},
+	}
+
+	custom := []Bang{
+		{Name: "MDN", Triggers: []string{"mdn", "g"}},
+		{Name: "My Mail", Triggers: []string{"gmail"}},
+	}
+
+	got := Override(bngs, custom)
+
+	want := []Bang{
+		{Name: "MDN", Triggers: []string{"mdn", "g"}},
+		{Name: "My Mail", Triggers: []string{"gmail"}},
+		{Name: "Google", Triggers: []string{"google"}},
+		{Name: "Google Images", Triggers: []string{"gi"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	if !reflect.DeepEqual(bngs[0].Triggers, []string{"g", "google"}) {
+		t.Fatal("the original !bangs should not change")
+	}
+}
+
+func TestWith(t *testing.T) {
+	b, err := fromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Suggester = &mockSuggester{}
+
+	u := b.With([]Bang{mdn})
+
+	bng, loc, ok := u.Detect("!g flexbox", language.MustParseRegion("US"), language.English)
+	if !ok || bng.Name != "MDN" || loc != "https://developer.mozilla.org/search?q=flexbox" {
+		t.Fatalf("got %v %q %t", bng.Name, loc, ok)
+	}
+
+	// everyone else still gets Google
+	if bng, _, _ := b.Detect("!g flexbox", language.MustParseRegion("US"), language.English); bng.Name != "Google" {
+		t.Fatalf("got %v; want Google", bng.Name)
+	}
+
+	if bng, _, _ := u.Detect("!google flexbox", language.MustParseRegion("US"), language.English); bng.Name != "Google" {
+		t.Fatalf("got %v; want Google", bng.Name)
+	}
+
+	got, err := u.Suggest("m", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Results{Suggestions: []Suggestion{
+		{"mdn", "MDN", "https://developer.mozilla.org/favicon.ico"},
+		{"g", "MDN", "https://developer.mozilla.org/favicon.ico"},
+		{"gfr", "Google France", "https://www.google.com/favicon.ico"},
+	}}
+
+	// the mock suggests "g..." for everything
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	s, err := Encode([]Bang{mdn})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, []Bang{mdn}) {
+		t.Fatalf("got %+v; want %+v", got, []Bang{mdn})
+	}
+
+	for _, c := range []struct {
+		name string
+		bng  func(b *Bang)
+	}{
+		{"name", func(b *Bang) { b.Name = "" }},
+		{"triggers", func(b *Bang) { b.Triggers = nil }},
+		{"trigger", func(b *Bang) { b.Triggers = []string{"!mdn"} }},
+		{"uppercase", func(b *Bang) { b.Triggers = []string{"MDN"} }},
+		{"default", func(b *Bang) { b.Regions = map[string]string{"us": mdn.Regions[def]} }},
+		{"scheme", func(b *Bang) { b.Regions = map[string]string{def: "javascript:alert({{{term}}})"} }},
+		{"term", func(b *Bang) { b.Regions = map[string]string{def: "https://developer.mozilla.org"} }},
+		{"function", func(b *Bang) { b.Functions = []string{"titleCase"} }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			bng := mdn
+			bng.Regions = map[string]string{def: mdn.Regions[def]}
+			c.bng(&bng)
+
+			if _, err := Encode([]Bang{bng}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	if _, err := Encode([]Bang{mdn, mdn}); err == nil {
+		t.Fatal("expected an error for duplicate triggers")
+	}
+
+	if _, err := Decode("not base64!"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// custom !bangs keep their functions
+func TestEncodeFunctions(t *testing.T) {
+	wiki := Bang{
+		Name:      "Our Wiki",
+		FavIcon:   "https://wiki.example.com/favicon.ico",
+		Triggers:  []string{"ourwiki"},
+		Regions:   map[string]string{def: "https://wiki.example.com/{{{term}}}"},
+		Functions: []string{"slugify"},
+	}
+
+	s, err := Encode([]Bang{wiki})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	custom, err := Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := (&Bangs{}).With(custom)
+	_, loc, ok := b.Detect("!ourwiki Bob Dylan: Blonde on Blonde", language.MustParseRegion("US"), language.English)
+	if !ok {
+		t.Fatal("expected a !bang")
+	}
+
+	if want := "https://wiki.example.com/bob-dylan-blonde-on-blonde"; loc != want {
+		t.Fatalf("got %q; want %q", loc, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	b, err := fromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	f := filepath.Join(dir, "ours.toml")
+	if err := os.WriteFile(f, []byte(`
+[[bang]]
+    name = "Our Wiki"
+    favicon = "https://wiki.example.com/favicon.ico"
+    triggers = ["w", "ourwiki"]
+    [bang.regions]
+        default = "https://wiki.example.com/search?q={{{term}}}"
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false} {
+		changed, err := b.Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if changed != want {
+			t.Fatalf("load %d: got changed %t; want %t", i, changed, want)
+		}
+	}
+
+	bng, loc, _ := b.Detect("!w bob", language.MustParseRegion("US"), language.English)
+	if bng.Name != "Our Wiki" || loc != "https://wiki.example.com/search?q=bob" {
+		t.Fatalf("got %v %q", bng.Name, loc)
+	}
+
+	// a broken file keeps the old !bangs
+	if err := os.WriteFile(f, []byte("[[bang]]\nname = \"Broken\""), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(f, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Load(dir); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if bng, _, _ := b.Detect("!w bob", language.MustParseRegion("US"), language.English); bng.Name != "Our Wiki" {
+		t.Fatalf("got %v; want Our Wiki", bng.Name)
+	}
+
+	// removing it brings back ours
+	if err := os.Remove(f); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if bng, _, _ := b.Detect("!w bob", language.MustParseRegion("US"), language.English); bng.Name != "Wikipedia" {
+		t.Fatalf("got %v; want Wikipedia", bng.Name)
+	}
+}
+
 type mockSuggester struct{}
 
 func (m *mockSuggester) SuggestResults(term string, size int) (Results, error) {
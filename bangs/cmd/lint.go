@@ -0,0 +1,94 @@
+// Command lint checks !bangs catalogs and reports every issue at once.
+//
+//	lint [bangs.toml|directory...]
+//
+// It defaults to bangs/bangs.toml. The toml files of a directory (e.g. the
+// operator's bangs.dir) are checked together as they are loaded together.
+// Each issue is reported with the file of its !bang.
+// It exits with a status of 1 if there are any issues.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"github.com/spf13/viper"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		args = []string{filepath.Join("bangs", "bangs.toml")}
+	}
+
+	n := 0
+	for _, arg := range args {
+		bngs, files, err := load(arg)
+		if err != nil {
+			return err
+		}
+
+		for _, issue := range bangs.Lint(bngs) {
+			fmt.Fprintf(w, "%v: %v\n", files[issue.Bang], issue)
+			n++
+		}
+	}
+
+	if n > 0 {
+		return fmt.Errorf("%d issues", n)
+	}
+
+	return nil
+}
+
+// load reads the !bangs of a file or the toml files of a directory
+// and which file each !bang (by name) is in
+func load(path string) ([]bangs.Bang, map[string]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.toml")); err != nil {
+			return nil, nil, err
+		}
+
+		if len(files) == 0 {
+			return nil, nil, errors.New("no toml files in " + path)
+		}
+	}
+
+	bngs := []bangs.Bang{}
+	in := map[string]string{}
+	for _, f := range files {
+		vb := viper.New()
+		vb.SetConfigFile(f)
+
+		b, err := bangs.New(vb)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", f, err)
+		}
+
+		for _, bng := range b.Bangs {
+			if _, ok := in[bng.Name]; !ok {
+				in[bng.Name] = f
+			}
+		}
+
+		bngs = append(bngs, b.Bangs...)
+	}
+
+	return bngs, in, nil
+}
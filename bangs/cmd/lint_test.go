@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, toml := range map[string]string{
+		"ours.toml": `
+[[bang]]
+    name = "Our Wiki"
+    favicon = "https://wiki.example.com/favicon.ico"
+    triggers = ["w"]
+    [bang.regions]
+        default = "https://wiki.example.com/search?q={{{term}}}"
+`,
+		"more.toml": `
+[[bang]]
+    name = "Our Other Wiki"
+    favicon = "//wiki.example.org/favicon.ico"
+    triggers = ["w"]
+    [bang.regions]
+        default = "https://wiki.example.org/search?q={{{term}}}"
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(toml), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := run([]string{dir}, &buf); err == nil || err.Error() != "2 issues" {
+		t.Fatalf("got %v; want 2 issues", err)
+	}
+
+	// each issue names the file of its !bang
+	more := filepath.Join(dir, "more.toml")
+	want := more + `: "Our Other Wiki": has an invalid favicon "//wiki.example.org/favicon.ico"` + "\n" +
+		more + `: "Our Other Wiki": shares trigger "w" with Our Wiki` + "\n"
+
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := run([]string{filepath.Join("..", "bangs.toml")}, &buf); err != nil {
+		t.Fatalf("%v\n%v", err, buf.String())
+	}
+
+	if err := run([]string{filepath.Join(dir, "missing")}, &buf); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/olivere/elastic/v7"
 )
 
@@ -86,15 +87,28 @@ func (e *ElasticSearch) IndexExists() (bool, error) {
 	return e.Client.IndexExists(e.Index).Do(context.TODO())
 }
 
-// DeleteIndex will delete the existing index
+// DeleteIndex will delete the existing index (and its alias)
 func (e *ElasticSearch) DeleteIndex() error {
-	_, err := e.Client.DeleteIndex(e.Index).Do(context.TODO())
+	idx, _, err := alias.Current(e.Client, e.Index)
+	if err != nil || idx == "" {
+		return err
+	}
+
+	_, err = e.Client.DeleteIndex(idx).Do(context.TODO())
 	return err
 }
 
-// Setup recreates the completion index
+// Setup recreates the completion index.
+// The !bangs go into a new version of the index which then replaces the current one.
+// The old versions are deleted as the !bangs come from our config and there is nothing to roll back to.
 func (e *ElasticSearch) Setup(bangs []Bang) error {
-	if _, err := e.Client.CreateIndex(e.Index).Body(e.mapping()).Do(context.TODO()); err != nil {
+	current, n, err := alias.Current(e.Client, e.Index)
+	if err != nil {
+		return err
+	}
+
+	idx, err := alias.Next(e.Client, e.Index, e.mapping(), n+1)
+	if err != nil {
 		return err
 	}
 
@@ -107,7 +121,7 @@ func (e *ElasticSearch) Setup(bangs []Bang) error {
 			}
 
 			_, err := e.Client.Index().
-				Index(e.Index).
+				Index(idx).
 				BodyJson(&q).
 				Do(context.TODO())
 
@@ -117,5 +131,10 @@ func (e *ElasticSearch) Setup(bangs []Bang) error {
 		}
 	}
 
-	return nil
+	if err := alias.Swap(e.Client, e.Index, current, idx); err != nil {
+		return err
+	}
+
+	_, err = alias.Cleanup(e.Client, e.Index)
+	return err
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
@@ -196,9 +197,14 @@ func TestDeleteIndex(t *testing.T) {
 			}))
 			defer ts.Close()
 
-			handler = func(w http.ResponseWriter, _ *http.Request) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				resp := c.resp
+				if strings.HasSuffix(r.URL.Path, "/_alias") {
+					resp = `{"test-bangs-v1": {"aliases": {"test-bangs": {}}}}`
+				}
+
 				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
+				if _, err := w.Write([]byte(resp)); err != nil {
 					t.Fatal(err)
 				}
 			}
@@ -232,9 +238,14 @@ func TestSetup(t *testing.T) {
 			}))
 			defer ts.Close()
 
-			handler = func(w http.ResponseWriter, _ *http.Request) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				resp := c.resp
+				if strings.HasSuffix(r.URL.Path, "/_alias") {
+					resp = `{"test-bangs-v1": {"aliases": {"test-bangs": {}}}}`
+				}
+
 				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
+				if _, err := w.Write([]byte(resp)); err != nil {
 					t.Fatal(err)
 				}
 			}
@@ -256,6 +267,43 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+// a setup that died half way leaves the next version behind
+func TestSetupLeftover(t *testing.T) {
+	leftover, deleted := true, false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/test-bangs/_alias":
+			w.Write([]byte(`{"test-bangs-v1": {"aliases": {"test-bangs": {}}}}`))
+		case r.URL.Path == "/test-bangs-v2/_alias":
+			w.Write([]byte(`{"test-bangs-v2": {"aliases": {}}}`))
+		case r.URL.Path == "/test-bangs-v*/_alias":
+			w.Write([]byte(`{"test-bangs-v1": {"aliases": {"test-bangs": {}}}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/test-bangs-v2" && leftover:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"type": "resource_already_exists_exception"}, "status": 400}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/test-bangs-v2":
+			leftover, deleted = false, true
+			w.Write([]byte(`{"acknowledged": true}`))
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Setup([]Bang{{Name: "Google", Triggers: []string{"g"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !deleted {
+		t.Fatal("expected the leftover index to be deleted")
+	}
+}
+
 func MockService(url string) (*ElasticSearch, error) {
 	client, err := elastic.NewSimpleClient(elastic.SetURL(url))
 	if err != nil {
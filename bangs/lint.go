@@ -0,0 +1,131 @@
+package bangs
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Issue is a problem with a !bang
+type Issue struct {
+	Bang    string // its name
+	Problem string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%q: %v", i.Bang, i.Problem)
+}
+
+// Lint checks every !bang and reports all the issues at once, ordered by !bang
+func Lint(bngs []Bang) []Issue {
+	issues := []Issue{}
+
+	owners := map[string][]string{} // trigger -> the !bangs that use it
+	for _, bng := range bngs {
+		for _, p := range bng.problems() {
+			issues = append(issues, Issue{bng.Name, p})
+		}
+
+		if bng.FavIcon == "" {
+			issues = append(issues, Issue{bng.Name, "needs a favicon"})
+		} else if !inline(bng.FavIcon) && !absolute(bng.FavIcon) {
+			issues = append(issues, Issue{bng.Name, fmt.Sprintf("has an invalid favicon %q", bng.FavIcon)})
+		}
+
+		for _, t := range bng.Triggers {
+			owners[t] = append(owners[t], bng.Name)
+		}
+	}
+
+	for t, names := range owners {
+		if len(names) > 1 {
+			issues = append(issues, Issue{names[0], fmt.Sprintf("shares trigger %q with %v", t, strings.Join(names[1:], ", "))})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Bang != issues[j].Bang {
+			return issues[i].Bang < issues[j].Bang
+		}
+		return issues[i].Problem < issues[j].Problem
+	})
+
+	return issues
+}
+
+// problems are what's wrong with a single !bang
+func (bng Bang) problems() []string {
+	problems := []string{}
+
+	if strings.TrimSpace(bng.Name) == "" {
+		problems = append(problems, "needs a name")
+	}
+
+	if len(bng.Triggers) == 0 {
+		problems = append(problems, "needs a trigger")
+	}
+
+	for _, t := range bng.Triggers {
+		if t == "" || t != strings.ToLower(t) || strings.ContainsAny(t, "! \t") {
+			problems = append(problems, fmt.Sprintf("has an invalid trigger %q", t))
+		}
+	}
+
+	if _, ok := bng.Regions[def]; !ok {
+		problems = append(problems, "needs a default region")
+	}
+
+	regions := []string{}
+	for reg := range bng.Regions {
+		regions = append(regions, reg)
+	}
+	sort.Strings(regions)
+
+	for _, reg := range regions {
+		if reg != def {
+			// Detect looks regions up by their lowercase canonical code
+			r, err := language.ParseRegion(reg)
+			switch {
+			case err != nil || !r.IsCountry():
+				problems = append(problems, fmt.Sprintf("has an unknown region %q", reg))
+			case reg != strings.ToLower(r.Canonicalize().String()):
+				problems = append(problems, fmt.Sprintf("has region %q that should be %q", reg, strings.ToLower(r.Canonicalize().String())))
+			}
+		}
+
+		u := bng.Regions[reg]
+		if !hasTerm(u) {
+			problems = append(problems, fmt.Sprintf("needs {{{term}}} in its url for region %q", reg))
+		}
+
+		for _, p := range unknownPlaceholders(u) {
+			problems = append(problems, fmt.Sprintf("has an unknown placeholder %v in its url for region %q", p, reg))
+		}
+
+		if !absolute(expand(u, "term", language.MustParseRegion("US"), language.English)) {
+			problems = append(problems, fmt.Sprintf("has an invalid url for region %q", reg))
+		}
+	}
+
+	for _, f := range bng.Functions {
+		if _, ok := functions[f]; !ok {
+			problems = append(problems, fmt.Sprintf("has an unknown function %q", f))
+		}
+	}
+
+	return problems
+}
+
+// absolute indicates if u is an absolute http(s) url
+func absolute(u string) bool {
+	p, err := url.Parse(u)
+	return err == nil && (p.Scheme == "http" || p.Scheme == "https") && p.Host != ""
+}
+
+// inline indicates if u is an image in a data: url, as some favicons are
+func inline(u string) bool {
+	return strings.HasPrefix(u, "data:image/")
+}
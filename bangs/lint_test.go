@@ -0,0 +1,90 @@
+package bangs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLint(t *testing.T) {
+	bngs := []Bang{
+		{
+			Name:     "Google",
+			FavIcon:  "https://www.google.com/favicon.ico",
+			Triggers: []string{"g", "google"},
+			Regions: map[string]string{
+				"default": "https://encrypted.google.com/search?hl={{{lang}}}&q={{{term}}}",
+				"uk":      "https://www.google.co.uk/search?q={{{term}}}",
+				"xx":      "https://www.google.xx/search?q={{{term}}}",
+			},
+		},
+		{
+			Name:      "Wikipedia",
+			FavIcon:   "//en.wikipedia.org/favicon.ico",
+			Triggers:  []string{"w", "Wiki", "g"},
+			Regions:   map[string]string{"default": "https://en.wikipedia.org/wiki/"},
+			Functions: []string{"wikipediaCanonical", "titleCase"},
+		},
+		{
+			Name:     "Inline",
+			FavIcon:  "data:image/png;base64,iVBORw0KGgo=",
+			Triggers: []string{"inline"},
+			Regions:  map[string]string{"default": "https://inline.example.com/?q={{{term}}}"},
+		},
+		{
+			Name:     "Script",
+			FavIcon:  "data:text/html,<script>alert(1)</script>",
+			Triggers: []string{"script"},
+			Regions:  map[string]string{"default": "https://script.example.com/?q={{{term}}}"},
+		},
+		{
+			Name:     "Nowhere",
+			Triggers: []string{"no where"},
+			Regions:  map[string]string{"fr": "ftp://example.fr/{{{term}}}?p={{{page}}}"},
+		},
+	}
+
+	got := Lint(bngs)
+
+	want := []Issue{
+		{"Google", `has an unknown region "xx"`},
+		{"Google", `has region "uk" that should be "gb"`},
+		{"Google", `shares trigger "g" with Wikipedia`},
+		{"Nowhere", `has an invalid trigger "no where"`},
+		{"Nowhere", `has an invalid url for region "fr"`},
+		{"Nowhere", `has an unknown placeholder {{{page}}} in its url for region "fr"`},
+		{"Nowhere", "needs a default region"},
+		{"Nowhere", "needs a favicon"},
+		{"Script", `has an invalid favicon "data:text/html,<script>alert(1)</script>"`},
+		{"Wikipedia", `has an invalid favicon "//en.wikipedia.org/favicon.ico"`},
+		{"Wikipedia", `has an invalid trigger "Wiki"`},
+		{"Wikipedia", `has an unknown function "titleCase"`},
+		{"Wikipedia", `needs {{{term}}} in its url for region "default"`},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+// TestLintCatalog lints the !bangs we ship
+func TestLintCatalog(t *testing.T) {
+	for _, name := range []string{"bangs", "bangs.test"} {
+		t.Run(name, func(t *testing.T) {
+			vb := viper.New()
+			vb.SetConfigType("toml")
+			vb.SetConfigName(name)
+			vb.AddConfigPath("../bangs")
+
+			b, err := New(vb)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, issue := range Lint(b.Bangs) {
+				t.Error(issue)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package bangs
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// The placeholders of a !bang's url. A url needs at least one for the term.
+//
+//	{{{term}}}     the query, escaped for a query string ("at&t" -> "at%26t")
+//	{{{rawterm}}}  the query as is
+//	{{{pathterm}}} the query, escaped for a path ("a/b c" -> "a%2Fb%20c")
+//	{{{term1}}}    the first word of the query (escaped for a query string), {{{term2}}} the second...
+//	{{{lang}}}     the user's language, e.g. "en-US"
+//	{{{region}}}   the user's region, e.g. "US"
+var placeholder = regexp.MustCompile(`\{\{\{([a-z]+)(\d*)\}\}\}`)
+
+// expand fills in the placeholders of a url.
+// Unknown placeholders are left alone (see Lint).
+func expand(u, term string, region language.Region, l language.Tag) string {
+	words := strings.Fields(term)
+
+	return placeholder.ReplaceAllStringFunc(u, func(p string) string {
+		m := placeholder.FindStringSubmatch(p)
+		name, n := m[1], m[2]
+
+		if n != "" { // a word of the term
+			i, _ := strconv.Atoi(n)
+			switch {
+			case name != "term":
+				return p
+			case i < 1 || i > len(words):
+				return ""
+			}
+			return url.QueryEscape(words[i-1])
+		}
+
+		switch name {
+		case "term":
+			return url.QueryEscape(term)
+		case "rawterm":
+			return term
+		case "pathterm":
+			return url.PathEscape(term)
+		case "lang":
+			return l.String()
+		case "region":
+			return region.String()
+		}
+
+		return p
+	})
+}
+
+// unknownPlaceholders are the placeholders of a url we don't know
+func unknownPlaceholders(u string) []string {
+	unknown := []string{}
+	for _, m := range placeholder.FindAllStringSubmatch(u, -1) {
+		if m[2] != "" && m[1] == "term" {
+			continue
+		}
+
+		switch m[1] + m[2] {
+		case "term", "rawterm", "pathterm", "lang", "region":
+		default:
+			unknown = append(unknown, m[0])
+		}
+	}
+
+	return unknown
+}
+
+// hasTerm indicates if a url has a placeholder for the query
+func hasTerm(u string) bool {
+	for _, m := range placeholder.FindAllStringSubmatch(u, -1) {
+		if m[1] == "term" || m[1] == "rawterm" || m[1] == "pathterm" {
+			return true
+		}
+	}
+	return false
+}
+
+// functions are the transforms a !bang can apply to the query, in order,
+// before it goes in the url. E.g. functions = ["stripPunctuation", "slugify"]
+var functions = map[string]fn{
+	"wikipediaCanonical": wikipediaCanonical,
+	"lowercase":          strings.ToLower,
+	"slugify":            slugify,
+	"stripPunctuation":   stripPunctuation,
+	"pathEscape":         url.PathEscape, // use with {{{rawterm}}} so it isn't escaped twice
+}
+
+// slugify makes a url slug of the query.
+// "Bob Dylan: Blonde on Blonde" -> "bob-dylan-blonde-on-blonde"
+func slugify(q string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+}
+
+// stripPunctuation removes punctuation from the query.
+// "what's up, doc?" -> "whats up doc"
+func stripPunctuation(q string) string {
+	return strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return -1
+		}
+		return r
+	}, q)), " ")
+}
@@ -0,0 +1,61 @@
+package bangs
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestExpand(t *testing.T) {
+	for _, c := range []struct {
+		u    string
+		term string
+		want string
+	}{
+		{"https://example.com/?q={{{term}}}", "at&t stock", "https://example.com/?q=at%26t+stock"},
+		{"https://example.com/{{{rawterm}}}", "bob-dylan", "https://example.com/bob-dylan"},
+		{"https://example.com/wiki/{{{pathterm}}}", "AC/DC live", "https://example.com/wiki/AC%2FDC%20live"},
+		{"https://example.com/?from={{{term1}}}&to={{{term2}}}", "zürich bern", "https://example.com/?from=z%C3%BCrich&to=bern"},
+		{"https://example.com/?from={{{term1}}}&to={{{term2}}}", "zürich", "https://example.com/?from=z%C3%BCrich&to="},
+		{"https://example.com/?q={{{term}}}&hl={{{lang}}}&gl={{{region}}}", "bob", "https://example.com/?q=bob&hl=fr-CA&gl=CA"},
+		{"https://example.com/?q={{{term}}}&x={{{unknown}}}", "bob", "https://example.com/?q=bob&x={{{unknown}}}"},
+	} {
+		t.Run(c.u, func(t *testing.T) {
+			got := expand(c.u, c.term, language.MustParseRegion("CA"), language.CanadianFrench)
+			if got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestUnknownPlaceholders(t *testing.T) {
+	got := unknownPlaceholders("https://example.com/?q={{{term}}}&t={{{term2}}}&s={{{s2}}}&l={{{lang}}}&x={{{lang2}}}")
+	want := []string{"{{{s2}}}", "{{{lang2}}}"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		q    string
+		want string
+	}{
+		{"wikipediaCanonical", "bob maRLey", "Bob_Marley"},
+		{"lowercase", "Bob Dylan", "bob dylan"},
+		{"slugify", "Bob Dylan: Blonde on Blonde (1966)", "bob-dylan-blonde-on-blonde-1966"},
+		{"slugify", "Café  Müller", "café-müller"},
+		{"stripPunctuation", "what's up, doc?", "whats up doc"},
+		{"pathEscape", "AC/DC live", "AC%2FDC%20live"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := functions[c.name](c.q); got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
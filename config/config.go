@@ -66,6 +66,7 @@ func SetDefaults(cfg Provider) {
 	// Server
 	port := 8000
 	cfg.SetDefault("server.host", fmt.Sprintf("http://127.0.0.1:%d", port))
+	cfg.SetDefault("proxies", []string{"127.0.0.1", "::1"}) // the reverse proxies (IPs or CIDRs) we trust to set X-Real-IP
 
 	// Frontend Cache
 	cfg.SetDefault("cache.instant", 1*time.Second)
@@ -81,6 +82,10 @@ func SetDefaults(cfg Provider) {
 	cfg.SetDefault("elasticsearch.url", "https://127.0.0.1:9200")
 	cfg.SetDefault("elasticsearch.search.index", "test-search")
 	cfg.SetDefault("elasticsearch.search.type", "document")
+	cfg.SetDefault("elasticsearch.synonyms", "") // e.g. "analysis/synonyms.txt" in the Elasticsearch config directory
+
+	cfg.SetDefault("search.profile", "default") // ranking profile
+	cfg.SetDefault("search.profiles", "")       // JSON file of extra ranking profiles
 
 	cfg.SetDefault("elasticsearch.bangs.index", "test-bangs")
 	cfg.SetDefault("elasticsearch.bangs.type", "bang")
@@ -94,6 +99,22 @@ func SetDefaults(cfg Provider) {
 	cfg.SetDefault("elasticsearch.robots.index", "test-robots")
 	cfg.SetDefault("elasticsearch.robots.type", "robots")
 
+	// Autocomplete
+	cfg.SetDefault("suggest.k", 5)                         // distinct people before a query is suggested
+	cfg.SetDefault("suggest.window", 24*time.Hour)         // how often the tokens telling people apart rotate
+	cfg.SetDefault("suggest.secret", "")                   // key for those tokens. Share it between frontends. Blank is random.
+	cfg.SetDefault("suggest.ttl", 30*24*time.Hour)         // forget a query no one has made in this long
+	cfg.SetDefault("suggest.decay.factor", .9)             // scale the weights of the suggestions by this...
+	cfg.SetDefault("suggest.decay.interval", 24*time.Hour) // ...this often
+
+	// !bangs
+	cfg.SetDefault("bangs.dir", "")             // a directory of the operator's own !bangs (toml files like bangs/bangs.toml). They override ours.
+	cfg.SetDefault("bangs.reload", time.Minute) // how often we check it for changes
+
+	// Safety lists for autocomplete & safe search. See safety/safety.go for their format.
+	cfg.SetDefault("safety.lists", "safety/lists")
+	cfg.SetDefault("safety.reload", time.Minute) // how often we check the lists for changes
+
 	// PostgreSQL
 	// Note: there is a security concern if postgres password is stored in env variable
 	// but setting it as an env var w/in systemd nullifies this.
@@ -130,9 +151,17 @@ func SetDefaults(cfg Provider) {
 	cfg.SetDefault("crawler.truncate.keywords", 25)
 	cfg.SetDefault("crawler.truncate.description", 250)
 
+	// raw responses are archived to WARC files if a directory is set
+	cfg.SetDefault("crawler.warc.dir", "")
+	cfg.SetDefault("crawler.warc.max.bytes", 1<<30) // rotate files at 1GB
+	cfg.SetDefault("crawler.warc.gzip", true)
+
 	// image nsfw scoring and metadata
+	// metadata is extracted natively. Set nsfw.host to "" to skip the classifier server.
+	// Without it images aren't scored and safe search relies on their alt text.
 	cfg.SetDefault("nsfw.host", "http://127.0.0.1:8080")
 	cfg.SetDefault("nsfw.workers", 10)
+	cfg.SetDefault("nsfw.max.bytes", 10<<20) // 10MB
 	cfg.SetDefault("nsfw.since", now().AddDate(0, -1, 0))
 
 	// Tor
@@ -67,11 +67,15 @@ func TestSetDefaults(t *testing.T) {
 
 		// Server
 		{"server.host", fmt.Sprintf("http://127.0.0.1:%d", port)},
+		{"proxies", []string{"127.0.0.1", "::1"}},
 
 		// Elasticsearch
 		{"elasticsearch.url", "https://127.0.0.1:9200"},
 		{"elasticsearch.search.index", "test-search"},
 		{"elasticsearch.search.type", "document"},
+		{"elasticsearch.synonyms", ""},
+		{"search.profile", "default"},
+		{"search.profiles", ""},
 		{"elasticsearch.bangs.index", "test-bangs"},
 		{"elasticsearch.bangs.type", "bang"},
 		{"elasticsearch.image.index", "test-images"},
@@ -81,6 +85,18 @@ func TestSetDefaults(t *testing.T) {
 		{"elasticsearch.robots.index", "test-robots"},
 		{"elasticsearch.robots.type", "robots"},
 
+		// Autocomplete
+		{"suggest.k", 5},
+		{"suggest.window", 24 * time.Hour},
+		{"suggest.secret", ""},
+		{"suggest.ttl", 30 * 24 * time.Hour},
+		{"suggest.decay.factor", .9},
+		{"suggest.decay.interval", 24 * time.Hour},
+		{"bangs.dir", ""},
+		{"bangs.reload", time.Minute},
+		{"safety.lists", "safety/lists"},
+		{"safety.reload", time.Minute},
+
 		// PostgreSQL
 		{"postgresql.host", "localhost"},
 		{"postgresql.user", "jivesearch"},
@@ -110,12 +126,16 @@ func TestSetDefaults(t *testing.T) {
 		{"crawler.truncate.title", 100},
 		{"crawler.truncate.keywords", 25},
 		{"crawler.truncate.description", 250},
+		{"crawler.warc.dir", ""},
+		{"crawler.warc.max.bytes", 1 << 30},
+		{"crawler.warc.gzip", true},
 
 		// useragent for fetching api's, images, etc.
 		{"useragent", "https://github.com/jonesrussell/jivesearch"},
 
 		// image nsfw scoring and metadata
 		{"nsfw.workers", 10},
+		{"nsfw.max.bytes", 10 << 20},
 		{"nsfw.since", time.Date(2018, 01, 06, 20, 34, 58, 651387237, time.UTC)},
 
 		// Tor
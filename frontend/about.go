@@ -3,6 +3,8 @@ package frontend
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/jonesrussell/jivesearch/log"
 )
 
 // GitHub holds settings for GitHub's API
@@ -45,6 +47,10 @@ func (f *Frontend) aboutHandler(w http.ResponseWriter, r *http.Request) *respons
 		Onion:   f.Onion,
 	}
 
+	if err := applyPreferences(r); err != nil {
+		log.Debug.Println(err)
+	}
+
 	abt.setTheme(r)
 
 	resp := &response{
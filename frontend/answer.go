@@ -71,7 +71,7 @@ func (f *Frontend) answerHandler(w http.ResponseWriter, r *http.Request) *respon
 
 func (f *Frontend) getAnswer(r *http.Request, dd data, ic chan instant.Data) {
 	lang, _, _ := f.Wikipedia.Matcher.Match(dd.Context.Preferred...)
-	key := cacheKey("instant", lang, f.detectRegion(lang, r), r.URL)
+	key := cacheKey("instant", lang, f.detectRegion(lang, r), formURL(r))
 
 	v, err := f.Cache.Get(key)
 	if err != nil {
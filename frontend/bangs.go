@@ -0,0 +1,102 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"github.com/jonesrussell/jivesearch/log"
+)
+
+// bangsCookie holds a user's signed custom !bangs.
+// Only the cookie counts: a signed value in a link would let anyone
+// send someone's !g searches to a site of the link maker's choosing.
+const bangsCookie = "bangs"
+
+// userBangs are our !bangs along with the user's custom ones
+func (f *Frontend) userBangs(r *http.Request) *bangs.Bangs {
+	c, err := r.Cookie(bangsCookie)
+	if err != nil || c.Value == "" {
+		return f.Bangs
+	}
+
+	v, ok := verify("bangs", c.Value)
+	if !ok {
+		log.Debug.Println("custom !bangs have an invalid signature")
+		return f.Bangs
+	}
+
+	custom, err := bangs.Decode(v)
+	if err != nil {
+		log.Debug.Println(err)
+		return f.Bangs
+	}
+
+	return f.Bangs.With(custom)
+}
+
+// bangsHandler saves a user's custom !bangs (a json array in the "bangs" form value)
+// in a cookie. Only our own pages can save them (see sameOrigin).
+// With "preview" they are checked and returned without being saved. The signed
+// value we used to return for a "bangs" search param is gone as that param
+// is no longer honored: the cookie is the only way to use custom !bangs.
+func (f *Frontend) bangsHandler(w http.ResponseWriter, r *http.Request) *response {
+	if !sameOrigin(r) {
+		return &response{
+			status: http.StatusForbidden,
+			err:    fmt.Errorf("cross-site request to save !bangs"),
+		}
+	}
+
+	custom := []bangs.Bang{}
+	if err := json.Unmarshal([]byte(r.PostFormValue("bangs")), &custom); err != nil {
+		return &response{
+			status: http.StatusBadRequest,
+			err:    err,
+		}
+	}
+
+	c := &http.Cookie{
+		Name:     bangsCookie,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   f.secure(r),
+	}
+
+	// an empty list clears them
+	if len(custom) == 0 {
+		c.MaxAge = -1
+	} else {
+		v, err := bangs.Encode(custom)
+		if err != nil {
+			return &response{
+				status: http.StatusBadRequest,
+				err:    err,
+			}
+		}
+
+		s, err := sign("bangs", v)
+		if err != nil {
+			return &response{
+				status: http.StatusInternalServerError,
+				err:    err,
+			}
+		}
+
+		c.Value = s
+		c.Expires = time.Now().AddDate(1, 0, 0)
+	}
+
+	if r.PostFormValue("preview") == "" {
+		http.SetCookie(w, c)
+	}
+
+	return &response{
+		status:   http.StatusOK,
+		template: "json",
+		data:     map[string][]bangs.Bang{"bangs": custom},
+	}
+}
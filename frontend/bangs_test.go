@@ -0,0 +1,164 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"golang.org/x/text/language"
+)
+
+func TestUserBangs(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	f := &Frontend{
+		Bangs: &bangs.Bangs{
+			Bangs: []bangs.Bang{
+				{
+					Name:     "Google",
+					Triggers: []string{"g"},
+					Regions:  map[string]string{"default": "https://www.google.com/search?q={{{term}}}"},
+				},
+			},
+		},
+	}
+
+	v, err := bangs.Encode([]bangs.Bang{
+		{
+			Name:     "Mine",
+			FavIcon:  "https://example.org/favicon.ico",
+			Triggers: []string{"g"},
+			Regions:  map[string]string{"default": "https://example.org/?q={{{term}}}"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := sign("bangs", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name   string
+		url    string
+		cookie string
+		want   string
+	}{
+		{"ours", "/?q=!g+foo", "", "https://www.google.com/search?q=foo"},
+		{"cookie", "/?q=!g+foo", signed, "https://example.org/?q=foo"},
+		{"forged cookie", "/?q=!g+foo", v + ".forged", "https://www.google.com/search?q=foo"},
+		{"param", "/?q=!g+foo&bangs=" + url.QueryEscape(signed), "", "https://www.google.com/search?q=foo"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.url, nil)
+			if c.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: bangsCookie, Value: c.cookie})
+			}
+
+			_, loc, ok := f.userBangs(r).Detect(r.FormValue("q"), language.MustParseRegion("US"), language.English)
+			if !ok {
+				t.Fatal("expected a !bang")
+			}
+
+			if loc != c.want {
+				t.Fatalf("got %q; want %q", loc, c.want)
+			}
+		})
+	}
+}
+
+func TestBangsHandler(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	body := url.Values{
+		"bangs": {`[{"name": "Mine", "favicon": "https://example.org/favicon.ico", "triggers": ["mine"], "regions": {"default": "https://example.org/?q={{{term}}}"}}]`},
+	}.Encode()
+
+	for _, c := range []struct {
+		name   string
+		header string
+		value  string
+		status int
+		cookie bool
+	}{
+		{"ours", "Sec-Fetch-Site", "same-origin", http.StatusOK, true},
+		{"cross site", "Sec-Fetch-Site", "cross-site", http.StatusForbidden, false},
+		{"other origin", "Origin", "https://evil.example.org", http.StatusForbidden, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "https://www.example.com/bangs", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set(c.header, c.value)
+
+			w := httptest.NewRecorder()
+			f := &Frontend{}
+			appHandler(f.bangsHandler).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("got %d; want %d", w.Code, c.status)
+			}
+
+			if got := w.Header().Get("Set-Cookie") != ""; got != c.cookie {
+				t.Fatalf("got cookie %v; want %v", got, c.cookie)
+			}
+
+			// the request was over https
+			if got := w.Header().Get("Set-Cookie"); c.cookie && !strings.Contains(got, "; Secure") {
+				t.Fatalf("got %v; want a secure cookie", got)
+			}
+		})
+	}
+}
+
+func TestBangsHandlerCookie(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	mine := `[{"name": "Mine", "favicon": "https://example.org/favicon.ico", "triggers": ["mine"], "regions": {"default": "https://example.org/?q={{{term}}}"}}]`
+
+	for _, c := range []struct {
+		name string
+		body url.Values
+		want []string // in the cookie we set
+	}{
+		{"save", url.Values{"bangs": {mine}}, []string{"bangs=", "; HttpOnly", "; Secure", "; SameSite=Lax", "; Expires="}},
+		{"clear", url.Values{"bangs": {`[]`}}, []string{"bangs=;", "; Max-Age=0", "; HttpOnly", "; Secure", "; SameSite=Lax"}},
+		{"preview", url.Values{"bangs": {mine}, "preview": {"true"}}, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "https://www.example.com/bangs", strings.NewReader(c.body.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Sec-Fetch-Site", "same-origin")
+
+			w := httptest.NewRecorder()
+			f := &Frontend{}
+			appHandler(f.bangsHandler).ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("got %d; want %d", w.Code, http.StatusOK)
+			}
+
+			got := w.Header().Get("Set-Cookie")
+			if c.want == nil && got != "" {
+				t.Fatalf("got cookie %v; want none", got)
+			}
+
+			for _, s := range c.want {
+				if !strings.Contains(got, s) {
+					t.Fatalf("got %v; want %v in it", got, s)
+				}
+			}
+
+			if !strings.Contains(w.Body.String(), `"Mine"`) && c.name != "clear" {
+				t.Fatalf("got %v; want the !bangs back", w.Body.String())
+			}
+		})
+	}
+}
@@ -4,9 +4,9 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"path"
 	"strconv"
 	"strings"
 
@@ -44,6 +44,7 @@ import (
 	"github.com/jonesrussell/jivesearch/instant/timezone"
 	"github.com/jonesrussell/jivesearch/instant/wikipedia"
 	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/safety"
 	"github.com/jonesrussell/jivesearch/search"
 	"github.com/jonesrussell/jivesearch/search/document"
 	img "github.com/jonesrussell/jivesearch/search/image"
@@ -72,7 +73,8 @@ func setup(v *viper.Viper) *http.Server {
 			Logo:      v.GetString("brand.logo"),
 			SmallLogo: v.GetString("brand.small_logo"),
 		},
-		Onion: v.GetString("onion"),
+		Onion:   v.GetString("onion"),
+		Proxies: proxies(v),
 	}
 
 	router := f.Router(v)
@@ -83,6 +85,70 @@ func setup(v *viper.Viper) *http.Server {
 	}
 }
 
+// leaser lets the frontends take turns so a chore is done once, not once per frontend
+type leaser interface {
+	Lease(name string, d time.Duration) (bool, error)
+}
+
+// decay fades the autocomplete weights every interval.
+// With a leaser only the frontend holding the lease does.
+func decay(s suggest.Suggester, l leaser, factor float64, interval time.Duration) {
+	for range time.Tick(interval) {
+		if l != nil {
+			ok, err := l.Lease("decay", interval)
+			if err != nil {
+				log.Info.Println(err)
+				continue
+			}
+
+			if !ok { // another frontend's turn
+				continue
+			}
+		}
+
+		if err := s.Decay(factor); err != nil {
+			log.Info.Println(err)
+		}
+	}
+}
+
+// reload picks up changes to the safety lists every interval
+func reload(s *safety.Filter, interval time.Duration) {
+	for range time.Tick(interval) {
+		changed, err := s.Reload()
+		if err != nil {
+			log.Info.Println(err)
+			continue
+		}
+
+		if changed {
+			log.Info.Println("Reloaded the safety lists")
+		}
+	}
+}
+
+// reloadBangs picks up changes to the operator's !bangs every interval
+func reloadBangs(b *bangs.Bangs, dir string, interval time.Duration) {
+	for range time.Tick(interval) {
+		changed, err := b.Load(dir)
+		if err != nil {
+			log.Info.Println(err)
+			continue
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := b.Suggester.Setup(b.All()); err != nil {
+			log.Info.Println(err)
+			continue
+		}
+
+		log.Info.Println("Reloaded the !bangs")
+	}
+}
+
 func main() {
 	v := viper.New()
 	s := setup(v)
@@ -115,6 +181,7 @@ func main() {
 				Index:  v.GetString("elasticsearch.search.index"),
 				Type:   v.GetString("elasticsearch.search.type"),
 			},
+			Profile: profile(v),
 		}
 	}
 
@@ -130,22 +197,20 @@ func main() {
 			Index:         v.GetString("elasticsearch.images.index"),
 			Type:          v.GetString("elasticsearch.images.type"),
 			NSFWThreshold: .80,
+			Classifier:    v.GetString("nsfw.host") != "",
 		}
 	}
 
 	f.Images.Client = httpClient
 	f.MapBoxKey = v.GetString("mapbox.key")
 
-	// load naughty list
-	cwd, err := os.Getwd()
+	// safety lists for autocomplete & safe search
+	f.Safety, err = safety.New(v.GetString("safety.lists"))
 	if err != nil {
 		panic(err)
 	}
 
-	//parent := filepath.Dir(cwd)
-	if err := suggest.NewNaughty(path.Join(cwd, "./suggest/naughty.txt")); err != nil {
-		panic(err)
-	}
+	go reload(f.Safety, v.GetDuration("safety.reload"))
 
 	// !bangs
 	debug := v.GetBool("debug")
@@ -158,7 +223,7 @@ func main() {
 	}
 
 	// Print the current working directory
-	cwd, err = os.Getwd()
+	cwd, err := os.Getwd()
 	if err != nil {
 		foo.Fatal(err)
 	}
@@ -176,6 +241,14 @@ func main() {
 		panic(err)
 	}
 
+	// the operator's own !bangs
+	bangsDir := v.GetString("bangs.dir")
+	if bangsDir != "" {
+		if _, err := f.Bangs.Load(bangsDir); err != nil {
+			panic(err)
+		}
+	}
+
 	f.Cache.Instant = v.GetDuration("cache.instant")
 	f.Cache.Search = v.GetDuration("cache.search")
 
@@ -271,6 +344,8 @@ func main() {
 
 	f.ProxyClient = httpClient
 
+	var sightings suggest.Sightings
+
 	// use Jive Data when debuggin to make setup easier
 	switch debug {
 	case true:
@@ -282,6 +357,7 @@ func main() {
 		f.Bangs.Suggester = &bangs.Simple{}
 
 		f.Suggest = &suggest.Simple{}
+		sightings = &suggest.Memory{}
 
 		f.Instant.DiscographyFetcher = &musicbrainz.JiveData{
 			HTTPClient: httpClient,
@@ -336,6 +412,11 @@ func main() {
 			Type:   v.GetString("elasticsearch.query.type"),
 		}
 
+		sightings = &suggest.Redis{
+			RedisPool: rds.RedisPool,
+			TTL:       v.GetDuration("suggest.ttl"),
+		}
+
 		f.Instant.DiscographyFetcher = &musicbrainz.PostgreSQL{
 			DB: db,
 		}
@@ -367,23 +448,18 @@ func main() {
 	}
 
 	// setup !bangs suggester
-	exists, err := f.Bangs.Suggester.IndexExists()
-	if err != nil {
+	// always want to recreate to add any changes/new !bangs.
+	// The new index replaces the old one so there's no gap in suggestions.
+	if err := f.Bangs.Suggester.Setup(f.Bangs.All()); err != nil {
 		panic(err)
 	}
 
-	if exists { // always want to recreate to add any changes/new !bangs
-		if err := f.Bangs.Suggester.DeleteIndex(); err != nil {
-			panic(err)
-		}
-	}
-
-	if err := f.Bangs.Suggester.Setup(f.Bangs.Bangs); err != nil {
-		panic(err)
+	if bangsDir != "" {
+		go reloadBangs(f.Bangs, bangsDir, v.GetDuration("bangs.reload"))
 	}
 
 	// autocomplete & phrase suggestor
-	exists, err = f.Suggest.IndexExists()
+	exists, err := f.Suggest.IndexExists()
 	if err != nil {
 		panic(err)
 	}
@@ -394,11 +470,38 @@ func main() {
 		}
 	}
 
+	// only suggest the queries enough people made and let old ones fade
+	f.Anonymity, err = suggest.NewAnonymity(
+		sightings, v.GetInt("suggest.k"), v.GetDuration("suggest.window"), v.GetString("suggest.secret"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	var l leaser
+	if rds, ok := sightings.(*suggest.Redis); ok {
+		l = rds
+	}
+
+	go decay(f.Suggest, l, v.GetFloat64("suggest.decay.factor"), v.GetDuration("suggest.decay.interval"))
+
 	// wikipedia setup
 	if err := f.Instant.WikipediaFetcher.Setup(); err != nil {
 		log.Info.Println(err)
 	}
 
+	// expand queries with Wiktionary synonyms
+	if es, ok := f.Search.(*search.ElasticSearch); ok {
+		if pg, ok := f.Instant.WikipediaFetcher.(*wikipedia.PostgreSQL); ok {
+			es.Expander = pg
+		}
+	}
+
+	// tell people what their suggestions are about
+	if pg, ok := f.Instant.WikipediaFetcher.(*wikipedia.PostgreSQL); ok {
+		f.Entities = pg
+	}
+
 	// supported languages
 	supported, unsupported := languages(v)
 	for _, lang := range unsupported {
@@ -431,6 +534,56 @@ func esClient(v *viper.Viper, client *elastic.Client) *elastic.Client {
 	return client
 }
 
+// profile is the ranking profile named by search.profile
+func profile(v *viper.Viper) *search.Profile {
+	profiles := search.Profiles
+
+	if f := v.GetString("search.profiles"); f != "" {
+		r, err := os.Open(f)
+		if err != nil {
+			panic(err)
+		}
+		defer r.Close()
+
+		profiles, err = search.LoadProfiles(r)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	p, ok := profiles[v.GetString("search.profile")]
+	if !ok {
+		panic(fmt.Sprintf("unknown ranking profile %q", v.GetString("search.profile")))
+	}
+
+	return p
+}
+
+// proxies are the reverse proxies we trust to set X-Real-IP.
+// A single IP is its own network.
+func proxies(cfg config.Provider) []*net.IPNet {
+	nets := []*net.IPNet{}
+
+	for _, p := range cfg.GetStringSlice("proxies") {
+		if ip := net.ParseIP(p); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			log.Info.Printf("ignoring proxy %q: %v\n", p, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+
+	return nets
+}
+
 func languages(cfg config.Provider) ([]language.Tag, []language.Tag) {
 	supported := []language.Tag{}
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"net"
 	"reflect"
 	"testing"
 
@@ -64,3 +65,22 @@ func TestLanguages(t *testing.T) {
 		})
 	}
 }
+
+func TestProxies(t *testing.T) {
+	v := viper.New()
+	v.SetDefault("proxies", []string{"127.0.0.1", "::1", "10.0.0.0/8", "nginx"})
+
+	got := []string{}
+	for _, n := range proxies(v) {
+		got = append(got, n.String())
+	}
+
+	want := []string{"127.0.0.1/32", "::1/128", "10.0.0.0/8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	if !proxies(v)[2].Contains(net.ParseIP("10.1.2.3")) {
+		t.Fatal("expected 10.1.2.3 to be a proxy")
+	}
+}
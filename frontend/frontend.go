@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/jonesrussell/jivesearch/frontend/cache"
 	"github.com/jonesrussell/jivesearch/instant"
 	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/safety"
 	"github.com/jonesrussell/jivesearch/search"
 	img "github.com/jonesrussell/jivesearch/search/image"
 	"github.com/jonesrussell/jivesearch/suggest"
@@ -39,8 +41,12 @@ type Frontend struct {
 	*instant.Instant
 	MapBoxKey   string
 	Onion       string
+	Proxies     []*net.IPNet // trusted to tell us the client's IP in X-Real-IP
 	ProxyClient *http.Client
 	Suggest     suggest.Suggester
+	Anonymity   *suggest.Anonymity    // nil to suggest every query
+	Entities    suggest.EntityFetcher // nil for plain suggestions
+	Safety      *safety.Filter        // nil to filter nothing
 	Search      search.Fetcher
 	Wikipedia
 	GitHub
@@ -80,6 +86,7 @@ func init() {
 type response struct {
 	status   int
 	redirect string
+	post     map[string][]string // resubmit these params to redirect as a POST
 	template string
 	data     interface{}
 	err      error
@@ -97,8 +104,24 @@ func (f *Frontend) middleware(next appHandler) http.Handler {
 	})
 }
 
+// postForm is a page that resubmits a request as a POST
+type postForm struct {
+	Action string
+	Params map[string][]string
+}
+
 func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if rsp := fn(w, r); rsp != nil {
+		// The http spec indicates 3xx redirects cannot change the
+		// method (e.g. GET to POST) so we send a form that submits itself instead.
+		if rsp.post != nil {
+			rsp.status, rsp.template = http.StatusOK, "post"
+			rsp.data = postForm{
+				Action: rsp.redirect,
+				Params: rsp.post,
+			}
+		}
+
 		switch rsp.status {
 		case http.StatusOK:
 			buf := bufpool.Get()
@@ -113,6 +136,14 @@ func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					errHandler(w, rsp)
 					return
 				}
+			case "opensearch_suggestions":
+				w.Header().Set("Content-Type", "application/x-suggestions+json")
+				err := json.NewEncoder(buf).Encode(rsp.data)
+				if err != nil {
+					rsp.status, rsp.err = http.StatusInternalServerError, err
+					errHandler(w, rsp)
+					return
+				}
 			case "jsonp":
 				w.Header().Set("Content-Type", "application/javascript")
 				err := json.NewEncoder(buf).Encode(rsp.data)
@@ -170,29 +201,8 @@ func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				errHandler(w, rsp)
 			}
 		case http.StatusFound:
-			switch rsp.data.(type) {
-			case map[string][]string: // POST request
-				// The http spec indicates 3xx redirects cannot change the
-				// method (e.g. GET to POST). Thus, the code below with
-				// http.Redirect will not work.
-				/*
-					m := rsp.data.(map[string][]string)
-					j, err := json.Marshal(m)
-					if err != nil {
-						log.Info.Println(err)
-					}
-
-					r.Method = "POST"
-					r.URL, _ = url.Parse(rsp.redirect)
-					r.RequestURI = rsp.redirect
-					r.Body = ioutil.NopCloser(bytes.NewReader(j))
-					r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-					http.Redirect(w, r, rsp.redirect, http.StatusFound)
-				*/
-			default: // !bang
-				http.Redirect(w, r, rsp.redirect, http.StatusFound)
-			}
-		case http.StatusBadRequest, http.StatusInternalServerError:
+			http.Redirect(w, r, rsp.redirect, http.StatusFound)
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError:
 			errHandler(w, rsp)
 		default:
 			log.Info.Printf("Unknown status %d\n", rsp.status)
@@ -202,7 +212,7 @@ func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func errHandler(w http.ResponseWriter, rsp *response) {
 	switch rsp.status {
-	case http.StatusBadRequest:
+	case http.StatusBadRequest, http.StatusForbidden:
 		log.Debug.Println(rsp.err)
 	case http.StatusInternalServerError:
 		log.Info.Println(rsp.err)
@@ -216,13 +226,35 @@ func (f *Frontend) autocompleteHandler(w http.ResponseWriter, r *http.Request) *
 		return fmt.Sprintf("/image/32x,s%v/%v", hmacKey(u), u)
 	}
 
+	if err := applyPreferences(r); err != nil {
+		log.Debug.Println(err)
+	}
+
 	q := strings.TrimSpace(r.FormValue("q"))
+	ub := f.userBangs(r)
+
+	// browsers ask for OpenSearch suggestions (see opensearch.xml)
+	respond := func(res interface{}) *response {
+		if r.FormValue("o") == "opensearch" {
+			return &response{
+				status:   http.StatusOK,
+				template: "opensearch_suggestions",
+				data:     openSearchSuggestions(q, res),
+			}
+		}
+
+		return &response{
+			status:   http.StatusOK,
+			template: "json",
+			data:     res,
+		}
+	}
 
 	if q == "!" {
 		bngs := []bangs.Suggestion{}
 		triggers := []string{"g", "a", "b", "reddit", "w"}
 		for _, trigger := range triggers {
-			for _, bng := range f.Bangs.Bangs {
+			for _, bng := range ub.All() {
 				for _, tr := range bng.Triggers {
 					if tr == trigger {
 						sug := bangs.Suggestion{
@@ -237,16 +269,12 @@ func (f *Frontend) autocompleteHandler(w http.ResponseWriter, r *http.Request) *
 		}
 
 		// give a default set of !bang suggestions
-		return &response{
-			status:   http.StatusOK,
-			template: "json",
-			data: bangs.Results{
-				Suggestions: bngs,
-			},
-		}
+		return respond(bangs.Results{
+			Suggestions: bngs,
+		})
 
 	} else if len(q) > 1 && !strings.HasPrefix(q, " ") && strings.HasPrefix(q, "!") {
-		res, err := f.Bangs.Suggest(q, 10)
+		res, err := ub.Suggest(q, 10)
 		if err != nil {
 			return &response{
 				status: http.StatusInternalServerError,
@@ -261,15 +289,12 @@ func (f *Frontend) autocompleteHandler(w http.ResponseWriter, r *http.Request) *
 				res.Suggestions[i] = b
 			}
 
-			return &response{
-				status:   http.StatusOK,
-				template: "json",
-				data:     res,
-			}
+			return respond(res)
 		}
 	}
 
-	res, err := f.Suggest.Completion(q, 10)
+	lang, _, _ := f.Document.Matcher.Match(f.detectLanguage(r)...)
+	res, err := f.Suggest.Completion(q, lang, f.detectRegion(lang, r), 10)
 	if err != nil {
 		return &response{
 			status: http.StatusInternalServerError,
@@ -277,11 +302,22 @@ func (f *Frontend) autocompleteHandler(w http.ResponseWriter, r *http.Request) *
 		}
 	}
 
-	return &response{
-		status:   http.StatusOK,
-		template: "json",
-		data:     res,
+	// what (or who) the suggestions are about. Plain suggestions are fine if this fails.
+	if f.Entities != nil && len(res.Suggestions) > 0 {
+		wl, _, _ := f.Wikipedia.Matcher.Match(lang)
+		res.Entities, err = f.Entities.Entities(res.Suggestions, wl)
+		if err != nil {
+			log.Info.Println(err)
+		}
+
+		for _, e := range res.Entities {
+			if e.Image != "" && !strings.HasPrefix(e.Image, "/image/") {
+				e.Image = fmt.Sprintf("/image/64x,s%v/%v", hmacKey(e.Image), e.Image)
+			}
+		}
 	}
+
+	return respond(res)
 }
 
 // Assuming ParseTemplates is defined in the same package as TestAnswerHandler
@@ -305,6 +341,16 @@ func ParseTemplates(fs afero.Fs) {
 		panic(err)
 	}
 
+	templates["preferences"] = template.Must(
+		template.New("base.html").
+			Funcs(funcMap).
+			ParseFiles(
+				"frontend/templates/base.html",
+				"frontend/templates/search_form.html",
+				"frontend/templates/preferences.html",
+			),
+	)
+
 	templates["answer"] = template.Must(
 		t.Funcs(funcMap).
 			ParseFiles(
@@ -319,6 +365,18 @@ func ParseTemplates(fs afero.Fs) {
 				"frontend/templates/maps.html",
 			),
 	)
+	templates["post"] = template.Must(
+		template.New("post.html").
+			ParseFiles(
+				"frontend/templates/post.html",
+			),
+	)
+	templates["redirect"] = template.Must(
+		template.New("redirect.html").
+			ParseFiles(
+				"frontend/templates/redirect.html",
+			),
+	)
 	templates["opensearch"] = template.Must(
 		template.New("opensearch.xml").
 			ParseFiles(
@@ -1,6 +1,11 @@
 package frontend
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"github.com/jonesrussell/jivesearch/suggest"
+)
 
 func (f *Frontend) openSearchHandler(w http.ResponseWriter, r *http.Request) *response {
 	resp := &response{
@@ -14,3 +19,20 @@ func (f *Frontend) openSearchHandler(w http.ResponseWriter, r *http.Request) *re
 
 	return resp
 }
+
+// openSearchSuggestions are autocomplete results in the format browsers
+// expect (application/x-suggestions+json): [query, [suggestions]]
+func openSearchSuggestions(q string, res interface{}) []interface{} {
+	suggestions := []string{}
+
+	switch res := res.(type) {
+	case bangs.Results:
+		for _, s := range res.Suggestions {
+			suggestions = append(suggestions, "!"+s.Trigger)
+		}
+	case suggest.Results:
+		suggestions = append(suggestions, res.Suggestions...)
+	}
+
+	return []interface{}{q, suggestions}
+}
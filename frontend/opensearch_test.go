@@ -0,0 +1,81 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"github.com/jonesrussell/jivesearch/suggest"
+	"golang.org/x/text/language"
+)
+
+func TestOpenSearchSuggestions(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	s := &suggest.Simple{}
+	if err := s.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{"bob dylan", "bob marley"} {
+		if err := s.Insert(q, language.English, language.MustParseRegion("US")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &Frontend{
+		Document: Document{
+			Matcher: language.NewMatcher([]language.Tag{language.English}),
+		},
+		Bangs: &bangs.Bangs{
+			Bangs: []bangs.Bang{
+				{
+					Name:     "Google",
+					FavIcon:  "https://www.google.com/favicon.ico",
+					Triggers: []string{"g"},
+					Regions:  map[string]string{"default": "https://www.google.com/search?q={{{term}}}"},
+				},
+				{
+					Name:     "Wikipedia",
+					FavIcon:  "https://en.wikipedia.org/favicon.ico",
+					Triggers: []string{"w"},
+					Regions:  map[string]string{"default": "https://en.wikipedia.org/wiki/{{{term}}}"},
+				},
+			},
+		},
+		Suggest: s,
+	}
+
+	for _, c := range []struct {
+		name string
+		q    string
+		want string
+	}{
+		{"bangs", "!", `["!",["!g","!w"]]`},
+		{"queries", "bob", `["bob",["bob dylan","bob marley"]]`},
+		{"nothing", "zzz", `["zzz",[]]`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/autocomplete?o=opensearch&q="+c.q, nil)
+			r.Header.Set("Accept-Language", "en-US")
+
+			w := httptest.NewRecorder()
+			appHandler(f.autocompleteHandler).ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("got %d; want %d", w.Code, http.StatusOK)
+			}
+
+			if got, want := w.Header().Get("Content-Type"), "application/x-suggestions+json"; got != want {
+				t.Fatalf("got %q; want %q", got, want)
+			}
+
+			if got := strings.TrimSpace(w.Body.String()); got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
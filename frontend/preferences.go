@@ -0,0 +1,274 @@
+package frontend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonesrussell/jivesearch/log"
+	"golang.org/x/text/language"
+)
+
+// preferencesCookie holds a user's preferences. We keep nothing on our end:
+// the cookie is all there is. It is signed so no one can forge it and
+// only our own pages can save it (see sameOrigin).
+const preferencesCookie = "preferences"
+
+// preferenceKeys are the params a user can save, in the order of the preferences page
+var preferenceKeys = []string{"theme", "safe", "f", "b", "l", "r", "n", "post"}
+
+var errNoSecret = fmt.Errorf(`can't sign without an hmac secret. Please set the "hmac_secret" env variable`)
+
+// sign lets us know later that we set the value.
+// The purpose (e.g. "prefs") is signed along with it so a value signed for
+// one thing (or by our image proxy) can't pass for another.
+func sign(purpose, v string) (string, error) {
+	mac, err := signature(purpose, v)
+	if err != nil {
+		return "", err
+	}
+
+	return v + "." + mac, nil
+}
+
+// verify returns the value of s if we signed it for the purpose.
+// Nothing verifies without a secret as anyone could have signed it.
+func verify(purpose, s string) (string, bool) {
+	i := strings.LastIndex(s, ".")
+	if i < 0 {
+		return "", false
+	}
+
+	v := s[:i]
+	mac, err := signature(purpose, v)
+	if err != nil {
+		return "", false
+	}
+
+	return v, hmac.Equal([]byte(s[i+1:]), []byte(mac))
+}
+
+func signature(purpose, v string) (string, error) {
+	secret := hmacSecret()
+	if secret == "" {
+		return "", errNoSecret
+	}
+
+	h := hmac.New(sha256.New, []byte(secret))
+	if _, err := h.Write([]byte(purpose + ":" + v)); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// sameOrigin tells us if a request that changes a user's settings came from one of our pages.
+// Otherwise any site could submit a form to us on their behalf (CSRF).
+// Browsers send Sec-Fetch-Site or Origin with a POST so a request with neither isn't from one.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none": // "none" is the user themselves, e.g. a bookmark
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	o := r.Header.Get("Origin")
+	if o == "" {
+		return true
+	}
+
+	u, err := url.Parse(o)
+	return err == nil && u.Host == r.Host
+}
+
+// safeParam is image safe search from the "safe" param. It is on unless it is "f",
+// which a user can save. An explicit "t" turns it back on over their saved "f".
+func safeParam(r *http.Request) (safe, explicit bool) {
+	switch strings.TrimSpace(r.FormValue("safe")) {
+	case "f":
+		return false, false
+	case "t":
+		return true, true
+	}
+
+	return true, false
+}
+
+// validPreference indicates if we can save the value of a param
+func validPreference(k, v string) bool {
+	if v == "" {
+		return false
+	}
+
+	switch k {
+	case "theme":
+		return themes[v]
+	case "safe":
+		return v == "f"
+	case "post":
+		return v == "true"
+	case "f":
+		return v == "strict" || v == "off"
+	case "b":
+		return len(v) <= 100 && !strings.ContainsAny(v, " !")
+	case "l":
+		_, err := language.Parse(v)
+		return err == nil
+	case "r":
+		reg, err := language.ParseRegion(v)
+		return err == nil && reg.IsCountry()
+	case "n":
+		n, err := strconv.Atoi(v)
+		return err == nil && n > 0 && n <= 100
+	}
+
+	return false
+}
+
+// savedPreferences are the valid preferences in the user's cookie
+func savedPreferences(r *http.Request) url.Values {
+	prefs := url.Values{}
+
+	c, err := r.Cookie(preferencesCookie)
+	if err != nil {
+		return prefs
+	}
+
+	v, ok := verify("prefs", c.Value)
+	if !ok {
+		log.Debug.Println("preferences have an invalid signature")
+		return prefs
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		log.Debug.Println(err)
+		return prefs
+	}
+
+	q, err := url.ParseQuery(string(b))
+	if err != nil {
+		log.Debug.Println(err)
+		return prefs
+	}
+
+	for _, k := range preferenceKeys {
+		if v := q.Get(k); validPreference(k, v) {
+			prefs.Set(k, v)
+		}
+	}
+
+	return prefs
+}
+
+// applyPreferences fills in the params a request doesn't have from the user's preferences.
+// A param in the request always wins.
+func applyPreferences(r *http.Request) error {
+	if err := r.ParseForm(); err != nil { // for POST requests
+		return err
+	}
+
+	for k, v := range savedPreferences(r) {
+		if _, ok := r.Form[k]; !ok {
+			r.Form[k] = v
+		}
+	}
+
+	return nil
+}
+
+type preferences struct {
+	Brand
+	*Context    `json:"-"`
+	Preferences map[string]string
+}
+
+// preferencesHandler shows (GET) and saves (POST) a user's preferences.
+// Saving with "reset" clears them.
+func (f *Frontend) preferencesHandler(w http.ResponseWriter, r *http.Request) *response {
+	if r.Method == http.MethodPost {
+		if !sameOrigin(r) {
+			return &response{
+				status: http.StatusForbidden,
+				err:    fmt.Errorf("cross-site request to save preferences"),
+			}
+		}
+
+		if err := r.ParseForm(); err != nil {
+			return &response{
+				status: http.StatusBadRequest,
+				err:    err,
+			}
+		}
+
+		prefs := url.Values{}
+		for _, k := range preferenceKeys {
+			if v := strings.TrimSpace(r.PostFormValue(k)); validPreference(k, v) {
+				prefs.Set(k, v)
+			}
+		}
+
+		c := &http.Cookie{
+			Name:     preferencesCookie,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			Secure:   f.secure(r),
+		}
+
+		if len(prefs) == 0 || r.PostFormValue("reset") != "" {
+			c.MaxAge = -1
+		} else {
+			v, err := sign("prefs", base64.RawURLEncoding.EncodeToString([]byte(prefs.Encode())))
+			if err != nil {
+				return &response{
+					status: http.StatusInternalServerError,
+					err:    err,
+				}
+			}
+
+			c.Value = v
+			c.Expires = time.Now().AddDate(1, 0, 0)
+		}
+
+		http.SetCookie(w, c)
+
+		return &response{
+			status:   http.StatusFound,
+			redirect: "/preferences",
+		}
+	}
+
+	p := preferences{
+		Brand:       f.Brand,
+		Context:     &Context{},
+		Preferences: map[string]string{},
+	}
+
+	for k, v := range savedPreferences(r) {
+		p.Preferences[k] = v[0]
+	}
+
+	if err := applyPreferences(r); err != nil {
+		return &response{
+			status: http.StatusBadRequest,
+			err:    err,
+		}
+	}
+
+	p.Context.setTheme(r)
+
+	return &response{
+		status:   http.StatusOK,
+		template: "preferences",
+		data:     p,
+	}
+}
@@ -0,0 +1,235 @@
+package frontend
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	s, err := sign("prefs", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name    string
+		purpose string
+		s       string
+		want    bool
+	}{
+		{"signed", "prefs", s, true},
+		{"other purpose", "bangs", s, false},
+		{"tampered", "prefs", strings.Replace(s, "value", "other", 1), false},
+		{"unsigned", "prefs", "value", false},
+		{"image proxy", "prefs", "value." + hmacKey("value"), false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			v, ok := verify(c.purpose, c.s)
+			if ok != c.want {
+				t.Fatalf("got %v; want %v", ok, c.want)
+			}
+
+			if ok && v != "value" {
+				t.Fatalf("got %q; want %q", v, "value")
+			}
+		})
+	}
+
+	// a blank secret is no secret at all
+	hmacSecret = func() string { return "" }
+	if _, err := sign("prefs", "value"); err != errNoSecret {
+		t.Fatalf("got %v; want %v", err, errNoSecret)
+	}
+
+	if _, ok := verify("prefs", s); ok {
+		t.Fatal("verified without a secret")
+	}
+}
+
+func TestSavedPreferences(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	for _, c := range []struct {
+		name   string
+		cookie func() string
+		want   url.Values
+	}{
+		{
+			name:   "none",
+			cookie: func() string { return "" },
+			want:   url.Values{},
+		},
+		{
+			name:   "saved",
+			cookie: func() string { return preferencesValue(t, "f=off&safe=f&n=50") },
+			want:   url.Values{"f": {"off"}, "safe": {"f"}, "n": {"50"}},
+		},
+		{
+			name:   "invalid values",
+			cookie: func() string { return preferencesValue(t, "f=none&theme=pink&n=1000&r=US") },
+			want:   url.Values{"r": {"US"}},
+		},
+		{
+			name: "forged",
+			cookie: func() string {
+				return base64.RawURLEncoding.EncodeToString([]byte("f=off")) + ".forged"
+			},
+			want: url.Values{},
+		},
+		{
+			name: "signed for something else",
+			cookie: func() string {
+				s, _ := sign("bangs", base64.RawURLEncoding.EncodeToString([]byte("f=off")))
+				return s
+			},
+			want: url.Values{},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?q=x", nil)
+			if v := c.cookie(); v != "" {
+				r.AddCookie(&http.Cookie{Name: preferencesCookie, Value: v})
+			}
+
+			if got := savedPreferences(r); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestApplyPreferences(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	r := httptest.NewRequest(http.MethodGet, "/?q=x&f=strict", nil)
+	r.AddCookie(&http.Cookie{Name: preferencesCookie, Value: preferencesValue(t, "f=off&safe=f")})
+
+	if err := applyPreferences(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// the request's own params win
+	want := url.Values{"q": {"x"}, "f": {"strict"}, "safe": {"f"}}
+	if !reflect.DeepEqual(r.Form, want) {
+		t.Fatalf("got %v; want %v", r.Form, want)
+	}
+}
+
+func TestSafeParam(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	for _, c := range []struct {
+		name     string
+		url      string
+		saved    string
+		safe     bool
+		explicit bool
+	}{
+		{"default", "/?q=x", "", true, false},
+		{"off", "/?q=x&safe=f", "", false, false},
+		{"saved off", "/?q=x", "safe=f", false, false},
+		{"back on", "/?q=x&safe=t", "safe=f", true, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.url, nil)
+			if c.saved != "" {
+				r.AddCookie(&http.Cookie{Name: preferencesCookie, Value: preferencesValue(t, c.saved)})
+			}
+
+			if err := applyPreferences(r); err != nil {
+				t.Fatal(err)
+			}
+
+			if safe, explicit := safeParam(r); safe != c.safe || explicit != c.explicit {
+				t.Fatalf("got %v, %v; want %v, %v", safe, explicit, c.safe, c.explicit)
+			}
+		})
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"not a browser", map[string]string{}, true},
+		{"same origin", map[string]string{"Sec-Fetch-Site": "same-origin"}, true},
+		{"cross site", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+		{"same site", map[string]string{"Sec-Fetch-Site": "same-site"}, false},
+		{"our origin", map[string]string{"Origin": "https://www.example.com"}, true},
+		{"other origin", map[string]string{"Origin": "https://evil.example.org"}, false},
+		{"null origin", map[string]string{"Origin": "null"}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "https://www.example.com/preferences", nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+
+			if got := sameOrigin(r); got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPreferencesHandler(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	for _, c := range []struct {
+		name   string
+		url    string
+		origin string
+		status int
+		cookie bool
+		secure bool
+	}{
+		{"ours", "https://www.example.com/preferences", "https://www.example.com", http.StatusFound, true, true},
+		{"over http", "http://www.example.com/preferences", "http://www.example.com", http.StatusFound, true, false},
+		{"cross site", "https://www.example.com/preferences", "https://evil.example.org", http.StatusForbidden, false, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, c.url, strings.NewReader("f=off&safe=f"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Origin", c.origin)
+
+			w := httptest.NewRecorder()
+			f := &Frontend{}
+			appHandler(f.preferencesHandler).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("got %d; want %d", w.Code, c.status)
+			}
+
+			if got := w.Header().Get("Set-Cookie") != ""; got != c.cookie {
+				t.Fatalf("got cookie %v; want %v", got, c.cookie)
+			}
+
+			if got := strings.Contains(w.Header().Get("Set-Cookie"), "; Secure"); got != c.secure {
+				t.Fatalf("got secure %v; want %v: %v", got, c.secure, w.Header().Get("Set-Cookie"))
+			}
+		})
+	}
+}
+
+// preferencesValue is a signed preferences cookie
+func preferencesValue(t *testing.T, q string) string {
+	s, err := sign("prefs", base64.RawURLEncoding.EncodeToString([]byte(q)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
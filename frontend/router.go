@@ -14,7 +14,7 @@ import (
 func (f *Frontend) Router(cfg config.Provider) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
-	router.NewRoute().Name("search").Methods("GET").Path("/").Handler(
+	router.NewRoute().Name("search").Methods("GET", "POST").Path("/").Handler(
 		f.middleware(appHandler(f.searchHandler)),
 	)
 	router.NewRoute().Name("answer").Methods("GET").Path("/answer").Handler(
@@ -23,9 +23,18 @@ func (f *Frontend) Router(cfg config.Provider) *mux.Router {
 	router.NewRoute().Name("about").Methods("GET").Path("/about").Handler(
 		f.middleware(appHandler(f.aboutHandler)),
 	)
+	router.NewRoute().Name("preferences").Methods("GET", "POST").Path("/preferences").Handler(
+		f.middleware(appHandler(f.preferencesHandler)),
+	)
 	router.NewRoute().Name("autocomplete").Methods("GET").Path("/autocomplete").Handler(
 		f.middleware(appHandler(f.autocompleteHandler)),
 	)
+	router.NewRoute().Name("bangs").Methods("POST").Path("/bangs").Handler(
+		f.middleware(appHandler(f.bangsHandler)),
+	)
+	router.NewRoute().Name("similar").Methods("GET", "POST").Path("/similar").Handler(
+		f.middleware(appHandler(f.similarHandler)),
+	)
 	router.NewRoute().Name("favicon").Methods("GET").Path("/favicon.ico").Handler(
 		http.FileServer(http.Dir("static")),
 	)
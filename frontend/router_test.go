@@ -21,6 +21,11 @@ func TestRouter(t *testing.T) {
 			method: "GET",
 			url:    "https://www.example.com/?q=search+term",
 		},
+		{
+			name:   "search",
+			method: "POST",
+			url:    "https://www.example.com/",
+		},
 		{
 			name:   "answer",
 			method: "GET",
@@ -31,11 +36,26 @@ func TestRouter(t *testing.T) {
 			method: "GET",
 			url:    "http://localhost/about",
 		},
+		{
+			name:   "preferences",
+			method: "POST",
+			url:    "http://localhost/preferences",
+		},
 		{
 			name:   "autocomplete",
 			method: "GET",
 			url:    "http://127.0.0.1/autocomplete",
 		},
+		{
+			name:   "bangs",
+			method: "POST",
+			url:    "http://localhost/bangs",
+		},
+		{
+			name:   "similar",
+			method: "GET",
+			url:    "http://localhost/similar?id=https://example.com/a.jpg",
+		},
 		{
 			name:   "favicon",
 			method: "GET",
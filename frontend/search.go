@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,7 +17,9 @@ import (
 	"github.com/jonesrussell/jivesearch/instant"
 	"github.com/jonesrussell/jivesearch/log"
 	"github.com/jonesrussell/jivesearch/search"
+	"github.com/jonesrussell/jivesearch/search/document"
 	img "github.com/jonesrussell/jivesearch/search/image"
+	"github.com/jonesrussell/jivesearch/search/langid"
 	"github.com/jonesrussell/jivesearch/suggest"
 	"github.com/pkg/errors"
 	"golang.org/x/text/language"
@@ -37,6 +40,11 @@ type Context struct {
 	T            string          `json:"-"`
 	Ref          string          `json:"-"`
 	Safe         bool            `json:"-"`
+	SafeOn       bool            `json:"-"` // safe=t, which overrides a saved safe=f
+	ImageFilters img.Filters     `json:"-"`
+	Time         string          `json:"-"` // day, week, month, year or a custom From/To range
+	From         string          `json:"-"`
+	To           string          `json:"-"`
 	DefaultBangs []DefaultBang   `json:"-"`
 	Preferred    []language.Tag  `json:"-"`
 	Region       language.Region `json:"-"`
@@ -57,6 +65,7 @@ type Results struct {
 	Images      *img.Results    `json:"images,omitempty"`
 	Instant     instant.Data    `json:"-"`
 	Search      *search.Results `json:"search,omitempty"`
+	Language    *langid.Guess   `json:"language,omitempty"` // the detected language of the query
 }
 
 // Instant is a wrapper to facilitate custom unmarshalling
@@ -82,9 +91,10 @@ type data struct {
 
 func (f *Frontend) defaultBangs(r *http.Request) []DefaultBang {
 	var bngs []DefaultBang
+	all := f.userBangs(r).All()
 
 	for _, db := range strings.Split(strings.TrimSpace(r.FormValue("b")), ",") {
-		for _, b := range f.Bangs.Bangs {
+		for _, b := range all {
 			for _, t := range b.Triggers {
 				if t == db {
 					bngs = append(bngs, DefaultBang{db, b})
@@ -107,7 +117,7 @@ func (f *Frontend) defaultBangs(r *http.Request) []DefaultBang {
 		{"a", "Amazon"},
 		{"yt", "YouTube"},
 	} {
-		for _, bng := range f.Bangs.Bangs {
+		for _, bng := range all {
 			if bng.Name == b.name {
 				bngs = append(bngs, DefaultBang{b.trigger, bng})
 			}
@@ -137,6 +147,18 @@ func (f *Frontend) detectLanguage(r *http.Request) []language.Tag {
 	return preferred
 }
 
+// languages are the supported languages of those preferred, most preferred first
+func (f *Frontend) languages(preferred []language.Tag) []language.Tag {
+	langs := []language.Tag{}
+	for _, p := range preferred {
+		if t, _, c := f.Document.Matcher.Match(p); c != language.No {
+			langs = append(langs, t)
+		}
+	}
+
+	return langs
+}
+
 // Detect the user's region. "r" param takes precedence over the language's region (if any).
 func (f *Frontend) detectRegion(lang language.Tag, r *http.Request) language.Region {
 	reg, err := language.ParseRegion(strings.TrimSpace(r.FormValue("r")))
@@ -147,30 +169,92 @@ func (f *Frontend) detectRegion(lang language.Tag, r *http.Request) language.Reg
 	return reg.Canonicalize()
 }
 
-var errIsNaughty = fmt.Errorf("naughty word")
+var (
+	errIsUnsafe   = fmt.Errorf("unsafe word")
+	errIsPersonal = fmt.Errorf("personal information")
+)
+
+// addQuery counts a query for autocomplete.
+// A new query is only suggested once enough different people made it.
+func (f *Frontend) addQuery(q string, lang language.Tag, region language.Region, r *http.Request) error {
+	// we don't even count queries that look personal or are NSFW
+	if suggest.PII(q) {
+		return errIsPersonal
+	}
+
+	if f.Safety != nil && f.Safety.Unsafe(q, lang) {
+		return errIsUnsafe
+	}
 
-func (f *Frontend) addQuery(q string) error {
 	exists, err := f.Suggest.Exists(q)
 	if err != nil {
 		return err
 	}
 
 	if !exists {
-		// are any of the words NSFW?
-		if suggest.Naughty(q) {
-			return errIsNaughty
+		if f.Anonymity != nil {
+			public, err := f.Anonymity.Public(q, f.client(r), time.Now())
+			if err != nil || !public {
+				return err
+			}
 		}
 
-		if err := f.Suggest.Insert(q); err != nil {
+		if err := f.Suggest.Insert(q, lang, region); err != nil {
 			return err
 		}
 	}
 
-	return f.Suggest.Increment(q)
+	return f.Suggest.Increment(q, lang, region)
+}
+
+// client tells people apart for the Anonymity of autocomplete by their IP.
+// Only our own proxies (e.g. Nginx) get to tell us that with X-Real-IP.
+// Anything else the client sends, like the User-Agent, it can change at will.
+func (f *Frontend) client(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil && f.proxied(ip) {
+		return real.String()
+	}
+
+	return ip
+}
+
+// secure tells us if the request came over HTTPS, to us or to one of our proxies
+func (f *Frontend) secure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return f.proxied(ip) && strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")), "https")
+}
+
+// proxied returns true if ip is one of our proxies
+func (f *Frontend) proxied(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, p := range f.Proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (f *Frontend) getData(r *http.Request) (data, error) {
-	err := r.ParseForm() // for POST requests
+	err := applyPreferences(r)
 	if err != nil {
 		return data{}, err
 	}
@@ -179,17 +263,15 @@ func (f *Frontend) getData(r *http.Request) (data, error) {
 		Brand:     f.Brand,
 		MapBoxKey: f.MapBoxKey,
 		Context: &Context{
-			Q:    strings.TrimSpace(r.FormValue("q")),
-			F:    search.Moderate,
-			Safe: true,
+			Q: strings.TrimSpace(r.FormValue("q")),
+			F: search.Moderate,
 		},
 	}
 
-	if strings.TrimSpace(r.FormValue("safe")) == "f" {
-		d.Context.Safe = false
-	}
+	d.Context.Safe, d.Context.SafeOn = safeParam(r)
 
 	d.Context.setTheme(r)
+	d.Context.POST = strings.ToLower(strings.TrimSpace(r.FormValue("post"))) == "true" // the start page needs it too
 
 	// Note: We can combine Safe with F. They are only separate for now
 	// because image filter is a boolean but that can be changed to off, moderate and strict.
@@ -209,11 +291,20 @@ func (f *Frontend) getData(r *http.Request) (data, error) {
 	d.Context.D = strings.TrimSpace(r.FormValue("d"))
 	d.Context.L = strings.TrimSpace(r.FormValue("l"))
 	d.Context.N = strings.TrimSpace(r.FormValue("n"))
-	d.Context.POST = strings.ToLower(strings.TrimSpace(r.FormValue("post"))) == "true"
 	d.Context.R = strings.TrimSpace(r.FormValue("r"))
 	d.Context.S = strings.TrimSpace(r.FormValue("s"))
 	d.Context.Ref = strings.TrimSpace(r.FormValue("ref"))
 	d.Context.T = strings.TrimSpace(r.FormValue("t"))
+	d.Context.Time = strings.TrimSpace(r.FormValue("time"))
+	d.Context.From = strings.TrimSpace(r.FormValue("from"))
+	d.Context.To = strings.TrimSpace(r.FormValue("to"))
+	d.Context.ImageFilters = img.ParseFilters(
+		strings.TrimSpace(r.FormValue("size")),
+		strings.TrimSpace(r.FormValue("aspect")),
+		strings.TrimSpace(r.FormValue("type")),
+		strings.TrimSpace(r.FormValue("color")),
+		strings.TrimSpace(r.FormValue("license")),
+	)
 	d.Context.DefaultBangs = f.defaultBangs(r)
 	d.Context.Preferred = f.detectLanguage(r)
 	d.Results = Results{
@@ -221,6 +312,8 @@ func (f *Frontend) getData(r *http.Request) (data, error) {
 	}
 
 	d.Context.lang, _, _ = f.Document.Matcher.Match(d.Context.Preferred...) // will use first supported tag in case of error
+	g := langid.Detect(d.Context.Q)
+	d.Results.Language = &g
 	d.Context.Region = f.detectRegion(d.Context.lang, r)
 
 	d.Context.Page, err = strconv.Atoi(strings.TrimSpace(r.FormValue("p")))
@@ -253,46 +346,32 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 	}
 
 	// if they sent a GET request but want POST then redirect them
-	// The http spec indicates 3xx redirects cannot change the
-	// method (e.g. GET to POST). Even if we hack around http.Redirect()
-	// it isn't guaranteed that all browsers would turn it into a POST request.
-	// Perhaps a better way is to send to a page with a POST form then submit that form via JavaScript.
-	/*
-		if r.Method == "GET" && d.POST {
-			m := map[string][]string{}
-			for k, v := range r.URL.Query() {
-				m[k] = v
-			}
+	// so the query doesn't stay in their history (see ServeHTTP).
+	if r.Method == http.MethodGet && d.Context.POST {
+		m := map[string][]string{}
+		for k, v := range r.URL.Query() {
+			m[k] = v
+		}
 
-			return &response{
-				status:   302,
-				redirect: "/",
-				data:     m,
-			}
+		return &response{
+			redirect: r.URL.Path,
+			post:     m,
 		}
-	*/
+	}
 
 	// is it a !bang? Redirect them
-	if bng, loc, ok := f.Bangs.Detect(d.Context.Q, d.Context.Region, d.Context.lang); ok {
+	if bng, loc, ok := f.userBangs(r).Detect(d.Context.Q, d.Context.Region, d.Context.lang); ok {
 		log.Info.Printf("!bang (%v)", bng.Name)
-		return &response{
-			status:   302,
-			redirect: loc,
-		}
+		return redirect(loc, d.Context.POST)
 	}
 
 	// Do they just want the first result?
 	// "! example", "example !" or "\example" but NOT "example ! now"
 	fields := strings.Fields(d.Context.Q)
 	if fields[0] == "!" || fields[len(fields)-1] == "!" || strings.HasPrefix(fields[0], `\`) {
-		docs := f.searchResults(d, d.Context.lang, d.Context.Region, r.URL)
+		docs := f.searchResults(d, d.Context.lang, d.Context.Region, formURL(r))
 		for _, doc := range docs.Documents {
-			loc := doc.ID
-
-			return &response{
-				status:   302,
-				redirect: loc,
-			}
+			return redirect(doc.ID, d.Context.POST)
 		}
 	}
 
@@ -308,7 +387,7 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 		channels++
 		ac = make(chan error)
 		go func(q string, ch chan error) {
-			ch <- f.addQuery(q)
+			ch <- f.addQuery(q, d.Context.lang, d.Context.Region, r)
 		}(d.Context.Q, ac)
 
 		channels++
@@ -319,7 +398,7 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 	go func(d data, lang language.Tag, region language.Region) {
 		switch d.Context.T {
 		case "images":
-			key := cacheKey("images", lang, region, r.URL)
+			key := cacheKey("images", lang, region, formURL(r))
 
 			v, err := f.Cache.Get(key)
 			if err != nil {
@@ -338,11 +417,19 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 
 			num := 100
 			offset := d.Context.Page*num - num
-			ir, err := f.Images.Fetch(d.Context.Q, d.Context.Safe, num, offset) // .8 is Yahoo's open_nsfw cutoff for nsfw
+			ir, err := f.Images.Fetch(d.Context.Q, d.Context.Safe, overfetch(num, d.Context.Safe), offset, d.Context.ImageFilters) // .8 is Yahoo's open_nsfw cutoff for nsfw
 			if err != nil {
 				log.Info.Println(err)
 			}
 
+			if d.Context.Safe {
+				ir = f.safeImages(ir, lang)
+			}
+
+			if ir != nil && len(ir.Images) > num {
+				ir.Images = ir.Images[:num]
+			}
+
 			if err := f.Cache.Put(key, ir, f.Cache.Search); err != nil {
 				log.Info.Println(err)
 			}
@@ -352,7 +439,7 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 			resp.template = "maps"
 			channels--
 		default:
-			sc <- f.searchResults(d, lang, region, r.URL)
+			sc <- f.searchResults(d, lang, region, formURL(r))
 		}
 
 	}(d, d.Context.lang, d.Context.Region)
@@ -368,35 +455,7 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 		select {
 		case d.Images = <-imageCH:
 			if d.Images != nil {
-				// fetch the image & convert to base64 for smoother user experience
-				tmp := make(chan *img.Image, len(d.Images.Images))
-
-				go func() {
-					for im := range tmp {
-						for i, o := range d.Images.Images {
-							if im.ID == o.ID {
-								d.Images.Images[i] = im
-							}
-						}
-					}
-				}()
-
-				var wg sync.WaitGroup
-
-				for _, im := range d.Images.Images {
-					wg.Add(1)
-					go func(im *img.Image) {
-						var err error
-						im, err = f.fetchImage(im)
-						if err != nil {
-							log.Debug.Println(err)
-						}
-						tmp <- im
-						wg.Done()
-					}(im)
-				}
-
-				wg.Wait()
+				f.encodeImages(d.Images)
 			}
 
 			stats.images = time.Since(strt).Round(time.Millisecond)
@@ -417,7 +476,7 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 		case err := <-ac:
 			switch err {
 			case nil:
-			case errIsNaughty:
+			case errIsUnsafe, errIsPersonal:
 				log.Debug.Println(err)
 			default:
 				log.Info.Println(err)
@@ -441,7 +500,19 @@ func (f *Frontend) searchHandler(w http.ResponseWriter, r *http.Request) *respon
 }
 
 func (f *Frontend) searchResults(d data, lang language.Tag, region language.Region, u *url.URL) *search.Results {
+	langs := f.languages(d.Context.Preferred)
+	if d.Context.L == "" && d.Results.Language != nil { // "l" means they chose the language
+		langs = search.Languages(langs, *d.Results.Language, f.Document.Matcher)
+	}
+	if len(langs) > 0 {
+		lang = langs[0]
+	}
+
+	// bilingual users get results in each of their languages
 	key := cacheKey("search", lang, region, u)
+	for _, l := range langs {
+		key += "::" + l.String()
+	}
 
 	v, err := f.Cache.Get(key)
 	if err != nil {
@@ -457,7 +528,16 @@ func (f *Frontend) searchResults(d data, lang language.Tag, region language.Regi
 	}
 
 	offset := d.Context.Page*d.Context.Number - d.Context.Number
-	sr, err := f.Search.Fetch(d.Context.Q, d.Context.F, lang, region, d.Context.Number, offset)
+	opts := search.NewOptions(d.Context.Q, search.ParseTimeRange(d.Context.Time, d.Context.From, d.Context.To, now()), langs, now())
+
+	// With PerDomain a page is Number domains with all their results (see search.Options).
+	// We can't overfetch or cut those pages short or the next page would skip domains.
+	number := d.Context.Number
+	if opts.PerDomain == 0 {
+		number = overfetch(number, d.Context.F != search.Off)
+	}
+
+	sr, err := f.Search.Fetch(d.Context.Q, d.Context.F, lang, region, number, offset, opts)
 	if err != nil {
 		log.Info.Println(err)
 		return &search.Results{}
@@ -467,6 +547,13 @@ func (f *Frontend) searchResults(d data, lang language.Tag, region language.Regi
 		log.Info.Println(sr.Err)
 	}
 
+	sr = f.safeSearch(sr, d.Context.F, lang)
+
+	// for providers that don't cluster by domain themselves
+	sr = sr.Diversify(opts.PerDomain)
+	if opts.PerDomain == 0 && len(sr.Documents) > d.Context.Number {
+		sr.Documents = sr.Documents[:d.Context.Number]
+	}
 	sr = sr.AddPagination(d.Context.Number, d.Context.Page) // move this to javascript??? (Wouldn't be available in API....)
 
 	if err := f.Cache.Put(key, sr, f.Cache.Search); err != nil {
@@ -476,6 +563,91 @@ func (f *Frontend) searchResults(d data, lang language.Tag, region language.Regi
 	return sr
 }
 
+// overfetch is how many results to ask for to still have n after safe search drops some.
+// A page that lost some to safe search may share a few results with the next one.
+func overfetch(n int, safe bool) int {
+	if !safe {
+		return n
+	}
+
+	return n + n/5 + 1
+}
+
+// safeSearch drops the documents that are unsafe for the filter.
+// Moderate checks what we show of a document and Strict its url too.
+func (f *Frontend) safeSearch(sr *search.Results, filter search.Filter, lang language.Tag) *search.Results {
+	if f.Safety == nil || filter == search.Off {
+		return sr
+	}
+
+	docs := []*document.Document{}
+	for _, doc := range sr.Documents {
+		text := doc.Title + " " + doc.Description
+		if filter == search.Strict {
+			text += " " + doc.Domain + " " + doc.PathParts
+		}
+
+		if f.Safety.Unsafe(text, lang) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	sr.Documents = docs
+	return sr
+}
+
+// safeImages drops the images with unsafe alt text.
+// The nsfw score misses some, e.g. illustrations.
+func (f *Frontend) safeImages(ir *img.Results, lang language.Tag) *img.Results {
+	if f.Safety == nil || ir == nil {
+		return ir
+	}
+
+	images := []*img.Image{}
+	for _, im := range ir.Images {
+		if f.Safety.Unsafe(im.Alt, lang) {
+			continue
+		}
+		images = append(images, im)
+	}
+
+	ir.Images = images
+	return ir
+}
+
+// encodeImages fetches the images & converts them to base64 for smoother user experience
+func (f *Frontend) encodeImages(ir *img.Results) {
+	tmp := make(chan *img.Image, len(ir.Images))
+
+	go func() {
+		for im := range tmp {
+			for i, o := range ir.Images {
+				if im.ID == o.ID {
+					ir.Images[i] = im
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for _, im := range ir.Images {
+		wg.Add(1)
+		go func(im *img.Image) {
+			var err error
+			im, err = f.fetchImage(im)
+			if err != nil {
+				log.Debug.Println(err)
+			}
+			tmp <- im
+			wg.Done()
+		}(im)
+	}
+
+	wg.Wait()
+}
+
 // fetchImage fetches and converts an image to Base64
 func (f *Frontend) fetchImage(i *img.Image) (*img.Image, error) {
 	var err error
@@ -500,6 +672,36 @@ func (f *Frontend) fetchImage(i *img.Image) (*img.Image, error) {
 	return i, err
 }
 
+// redirect sends them to loc. In POST mode we go through a page
+// that doesn't send a referrer so the site can't tell where they came from.
+func redirect(loc string, post bool) *response {
+	if post {
+		return &response{
+			status:   http.StatusOK,
+			template: "redirect",
+			data:     loc,
+		}
+	}
+
+	return &response{
+		status:   http.StatusFound,
+		redirect: loc,
+	}
+}
+
+// formURL is the url of the request with the params we used as its query:
+// the form of a POST request along with the user's saved preferences.
+// Otherwise someone with safe search off could fill the cache for everyone.
+func formURL(r *http.Request) *url.URL {
+	if r.Form == nil {
+		return r.URL
+	}
+
+	u := *r.URL
+	u.RawQuery = r.Form.Encode()
+	return &u
+}
+
 func cacheKey(item string, lang language.Tag, region language.Region, u *url.URL) string {
 	// language and region might be different than what is pass as l & r params
 	// ::search::en-US::US::/?q=reverse+%22this%22
@@ -0,0 +1,268 @@
+package frontend
+
+import (
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/bangs"
+	"github.com/jonesrussell/jivesearch/frontend/cache"
+	"github.com/jonesrussell/jivesearch/search"
+	"github.com/jonesrussell/jivesearch/search/document"
+	"golang.org/x/text/language"
+)
+
+func TestCacheKey(t *testing.T) {
+	defer func(f func() string) { hmacSecret = f }(hmacSecret)
+	hmacSecret = func() string { return "very secret" }
+
+	key := func(r *http.Request) string {
+		if err := applyPreferences(r); err != nil {
+			t.Fatal(err)
+		}
+		return cacheKey("search", language.English, language.MustParseRegion("US"), formURL(r))
+	}
+
+	plain := key(httptest.NewRequest(http.MethodGet, "/?q=x", nil))
+	if want := "::search::en::US::/?q=x"; plain != want {
+		t.Fatalf("got %q; want %q", plain, want)
+	}
+
+	// safe search is off for this user
+	r := httptest.NewRequest(http.MethodGet, "/?q=x", nil)
+	r.AddCookie(&http.Cookie{Name: preferencesCookie, Value: preferencesValue(t, "f=off&safe=f")})
+	if got := key(r); got == plain {
+		t.Fatalf("a user's preferences share the cache key %q", got)
+	}
+
+	// same as asking for it
+	if got, want := key(r), key(httptest.NewRequest(http.MethodGet, "/?q=x&f=off&safe=f", nil)); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	// POST searches are told apart by their body
+	post := func(body string) string {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return key(r)
+	}
+
+	if x, y := post("q=x&post=true"), post("q=y&post=true"); x == y {
+		t.Fatalf("different POST searches share the cache key %q", x)
+	}
+
+	if got, want := post("q=x"), plain; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestSearchHandlerPOST(t *testing.T) {
+	defer func(m map[string]*template.Template) { templates = m }(templates)
+	templates = map[string]*template.Template{
+		"post":     template.Must(template.ParseFiles("templates/post.html")),
+		"redirect": template.Must(template.ParseFiles("templates/redirect.html")),
+	}
+
+	f := &Frontend{
+		Document: Document{
+			Matcher: language.NewMatcher([]language.Tag{language.English}),
+		},
+		Bangs: &bangs.Bangs{
+			Bangs: []bangs.Bang{
+				{
+					Name:     "Google",
+					Triggers: []string{"g"},
+					Regions:  map[string]string{"default": "https://www.google.com/search?q={{{term}}}"},
+				},
+			},
+		},
+	}
+
+	for _, c := range []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		status int
+		want   []string
+	}{
+		{
+			name:   "GET in POST mode",
+			method: http.MethodGet,
+			url:    "/?q=x&post=true",
+			status: http.StatusOK,
+			want:   []string{`<form id="post" method="POST" action="/">`, `name="q" value="x"`, `name="post" value="true"`},
+		},
+		{
+			name:   "!bang",
+			method: http.MethodGet,
+			url:    "/?q=%21g+foo",
+			status: http.StatusFound,
+			want:   []string{`https://www.google.com/search?q=foo`},
+		},
+		{
+			name:   "!bang in POST mode",
+			method: http.MethodPost,
+			url:    "/",
+			body:   "q=%21g+foo&post=true",
+			status: http.StatusOK,
+			want:   []string{`<meta name="referrer" content="no-referrer">`, `href="https://www.google.com/search?q=foo"`, `rel="noreferrer"`},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.url, strings.NewReader(c.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			w := httptest.NewRecorder()
+			appHandler(f.searchHandler).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("got %d; want %d", w.Code, c.status)
+			}
+
+			got := w.Body.String()
+			if c.status == http.StatusFound {
+				got = w.Header().Get("Location")
+			}
+
+			for _, want := range c.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %v; want it to contain %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	_, local, _ := net.ParseCIDR("127.0.0.1/32")
+	f := &Frontend{Proxies: []*net.IPNet{local}}
+
+	for _, c := range []struct {
+		name   string
+		remote string
+		real   string
+		want   string
+	}{
+		{"direct", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"spoofed", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"our proxy", "127.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"our proxy without a real ip", "127.0.0.1:1234", "", "127.0.0.1"},
+		{"our proxy with garbage", "127.0.0.1:1234", "not an ip", "127.0.0.1"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?q=x", nil)
+			r.RemoteAddr = c.remote
+			r.Header.Set("User-Agent", "Mozilla/5.0")
+			if c.real != "" {
+				r.Header.Set("X-Real-IP", c.real)
+			}
+
+			if got := f.client(r); got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSecure(t *testing.T) {
+	_, local, _ := net.ParseCIDR("127.0.0.1/32")
+	f := &Frontend{Proxies: []*net.IPNet{local}}
+
+	for _, c := range []struct {
+		name   string
+		url    string
+		remote string
+		proto  string
+		want   bool
+	}{
+		{"https", "https://www.example.com/", "203.0.113.7:1234", "", true},
+		{"http", "http://www.example.com/", "203.0.113.7:1234", "", false},
+		{"spoofed", "http://www.example.com/", "203.0.113.7:1234", "https", false},
+		{"our proxy", "http://www.example.com/", "127.0.0.1:1234", "https", true},
+		{"our proxy over http", "http://www.example.com/", "127.0.0.1:1234", "http", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.url, nil)
+			r.RemoteAddr = c.remote
+			if c.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", c.proto)
+			}
+
+			if got := f.secure(r); got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// groupingFetcher clusters like Elasticsearch's field collapsing:
+// number and offset count domains and each has up to PerDomain documents.
+type groupingFetcher struct {
+	domains []string
+}
+
+func (g *groupingFetcher) Fetch(q string, s search.Filter, lang language.Tag, region language.Region, number int, offset int, opts search.Options) (*search.Results, error) {
+	res := &search.Results{Count: int64(len(g.domains))}
+	for i := offset; i < offset+number && i < len(g.domains); i++ {
+		for j := 0; j < opts.PerDomain; j++ {
+			res.Documents = append(res.Documents, &document.Document{
+				ID:     fmt.Sprintf("https://%v/%d", g.domains[i], j),
+				Domain: g.domains[i],
+			})
+		}
+	}
+	return res, nil
+}
+
+func TestSearchResultsPages(t *testing.T) {
+	domains := []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com"}
+
+	f := &Frontend{
+		Document: Document{
+			Matcher: language.NewMatcher([]language.Tag{language.English}),
+		},
+		Search: &groupingFetcher{domains},
+	}
+	f.Cache.Cacher = &cache.Simple{M: map[string]cache.Value{}}
+
+	seen := map[string]int{}
+	for page := 1; page <= 2; page++ {
+		d := data{
+			Context: &Context{
+				Q:         "x",
+				L:         "en",
+				F:         search.Moderate,
+				Number:    3,
+				Page:      page,
+				Preferred: []language.Tag{language.English},
+			},
+		}
+
+		u, _ := url.Parse(fmt.Sprintf("/?q=x&p=%d", page))
+		sr := f.searchResults(d, language.English, language.MustParseRegion("US"), u)
+
+		if len(sr.Documents) != 3*search.PerDomain {
+			t.Fatalf("page %d: got %d documents; want every result of 3 domains", page, len(sr.Documents))
+		}
+
+		if page == 1 && sr.Next != "2" {
+			t.Fatalf("got next page %q; want 2", sr.Next)
+		}
+
+		for _, doc := range sr.Documents {
+			seen[doc.Domain]++
+		}
+	}
+
+	for _, dom := range domains {
+		if seen[dom] != search.PerDomain {
+			t.Errorf("got %d results of %v; want %d", seen[dom], dom, search.PerDomain)
+		}
+	}
+}
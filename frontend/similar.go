@@ -0,0 +1,120 @@
+package frontend
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	img "github.com/jonesrussell/jivesearch/search/image"
+)
+
+// maxUpload is the largest image we accept for a reverse image lookup
+const maxUpload = 10 << 20
+
+var errNoFinder = fmt.Errorf("image backend doesn't support similar images")
+
+// similarHandler finds images that look like an indexed image (GET "?id=")
+// or an uploaded image (POST "image"). Results are shown as an image search.
+func (f *Frontend) similarHandler(w http.ResponseWriter, r *http.Request) *response {
+	d := data{
+		Brand:     f.Brand,
+		MapBoxKey: f.MapBoxKey,
+		Context: &Context{
+			T: "images",
+		},
+		Results: Results{
+			Images: &img.Results{},
+		},
+	}
+
+	if err := applyPreferences(r); err != nil {
+		return &response{
+			status: http.StatusBadRequest,
+			err:    err,
+		}
+	}
+
+	d.Context.Safe, d.Context.SafeOn = safeParam(r)
+	d.Context.setTheme(r)
+
+	resp := &response{
+		status:   http.StatusOK,
+		data:     d,
+		template: "search",
+	}
+
+	if r.FormValue("o") == "json" {
+		resp.template = "json"
+	}
+
+	finder, ok := f.Images.Fetcher.(img.Finder)
+	if !ok {
+		resp.status, resp.err = http.StatusInternalServerError, errNoFinder
+		return resp
+	}
+
+	var hash string
+
+	switch r.Method {
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			resp.status, resp.err = http.StatusBadRequest, err
+			return resp
+		}
+		defer file.Close()
+
+		b, err := io.ReadAll(io.LimitReader(file, maxUpload))
+		if err != nil {
+			resp.status, resp.err = http.StatusBadRequest, err
+			return resp
+		}
+
+		m, err := img.Decode(b)
+		if err != nil {
+			resp.status, resp.err = http.StatusBadRequest, err
+			return resp
+		}
+
+		hash = fmt.Sprintf("%016x", img.DHash(m))
+		d.Context.Q = "uploaded image"
+	default:
+		id := strings.TrimSpace(r.FormValue("id"))
+		if id == "" {
+			resp.status, resp.err = http.StatusBadRequest, fmt.Errorf("missing id")
+			return resp
+		}
+
+		i, err := finder.Get(id)
+		if err != nil {
+			resp.status, resp.err = http.StatusBadRequest, err
+			return resp
+		}
+
+		hash = i.Hash
+		d.Context.Q = id
+	}
+
+	// the image hasn't been crawled yet
+	if hash == "" {
+		return resp
+	}
+
+	ir, err := finder.Similar(hash, d.Context.Safe, 100)
+	if err != nil {
+		resp.status, resp.err = http.StatusInternalServerError, err
+		return resp
+	}
+
+	if d.Context.Safe && f.Safety != nil {
+		lang, _, _ := f.Document.Matcher.Match(f.detectLanguage(r)...)
+		ir = f.safeImages(ir, lang)
+	}
+
+	f.encodeImages(ir)
+	d.Images = ir
+	resp.data = d
+	return resp
+}
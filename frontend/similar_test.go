@@ -0,0 +1,147 @@
+package frontend
+
+import (
+	"bytes"
+	goimage "image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	img "github.com/jonesrussell/jivesearch/search/image"
+	"golang.org/x/text/language"
+)
+
+type mockFinder struct {
+	images map[string]*img.Image
+	hash   string // the hash we were asked for
+}
+
+func (m *mockFinder) Fetch(q string, safe bool, number int, offset int, f img.Filters) (*img.Results, error) {
+	return &img.Results{}, nil
+}
+
+func (m *mockFinder) Get(id string) (*img.Image, error) {
+	i, ok := m.images[id]
+	if !ok {
+		return nil, io.EOF
+	}
+	return i, nil
+}
+
+func (m *mockFinder) Similar(hash string, safe bool, number int) (*img.Results, error) {
+	m.hash = hash
+	return &img.Results{}, nil
+}
+
+func upload(t *testing.T, b []byte) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("image", "upload.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/similar", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestSimilarHandler(t *testing.T) {
+	// black on the left and white on the right so no pixel is brighter than its right neighbor
+	m := goimage.NewGray(goimage.Rect(0, 0, 90, 40))
+	for x := 45; x < 90; x++ {
+		for y := 0; y < 40; y++ {
+			m.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name   string
+		req    func() *http.Request
+		status int
+		hash   string
+	}{
+		{
+			"indexed",
+			func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/similar?id=https://example.com/a.jpg", nil)
+			},
+			http.StatusOK,
+			"00000000000000ff",
+		},
+		{
+			"not crawled",
+			func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/similar?id=https://example.com/b.jpg", nil)
+			},
+			http.StatusOK,
+			"",
+		},
+		{
+			"missing id",
+			func() *http.Request { return httptest.NewRequest(http.MethodGet, "/similar", nil) },
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"upload",
+			func() *http.Request { return upload(t, buf.Bytes()) },
+			http.StatusOK,
+			"0000000000000000",
+		},
+		{
+			"not an image",
+			func() *http.Request { return upload(t, []byte("<html></html>")) },
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"decompression bomb",
+			func() *http.Request { return upload(t, []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")) },
+			http.StatusBadRequest,
+			"",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			finder := &mockFinder{
+				images: map[string]*img.Image{
+					"https://example.com/a.jpg": {ID: "https://example.com/a.jpg", Hash: "00000000000000ff"},
+					"https://example.com/b.jpg": {ID: "https://example.com/b.jpg"},
+				},
+			}
+
+			f := &Frontend{
+				Document: Document{
+					Matcher: language.NewMatcher([]language.Tag{language.English}),
+				},
+			}
+			f.Images.Fetcher = finder
+
+			resp := f.similarHandler(httptest.NewRecorder(), c.req())
+			if resp.status != c.status {
+				t.Fatalf("got %d; want %d (%v)", resp.status, c.status, resp.err)
+			}
+
+			if finder.hash != c.hash {
+				t.Fatalf("got hash %q; want %q", finder.hash, c.hash)
+			}
+		})
+	}
+}
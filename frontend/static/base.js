@@ -1,5 +1,44 @@
+// In POST mode the search form is POSTed so the params aren't in the url
+var isPost = function(){
+  var form = document.getElementById('form');
+  return form !== null && form.method.toLowerCase() === 'post';
+};
+
+// currentParams are the params of the current search, e.g. "?q=example&t=images"
+var currentParams = function(){
+  if (!isPost()){
+    return document.location.search;
+  }
+
+  var params = $('#form').find('input[type=hidden]').serialize();
+  var q = $('#query').data('query');
+  if (q !== undefined && q !== ''){
+    params = 'q=' + encodeURIComponent(q) + (params ? '&' + params : '');
+  }
+  return params ? '?' + params : '';
+};
+
+// formParams splits params into the [{name, value}] that $.param and our forms use
+var formParams = function(params){
+  var decode = function(s){
+    try {
+      return decodeURIComponent(s.replace(/\+/g, ' '));
+    } catch(e) { // changeParam doesn't encode its values
+      return s;
+    }
+  };
+
+  return $.map(params.replace(/^\?/, '').split('&'), function(p){
+    if (p === ''){
+      return null;
+    }
+    var kv = p.split('=');
+    return {name: decode(kv[0]), value: decode(kv.slice(1).join('='))};
+  });
+};
+
 var changeParam = function(key, value) {
-  var  urlQueryString = document.location.search,
+  var  urlQueryString = currentParams(),
        newParam = key + '=' + value,
        params = '?' + newParam;
 
@@ -21,7 +60,7 @@ var changeParam = function(key, value) {
 };
 
 var removeParam = function(key){
-  var  urlQueryString = document.location.search;
+  var  urlQueryString = currentParams();
   var removeRegex = new RegExp('([\?&])' + key + '=[^&;]+[&;]?');
   params = urlQueryString.replace(removeRegex, "$1");
   params = params.replace( /[&;]$/, "" );
@@ -29,7 +68,16 @@ var removeParam = function(key){
 };
 
 var redirect = function(params){
-  window.location.href = window.location.pathname + params;
+  if (!isPost()){
+    window.location.href = window.location.pathname + params;
+    return;
+  }
+
+  var form = $('<form method="POST"></form>').attr('action', window.location.pathname);
+  $.each(formParams(params), function(i, p){
+    form.append($('<input type="hidden">').attr('name', p.name).val(p.value));
+  });
+  form.appendTo('body').submit();
 };
 
 function isBang(item) {
@@ -59,7 +107,13 @@ $(document).ready(function() {
       },
       source: function(request, callback){
         $.getJSON('/autocomplete', {q: request.term}, function(data){ // '{q: request.term}' changes it from ?term=b to ?q=b so nginx doesn't log query.
-          callback(data.suggestions);
+          var entities = data.entities || {};
+          callback($.map(data.suggestions, function(s){
+            if (typeof s === "string" && entities.hasOwnProperty(s)){
+              return {label: s, value: s, entity: entities[s]};
+            }
+            return s;
+          }));
         });
       },
       select: function(event, ui){
@@ -98,6 +152,20 @@ $(document).ready(function() {
           formatted = '<a><img width="20" height="20" style="vertical-align:top;" src="' + item.favicon + '"/> ' + r + '</a>';
         }
 
+        // what (or who) the suggestion is about
+        if (item.entity){
+          var e = item.entity;
+          var img = "";
+          if (e.image){
+            img = '<img width="32" height="32" style="float:right;object-fit:cover;" src="' + $("<span/>").text(e.image).html() + '"/>';
+          }
+          var description = "";
+          if (e.description){
+            description = '<span style="font-weight:normal;color:#777;"> &mdash; ' + $("<span/>").text(e.description).html() + '</span>';
+          }
+          formatted = "<a>" + img + r + description + "</a>";
+        }
+
         return $("<li></li>").data("item.autocomplete", item).append(formatted).appendTo(ul);
       };
   });
@@ -248,7 +316,7 @@ function brave() {
 }
 
 var getUrlParameter = function getUrlParameter(sParam) {
-  var sPageURL = window.location.search.substring(1), sURLVariables = sPageURL.split('&'), sParameterName, i;
+  var sPageURL = currentParams().substring(1), sURLVariables = sPageURL.split('&'), sParameterName, i;
   for (i = 0; i < sURLVariables.length; i++) {
     sParameterName = sURLVariables[i].split('=');
     if (sParameterName[0] === sParam) {
@@ -82,10 +82,11 @@ $(document).ready(function() {
     if (isretry === true){ // were the initial results blank and this is just a retry?
       params = params + "&isretry=true";
     }
-    var u = window.location.pathname + params;
-    $.ajax({
-      url: u,
-    }).done(function(data) {
+    var req = {url: window.location.pathname + params};
+    if (isPost()){ // keep the query out of the url
+      req = {url: window.location.pathname, method: "POST", data: $.param(formParams(params))};
+    }
+    $.ajax(req).done(function(data) {
       $("#next_page").attr("data-page", data.search.next);
       var i;
       for (i = 0; i < data.search.documents.length; i++) {
@@ -117,6 +118,12 @@ $(document).ready(function() {
     redirect(params);
   });
   
+  // "more results from" a site
+  $(document).on('click', '.more_results', function(){
+    params = changeParam("q", $(this).data('query'));
+    redirect(params);
+  });
+
   // redirect "did you mean?" queries
   $("#alternative").on("click", function(){  
     params = changeParam("q", $(this).attr("data-alternative")); 
@@ -129,7 +136,7 @@ $(document).ready(function() {
   });
 
   $("#safe").on("click", function(){
-    var checked = $("#safe").is(':checked') ? "" : "f";
+    var checked = $("#safe").is(':checked') ? "t" : "f";
     params = changeParam("safe", checked);
     redirect(params);
   });
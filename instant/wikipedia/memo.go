@@ -0,0 +1,41 @@
+package wikipedia
+
+import (
+	"sync"
+	"time"
+)
+
+// memoSize is the most lookups a memo holds
+const memoSize = 100000
+
+// memoAge is how long a memo holds on to lookups, so a new dump is picked up
+const memoAge = 24 * time.Hour
+
+// memo remembers lookups in the dumps, which are asked for on every autocomplete
+// and search. A nil value remembers that there was nothing.
+// Rather than keeping track of what was used last it forgets everything
+// once it is full or old.
+type memo struct {
+	mu      sync.Mutex
+	m       map[string]interface{}
+	started time.Time
+}
+
+func (m *memo) get(k string) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.m[k]
+	return v, ok
+}
+
+func (m *memo) put(k string, v interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.m == nil || len(m.m) >= memoSize || time.Since(m.started) > memoAge {
+		m.m, m.started = map[string]interface{}{}, time.Now()
+	}
+
+	m.m[k] = v
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/suggest"
 	"github.com/lib/pq"
 	"golang.org/x/text/language"
 )
@@ -15,6 +16,8 @@ import (
 // PostgreSQL contains our client and database info
 type PostgreSQL struct {
 	*sql.DB
+	entities memo // lang:lowercase title -> suggest.Entity
+	synonyms memo // lang:word -> []string
 }
 
 type tableType = string
@@ -247,7 +250,7 @@ func (p *PostgreSQL) Fetch(query string, lang language.Tag) ([]*Item, error) {
 
 	// is it a disambiguation page???
 	if v, ok := item.Wikidata.Descriptions["en"]; ok {
-		if v.Text == "Wikipedia disambiguation page" || v.Text == "Wikimedia disambiguation page" {
+		if disambiguation(v.Text) {
 			dis := []string{}
 			lc := strings.ToLower(strings.Replace(item.Wikipedia.Title, " ", "_", -1))
 
@@ -306,8 +309,166 @@ func (p *PostgreSQL) Fetch(query string, lang language.Tag) ([]*Item, error) {
 	return []*Item{item}, err
 }
 
+func disambiguation(description string) bool {
+	return description == "Wikipedia disambiguation page" || description == "Wikimedia disambiguation page"
+}
+
 type transaction = func(tx *sql.Tx) error
 
+// Entities looks up the Wikipedia articles titled the same as the suggestions
+// along with their Wikidata label, description and first image.
+// Disambiguation pages are skipped.
+func (p *PostgreSQL) Entities(suggestions []string, lang language.Tag) (map[string]*suggest.Entity, error) {
+	base, _ := lang.Base()
+	l := base.String()
+
+	entities := map[string]*suggest.Entity{}
+	titles := map[string][]string{} // lowercase title -> suggestions
+	lower := []string{}             // the titles we haven't looked up yet
+	for _, s := range suggestions {
+		t := strings.ToLower(s)
+		if v, ok := p.entities.get(l + ":" + t); ok {
+			if e, ok := v.(suggest.Entity); ok {
+				entities[s] = &e // a copy as the caller may change it
+			}
+			continue
+		}
+
+		if _, ok := titles[t]; !ok {
+			lower = append(lower, t)
+		}
+		titles[t] = append(titles[t], s)
+	}
+
+	if len(lower) == 0 {
+		return entities, nil
+	}
+
+	// the most popular article when titles differ only by case
+	rows, err := p.DB.Query(fmt.Sprintf(`
+		SELECT DISTINCT ON (LOWER(w.title))
+			LOWER(w.title), w.id, coalesce(wd.labels->'%v'->>'value', w.title),
+			coalesce(wd.descriptions->'%v'->>'value', ''), coalesce(wd.descriptions->'en'->>'value', ''),
+			coalesce(build_image(wd.claims->'image')->>0, '')
+		FROM %vwikipedia w
+		LEFT JOIN wikidata wd ON w.id = wd.id
+		WHERE LOWER(w.title) = ANY($1)
+		ORDER BY LOWER(w.title), w.popularity_score DESC
+	`, l, l, l), pq.Array(lower))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var title, en string
+		e := suggest.Entity{}
+		if err := rows.Scan(&title, &e.ID, &e.Label, &e.Description, &en, &e.Image); err != nil {
+			return nil, err
+		}
+
+		found[title] = true
+		if disambiguation(en) {
+			p.entities.put(l+":"+title, nil)
+			continue
+		}
+
+		p.entities.put(l+":"+title, e)
+		for _, s := range titles[title] {
+			e := e
+			entities[s] = &e
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range lower {
+		if !found[t] {
+			p.entities.put(l+":"+t, nil)
+		}
+	}
+
+	return entities, nil
+}
+
+// Synonyms looks up the Wiktionary synonyms of words.
+// Only synonyms in the same language are kept.
+func (p *PostgreSQL) Synonyms(words []string, lang language.Tag) (map[string][]string, error) {
+	base, _ := lang.Base()
+	l := base.String()
+
+	synonyms := map[string][]string{}
+	lower := []string{} // the words we haven't looked up yet
+	for _, w := range words {
+		w = strings.ToLower(w)
+		if v, ok := p.synonyms.get(l + ":" + w); ok {
+			if sy, ok := v.([]string); ok {
+				synonyms[w] = append([]string{}, sy...)
+			}
+			continue
+		}
+		lower = append(lower, w)
+	}
+
+	if len(lower) == 0 {
+		return synonyms, nil
+	}
+
+	rows, err := p.DB.Query(
+		fmt.Sprintf(`SELECT "title", "definitions" FROM %vwiktionary WHERE "title" = ANY($1)`, l),
+		pq.Array(lower),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string][]string{}
+	for rows.Next() {
+		var title, definitions string
+		if err := rows.Scan(&title, &definitions); err != nil {
+			return nil, err
+		}
+
+		defs := []*Definition{}
+		if err := json.Unmarshal([]byte(definitions), &defs); err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{title: true}
+		for _, d := range defs {
+			for _, sy := range d.Synonyms {
+				w := strings.ToLower(strings.TrimSpace(sy.Word))
+				if sy.Language != l || w == "" || seen[w] || strings.Contains(w, ":") { // e.g. Thesaurus:car
+					continue
+				}
+				seen[w] = true
+				found[title] = append(found[title], w)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, w := range lower {
+		sy, ok := found[w]
+		if !ok {
+			p.synonyms.put(l+":"+w, nil)
+			continue
+		}
+
+		p.synonyms.put(l+":"+w, sy)
+		synonyms[w] = append([]string{}, sy...)
+	}
+
+	return synonyms, nil
+}
+
 func (p *PostgreSQL) executeTransaction(t transaction) (err error) {
 	tx, err := p.DB.Begin()
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/jonesrussell/jivesearch/suggest"
 	"github.com/lib/pq"
 	"golang.org/x/text/language"
 	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v2"
@@ -131,6 +132,134 @@ func TestPostgreSQL_Fetch(t *testing.T) {
 	}
 }
 
+func TestPostgreSQL_Synonyms(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"title", "definitions"}).
+		AddRow("car", []byte(`[
+			{"part": "noun", "synonyms": [{"word": "automobile", "language": "en"}, {"word": "Thesaurus:car", "language": "en"}]},
+			{"part": "noun", "synonyms": [{"word": "Automobile", "language": "en"}, {"word": "voiture", "language": "fr"}, {"word": "auto", "language": "en"}]}
+		]`)).
+		AddRow("nyc", []byte(`[{"part": "proper noun", "synonyms": [{"word": "new york city", "language": "en"}]}]`))
+
+	mock.ExpectQuery(`SELECT "title", "definitions" FROM enwiktionary`).
+		WithArgs(pq.Array([]string{"car", "nyc", "rental"})).
+		WillReturnRows(rows)
+
+	p := &PostgreSQL{DB: db}
+
+	got, err := p.Synonyms([]string{"car", "NYC", "rental"}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"car": {"automobile", "auto"},
+		"nyc": {"new york city"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	// only the words we haven't seen are looked up again
+	mock.ExpectQuery(`SELECT "title", "definitions" FROM enwiktionary`).
+		WithArgs(pq.Array([]string{"bus"})).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "definitions"}))
+
+	got, err = p.Synonyms([]string{"car", "rental", "bus"}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = map[string][]string{
+		"car": {"automobile", "auto"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	if _, err := p.Synonyms([]string{"car", "bus"}, language.AmericanEnglish); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgreSQL_Entities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hendrix := &suggest.Entity{
+		ID:          "Q5928",
+		Label:       "Jimi Hendrix",
+		Description: "American guitarist",
+		Image:       "https://upload.wikimedia.org/wikipedia/commons/a/ae/Jimi_Hendrix_1967.png",
+	}
+
+	rows := sqlmock.NewRows([]string{"title", "id", "label", "description", "en", "image"}).
+		AddRow("jimi hendrix", hendrix.ID, hendrix.Label, hendrix.Description, hendrix.Description, hendrix.Image).
+		AddRow("mercury", "Q3409", "Mercury", "Wikimedia disambiguation page", "Wikimedia disambiguation page", "")
+
+	mock.ExpectQuery(`FROM enwikipedia w\s+LEFT JOIN wikidata wd ON w.id = wd.id`).
+		WithArgs(pq.Array([]string{"jimi hendrix", "mercury", "jimi hendrix songs"})).
+		WillReturnRows(rows)
+
+	p := &PostgreSQL{DB: db}
+
+	got, err := p.Entities([]string{"jimi hendrix", "Mercury", "jimi hendrix songs", "Jimi Hendrix"}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]*suggest.Entity{
+		"jimi hendrix": hendrix,
+		"Jimi Hendrix": hendrix,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	got["jimi hendrix"].Image = "" // shouldn't change what is remembered
+
+	// only the titles we haven't seen are looked up again
+	mock.ExpectQuery(`FROM enwikipedia w\s+LEFT JOIN wikidata wd ON w.id = wd.id`).
+		WithArgs(pq.Array([]string{"hendrix"})).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "id", "label", "description", "en", "image"}))
+
+	got, err = p.Entities([]string{"Jimi Hendrix", "mercury", "jimi hendrix songs", "hendrix"}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = map[string]*suggest.Entity{
+		"Jimi Hendrix": hendrix,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	if _, err := p.Entities([]string{"hendrix", "Mercury"}, language.AmericanEnglish); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestPostgreSQL_Dump(t *testing.T) {
 	type args struct {
 		lang language.Tag
@@ -0,0 +1,294 @@
+// Package safety flags unsafe (e.g. adult) words and phrases in queries, results and image alt text.
+//
+// Lists live in a directory with a file per language named after its base language ("en.txt")
+// and "all.txt" for every language. A line of a list is one of:
+//
+//	# a comment
+//	a blocked word or phrase
+//	*a blocked word or phrase that can be part of a longer word*
+//	/a blocked regular expression/
+//	!an allowed word or phrase (overrides the blocked ones within it)
+//
+// Matching is case-insensitive. Words and phrases only match whole words
+// unless they are in a script written without spaces (e.g. Chinese or Thai).
+// A "*" at the start (or end) lets other letters come before (or after) it,
+// so "*porn*" catches "freeporn" and "pornhub" while "ass" leaves "class" alone.
+package safety
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/afero"
+	"golang.org/x/text/language"
+)
+
+var appFs = afero.NewOsFs()
+
+// all is the list for every language
+const all = "all"
+
+// Filter holds the lists of a directory
+type Filter struct {
+	Dir      string
+	mu       sync.RWMutex
+	lists    map[string]*list // language -> its list
+	modified map[string]time.Time
+}
+
+type list struct {
+	blocked []entry
+	regexps []*regexp.Regexp
+	allowed []entry
+}
+
+// entry is a word or phrase of a list
+type entry struct {
+	phrase string
+	before bool // it can have other letters before it
+	after  bool // or after it
+}
+
+func newEntry(line string) entry {
+	e := entry{}
+	line = strings.ToLower(strings.TrimSpace(line))
+	if strings.HasPrefix(line, "*") {
+		e.before, line = true, strings.TrimSpace(line[1:])
+	}
+	if strings.HasSuffix(line, "*") {
+		e.after, line = true, strings.TrimSpace(line[:len(line)-1])
+	}
+
+	e.phrase = line
+	return e
+}
+
+// New loads the lists in dir
+func New(dir string) (*Filter, error) {
+	f := &Filter{Dir: dir}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Reload reads the lists again if any of them changed and tells us if they did.
+// We keep the old lists if the new ones have an error.
+func (f *Filter) Reload() (bool, error) {
+	files, err := afero.ReadDir(appFs, f.Dir)
+	if err != nil {
+		return false, err
+	}
+
+	modified := map[string]time.Time{}
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".txt" {
+			continue
+		}
+		modified[fi.Name()] = fi.ModTime()
+	}
+
+	if len(modified) == 0 {
+		return false, fmt.Errorf("no lists in %v", f.Dir)
+	}
+
+	f.mu.RLock()
+	changed := len(modified) != len(f.modified)
+	for name, t := range modified {
+		if !f.modified[name].Equal(t) {
+			changed = true
+		}
+	}
+	f.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	lists := map[string]*list{}
+	for name := range modified {
+		lang := strings.TrimSuffix(name, ".txt")
+		if lang != all {
+			if _, err := language.ParseBase(lang); err != nil {
+				return false, fmt.Errorf("%v: %v is not a language", name, lang)
+			}
+		}
+
+		fh, err := appFs.Open(filepath.Join(f.Dir, name))
+		if err != nil {
+			return false, err
+		}
+
+		l, err := parse(fh)
+		fh.Close()
+		if err != nil {
+			return false, fmt.Errorf("%v: %v", name, err)
+		}
+
+		lists[lang] = l
+	}
+
+	f.mu.Lock()
+	f.lists, f.modified = lists, modified
+	f.mu.Unlock()
+
+	return true, nil
+}
+
+func parse(r io.Reader) (*list, error) {
+	l := &list{}
+	seen := map[string]bool{} // lists tend to repeat themselves
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+		case len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/"):
+			re, err := regexp.Compile("(?i)" + line[1:len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			l.regexps = append(l.regexps, re)
+		case strings.HasPrefix(line, "!"):
+			if a := newEntry(line[1:]); a.phrase != "" {
+				l.allowed = append(l.allowed, a)
+			}
+		default:
+			if b := newEntry(line); b.phrase != "" {
+				l.blocked = append(l.blocked, b)
+			}
+		}
+	}
+
+	return l, scanner.Err()
+}
+
+// Unsafe indicates if text in the language has a blocked word, phrase or
+// regular expression that isn't part of an allowed phrase
+func (f *Filter) Unsafe(s string, lang language.Tag) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	lists := []*list{f.lists[all]}
+	if b, conf := lang.Base(); conf >= language.High {
+		lists = append(lists, f.lists[b.String()])
+	}
+
+	s = strings.ToLower(s)
+
+	// the spans of the allowed phrases
+	allowed := [][]int{}
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+		for _, a := range l.allowed {
+			allowed = append(allowed, find(s, a)...)
+		}
+	}
+
+	unsafe := func(spans [][]int) bool {
+		for _, sp := range spans {
+			if !within(sp, allowed) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+
+		for _, b := range l.blocked {
+			if unsafe(find(s, b)) {
+				return true
+			}
+		}
+
+		for _, re := range l.regexps {
+			if unsafe(re.FindAllStringIndex(s, -1)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// find returns the spans of the entry in s.
+// Only whole words count unless the entry says otherwise
+// or is in a script written without spaces.
+func find(s string, e entry) [][]int {
+	spans := [][]int{}
+	phrase := e.phrase
+	if phrase == "" {
+		return spans
+	}
+
+	whole := !unspaced(phrase)
+	for i := 0; i <= len(s)-len(phrase); {
+		j := strings.Index(s[i:], phrase)
+		if j < 0 {
+			break
+		}
+
+		start, end := i+j, i+j+len(phrase)
+		if !whole || ((e.before || !wordBefore(s[:start])) && (e.after || !wordAfter(s[end:]))) {
+			spans = append(spans, []int{start, end})
+		}
+
+		i = start + 1
+	}
+
+	return spans
+}
+
+func within(span []int, spans [][]int) bool {
+	for _, sp := range spans {
+		if span[0] >= sp[0] && span[1] <= sp[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func word(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func wordBefore(s string) bool {
+	r, n := utf8.DecodeLastRuneInString(s)
+	return n > 0 && word(r)
+}
+
+func wordAfter(s string) bool {
+	r, n := utf8.DecodeRuneInString(s)
+	return n > 0 && word(r)
+}
+
+// unspaced indicates if s is in a script that doesn't put spaces between words
+func unspaced(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package safety
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"golang.org/x/text/language"
+)
+
+func mockLists(t *testing.T, lists map[string]string) {
+	for name, l := range lists {
+		if err := afero.WriteFile(appFs, "lists/"+name, []byte(l), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUnsafe(t *testing.T) {
+	appFs = afero.NewMemMapFs()
+	mockLists(t, map[string]string{
+		"all.txt": "# a comment that should be skipped\nnaughty\nbad phrase\n色情\n/x{3,}/\n*porn*\nfap*\n*shit\n",
+		"en.txt":  "ass\n!naughty by nature\n",
+		"fr.txt":  "merde\n",
+	})
+
+	f, err := New("lists")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		s    string
+		lang language.Tag
+		want bool
+	}{
+		{"this is a safe phrase", language.English, false},
+		{"naughty", language.English, true},
+		{"the bad bAD pHrase here", language.English, true},
+		{"bad", language.English, false},
+		{"class assignment", language.English, false}, // whole words only
+		{"kick ass", language.English, true},
+		{"kick ass", language.French, false},
+		{"merde", language.French, true},
+		{"merde", language.English, false},
+		{"naughty by nature albums", language.English, false}, // allowed
+		{"naughty by nature", language.French, true},
+		{"naughty naughty by nature", language.English, true},
+		{"免费色情电影", language.Chinese, true}, // no spaces between words
+		{"XXX movies", language.English, true},
+		{"naughty", language.Und, true},
+		{"kick ass", language.Und, false},
+		{"freeporn videos", language.English, true}, // part of a longer word
+		{"pornhub", language.English, true},
+		{"free porn", language.English, true},
+		{"fapping", language.English, true},
+		{"unfap", language.English, false},
+		{"bullshit", language.English, true},
+		{"shitake mushrooms", language.English, false},
+	} {
+		t.Run(c.s, func(t *testing.T) {
+			if got := f.Unsafe(c.s, c.lang); got != c.want {
+				t.Fatalf("got %t; want %t", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	l, err := parse(strings.NewReader("naughty\n*porn*\nnaughty\n!nice\n!nice\n/x{3,}/\n/x{3,}/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &list{
+		blocked: []entry{{phrase: "naughty"}, {phrase: "porn", before: true, after: true}},
+		allowed: []entry{{phrase: "nice"}},
+	}
+
+	if !reflect.DeepEqual(l.blocked, want.blocked) || !reflect.DeepEqual(l.allowed, want.allowed) || len(l.regexps) != 1 {
+		t.Fatalf("got %+v; want %+v", l, want)
+	}
+}
+
+func TestReload(t *testing.T) {
+	appFs = afero.NewMemMapFs()
+	mockLists(t, map[string]string{"all.txt": "naughty\n"})
+
+	f, err := New("lists")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := f.Reload(); err != nil || changed {
+		t.Fatalf("got %t, %v; want false, nil", changed, err)
+	}
+
+	mockLists(t, map[string]string{"all.txt": "rude\n"})
+	if err := appFs.Chtimes("lists/all.txt", time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := f.Reload(); err != nil || !changed {
+		t.Fatalf("got %t, %v; want true, nil", changed, err)
+	}
+
+	if f.Unsafe("naughty", language.English) || !f.Unsafe("rude", language.English) {
+		t.Fatal("expected the new list")
+	}
+
+	// a broken list keeps the old one
+	mockLists(t, map[string]string{"en.txt": "/(/\n"})
+	if _, err := f.Reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !f.Unsafe("rude", language.English) {
+		t.Fatal("expected the old list")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		lists map[string]string
+	}{
+		{"missing", nil},
+		{"empty", map[string]string{"README.md": "lists go here"}},
+		{"language", map[string]string{"english.txt": "naughty\n"}},
+		{"regexp", map[string]string{"all.txt": "/[a-/\n"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			appFs = afero.NewMemMapFs()
+			mockLists(t, c.lists)
+
+			if _, err := New("lists"); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLists(t *testing.T) {
+	appFs = afero.NewOsFs()
+
+	f, err := New("lists")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.Unsafe("nude", language.English) || f.Unsafe("sex education", language.English) || f.Unsafe("data analysis", language.English) {
+		t.Fatal("unexpected lists")
+	}
+
+	for _, s := range []string{"freeporn", "pornhub", "youporn"} {
+		if !f.Unsafe(s, language.English) {
+			t.Fatalf("%q should be unsafe", s)
+		}
+	}
+
+	for _, s := range []string{"class schedule", "title", "scunthorpe", "peacock"} {
+		if f.Unsafe(s, language.English) {
+			t.Fatalf("%q should be safe", s)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package alias keeps Elasticsearch indices versioned behind an alias
+// so a mapping can change without downtime.
+//
+// We read and write through the alias ("search-english") and the documents live in
+// a version of it ("search-english-v2"). A migration creates the next version with the
+// new mapping, fills it and then atomically points the alias at it.
+// The old version is kept so a bad migration can be rolled back by pointing the alias
+// at it again, until Cleanup deletes it.
+// An index created before we used aliases is version 0 and is replaced by the migration
+// as it has the name the alias needs.
+package alias
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonesrussell/jivesearch/log"
+	"github.com/olivere/elastic/v7"
+)
+
+// Version is the name of version n of the alias, e.g. "search-english-v2"
+func Version(alias string, n int) string {
+	return alias + "-v" + strconv.Itoa(n)
+}
+
+// Current is the index behind the alias and its version.
+// The index is "" if there is neither an alias nor an old index of that name.
+func Current(client *elastic.Client, alias string) (string, int, error) {
+	exists, err := client.IndexExists(alias).Do(context.TODO())
+	if err != nil || !exists {
+		return "", 0, err
+	}
+
+	res, err := client.Aliases().Index(alias).Do(context.TODO())
+	if err != nil {
+		return "", 0, err
+	}
+
+	indices := res.IndicesByAlias(alias)
+	switch len(indices) {
+	case 0:
+		return alias, 0, nil // an index from before aliases
+	case 1:
+	default:
+		return "", 0, fmt.Errorf("alias %q points to %d indices", alias, len(indices))
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(indices[0], alias+"-v"))
+	if err != nil {
+		return "", 0, fmt.Errorf("alias %q points to unversioned index %q", alias, indices[0])
+	}
+
+	return indices[0], n, nil
+}
+
+// Create creates version 1 of the alias with the mapping
+// unless the alias (or an old index of that name) already exists.
+func Create(client *elastic.Client, alias, mapping string) error {
+	current, _, err := Current(client, alias)
+	if err != nil || current != "" {
+		return err
+	}
+
+	idx, err := Next(client, alias, mapping, 1)
+	if err != nil {
+		return err
+	}
+
+	return Swap(client, alias, "", idx)
+}
+
+// Next creates version n of the alias with the mapping.
+// The alias still points to the current version until Swap.
+// A version n left over from a setup or migration that didn't finish is replaced.
+func Next(client *elastic.Client, alias, mapping string, n int) (string, error) {
+	idx := Version(alias, n)
+	log.Info.Println("Creating index:", idx)
+
+	_, err := client.CreateIndex(idx).Body(mapping).Do(context.TODO())
+	switch {
+	case err == nil:
+		return idx, nil
+	case !exists(err):
+		return "", err
+	}
+
+	if err := dropLeftover(client, idx); err != nil {
+		return "", err
+	}
+
+	if _, err := client.CreateIndex(idx).Body(mapping).Do(context.TODO()); err != nil {
+		return "", err
+	}
+
+	return idx, nil
+}
+
+// exists returns true if err is Elasticsearch telling us the index already exists
+func exists(err error) bool {
+	e, ok := err.(*elastic.Error)
+	return ok && e.Details != nil && e.Details.Type == "resource_already_exists_exception"
+}
+
+// dropLeftover deletes an index no alias points to.
+// An index with an alias is in use so we leave it alone.
+func dropLeftover(client *elastic.Client, idx string) error {
+	res, err := client.Aliases().Index(idx).Do(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if aliases := res.Indices[idx].Aliases; len(aliases) > 0 {
+		return fmt.Errorf("index %q already exists and is aliased as %q", idx, aliases[0].AliasName)
+	}
+
+	log.Info.Println("Deleting leftover index:", idx)
+	_, err = client.DeleteIndex(idx).Do(context.TODO())
+	return err
+}
+
+// Reindex copies the documents of one index into another
+func Reindex(client *elastic.Client, from, to string) (int64, error) {
+	res, err := client.Reindex().
+		SourceIndex(from).
+		DestinationIndex(to).
+		WaitForCompletion(true).
+		Refresh("true").
+		Do(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(res.Failures) > 0 {
+		return res.Created, fmt.Errorf("reindexing %v to %v: %d failures", from, to, len(res.Failures))
+	}
+
+	return res.Created, nil
+}
+
+// Swap moves the alias from the old index to the new one in a single request
+// so searches always see exactly one of them. The old version is kept (see Cleanup)
+// unless it is an index from before aliases.
+func Swap(client *elastic.Client, alias, old, idx string) error {
+	actions := []elastic.AliasAction{}
+	switch old {
+	case "":
+	case alias:
+		actions = append(actions, elastic.NewAliasRemoveIndexAction(old))
+	default:
+		actions = append(actions, elastic.NewAliasRemoveAction(alias).Index(old))
+	}
+	actions = append(actions, elastic.NewAliasAddAction(alias).Index(idx))
+
+	_, err := client.Alias().Action(actions...).Do(context.TODO())
+	return err
+}
+
+// Migrate moves the alias to a new version with the mapping, copying over the documents.
+// Documents written to the alias while we reindex may be lost so pause the writers first.
+func Migrate(client *elastic.Client, alias, mapping string) (string, error) {
+	old, n, err := Current(client, alias)
+	if err != nil {
+		return "", err
+	}
+
+	idx, err := Next(client, alias, mapping, n+1)
+	if err != nil {
+		return "", err
+	}
+
+	if old != "" {
+		cnt, err := Reindex(client, old, idx)
+		if err != nil {
+			return "", err
+		}
+		log.Info.Printf("Reindexed %d documents from %v to %v\n", cnt, old, idx)
+	}
+
+	return idx, Swap(client, alias, old, idx)
+}
+
+// Cleanup deletes the versions older than the one the alias points to and returns them.
+// A newer version is left alone as it may be a migration in progress (or rolled back).
+func Cleanup(client *elastic.Client, alias string) ([]string, error) {
+	current, n, err := Current(client, alias)
+	if err != nil || current == "" {
+		return nil, err
+	}
+
+	res, err := client.Aliases().Index(alias + "-v*").Do(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	var old []string
+	for idx := range res.Indices {
+		v, err := strconv.Atoi(strings.TrimPrefix(idx, alias+"-v"))
+		if err != nil || !strings.HasPrefix(idx, alias+"-v") || v >= n {
+			continue
+		}
+		old = append(old, idx)
+	}
+
+	sort.Strings(old)
+
+	for _, idx := range old {
+		log.Info.Println("Deleting old index:", idx)
+		if _, err := client.DeleteIndex(idx).Do(context.TODO()); err != nil {
+			return nil, err
+		}
+	}
+
+	return old, nil
+}
@@ -0,0 +1,253 @@
+package alias
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+)
+
+func TestVersion(t *testing.T) {
+	if got := Version("search-english", 2); got != "search-english-v2" {
+		t.Fatalf("got %q; want %q", got, "search-english-v2")
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		exists  bool
+		aliases string
+		index   string
+		version int
+		err     bool
+	}{
+		{name: "missing"},
+		{name: "unaliased", exists: true, aliases: `{"images": {"aliases": {}}}`, index: "images"},
+		{name: "versioned", exists: true, aliases: `{"images-v4": {"aliases": {"images": {}}}}`, index: "images-v4", version: 4},
+		{name: "unversioned", exists: true, aliases: `{"pictures": {"aliases": {"images": {}}}}`, err: true},
+		{
+			name: "several", exists: true,
+			aliases: `{"images-v1": {"aliases": {"images": {}}}, "images-v2": {"aliases": {"images": {}}}}`,
+			err:     true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !c.exists {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(c.aliases))
+			}))
+			defer ts.Close()
+
+			index, version, err := Current(mockClient(t, ts.URL), "images")
+			if (err != nil) != c.err {
+				t.Fatalf("got error %v; want error %v", err, c.err)
+			}
+
+			if index != c.index || version != c.version {
+				t.Fatalf("got %q v%d; want %q v%d", index, version, c.index, c.version)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		aliases string
+		index   string
+		actions string
+	}{
+		{
+			name:    "unaliased",
+			aliases: `{"images": {"aliases": {}}}`,
+			index:   "images-v1",
+			actions: `{"actions":[{"remove_index":{"index":"images"}},{"add":{"alias":"images","index":"images-v1"}}]}`,
+		},
+		{
+			name:    "versioned",
+			aliases: `{"images-v1": {"aliases": {"images": {}}}}`,
+			index:   "images-v2",
+			actions: `{"actions":[{"remove":{"alias":"images","index":"images-v1"}},{"add":{"alias":"images","index":"images-v2"}}]}`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var created, reindexed, actions string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+
+				switch {
+				case r.URL.Path == "/images/_alias":
+					w.Write([]byte(c.aliases))
+				case r.Method == http.MethodPut:
+					created = r.URL.Path
+					w.Write([]byte(`{"acknowledged": true}`))
+				case r.URL.Path == "/_reindex":
+					reindexed = string(b)
+					w.Write([]byte(`{"created": 10}`))
+				case r.URL.Path == "/_aliases":
+					actions = string(b)
+					w.Write([]byte(`{"acknowledged": true}`))
+				}
+			}))
+			defer ts.Close()
+
+			index, err := Migrate(mockClient(t, ts.URL), "images", `{}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if index != c.index || created != "/"+c.index {
+				t.Fatalf("got %q (created %q); want %q", index, created, c.index)
+			}
+
+			if reindexed == "" {
+				t.Fatal("expected the documents to be reindexed")
+			}
+
+			if actions != c.actions {
+				t.Fatalf("got %v; want %v", actions, c.actions)
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		aliases string
+		deleted []string
+	}{
+		{name: "missing"},
+		{
+			name:    "versions",
+			aliases: `{"images-v3": {"aliases": {"images": {}}}}`,
+			deleted: []string{"/images-v1", "/images-v2"},
+		},
+		{
+			name:    "unaliased",
+			aliases: `{"images": {"aliases": {}}}`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var deleted []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead && c.aliases == "":
+					http.NotFound(w, r)
+				case r.URL.Path == "/images/_alias":
+					w.Write([]byte(c.aliases))
+				case r.URL.Path == "/images-v*/_alias":
+					// a migration in progress and an index of another alias
+					w.Write([]byte(`{
+						"images-v1": {"aliases": {}},
+						"images-v2": {"aliases": {}},
+						"images-v3": {"aliases": {"images": {}}},
+						"images-v4": {"aliases": {}},
+						"images-vintage": {"aliases": {"vintage": {}}}
+					}`))
+				case r.Method == http.MethodDelete:
+					deleted = append(deleted, r.URL.Path)
+					w.Write([]byte(`{"acknowledged": true}`))
+				}
+			}))
+			defer ts.Close()
+
+			old, err := Cleanup(mockClient(t, ts.URL), "images")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(old) != len(c.deleted) || len(deleted) != len(c.deleted) {
+				t.Fatalf("got %v (deleted %v); want %v", old, deleted, c.deleted)
+			}
+
+			for i, idx := range deleted {
+				if idx != c.deleted[i] || "/"+old[i] != idx {
+					t.Fatalf("got %v (deleted %v); want %v", old, deleted, c.deleted)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateFailures(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/_alias":
+			w.Write([]byte(`{"images-v1": {"aliases": {"images": {}}}}`))
+		case "/_aliases":
+			t.Fatal("the alias should not be swapped")
+		case "/_reindex":
+			w.Write([]byte(`{"created": 9, "failures": [{"id": "1"}]}`))
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := Migrate(mockClient(t, ts.URL), "images", `{}`); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNext(t *testing.T) {
+	const leftover = `{"error": {"type": "resource_already_exists_exception", "reason": "index [images-v2] already exists"}, "status": 400}`
+
+	for _, c := range []struct {
+		name     string
+		existing bool
+		aliases  string
+		deleted  bool
+		err      bool
+	}{
+		{name: "new"},
+		{name: "leftover", existing: true, aliases: `{"images-v2": {"aliases": {}}}`, deleted: true},
+		{name: "in use", existing: true, aliases: `{"images-v2": {"aliases": {"pictures": {}}}}`, err: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			existing, deleted := c.existing, false
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPut && existing:
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(leftover))
+				case r.Method == http.MethodPut:
+					w.Write([]byte(`{"acknowledged": true}`))
+				case r.URL.Path == "/images-v2/_alias":
+					w.Write([]byte(c.aliases))
+				case r.Method == http.MethodDelete:
+					existing, deleted = false, true
+					w.Write([]byte(`{"acknowledged": true}`))
+				}
+			}))
+			defer ts.Close()
+
+			idx, err := Next(mockClient(t, ts.URL), "images", `{}`, 2)
+			if (err != nil) != c.err {
+				t.Fatalf("got error %v; want error %v", err, c.err)
+			}
+
+			if deleted != c.deleted {
+				t.Fatalf("got deleted %v; want %v", deleted, c.deleted)
+			}
+
+			if !c.err && idx != "images-v2" {
+				t.Fatalf("got %q; want %q", idx, "images-v2")
+			}
+		})
+	}
+}
+
+func mockClient(t *testing.T, url string) *elastic.Client {
+	client, err := elastic.NewSimpleClient(elastic.SetURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
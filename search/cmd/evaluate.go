@@ -0,0 +1,148 @@
+// Command evaluate scores ranking profiles against a judged query set.
+//
+//	evaluate <judgments.csv> [profiles.json]
+//
+// Each line of the judgments is query,url,grade where a grade of 0 is not relevant
+// and higher grades are more relevant. Every profile (the built-in ones plus any in
+// profiles.json) runs the queries against elasticsearch.search.index and we print
+// NDCG, MRR and precision of the top 10 results for each.
+//
+// The queries are searched like the frontend searches them for a user of the configured
+// languages with the default (moderate) safe search.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jonesrussell/jivesearch/config"
+	"github.com/jonesrussell/jivesearch/search"
+	"github.com/jonesrussell/jivesearch/search/document"
+	"github.com/jonesrussell/jivesearch/search/langid"
+	"github.com/olivere/elastic/v7"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+// k is how many results of each query we score
+const k = 10
+
+func setup(v *viper.Viper) {
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	config.SetDefaults(v)
+}
+
+func main() {
+	v := viper.New()
+	setup(v)
+
+	if err := run(v, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(v *viper.Viper, args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return errors.New("usage: evaluate <judgments.csv> [profiles.json]")
+	}
+
+	js, err := judgments(args[0])
+	if err != nil {
+		return err
+	}
+
+	profiles := search.Profiles
+	if len(args) > 1 {
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if profiles, err = search.LoadProfiles(f); err != nil {
+			return err
+		}
+	}
+
+	client, err := elastic.NewClient(
+		elastic.SetURL(v.GetString("elasticsearch.url")),
+		elastic.SetSniff(false),
+	)
+	if err != nil {
+		return err
+	}
+
+	e := &search.ElasticSearch{
+		ElasticSearch: &document.ElasticSearch{
+			Client: client,
+			Index:  v.GetString("elasticsearch.search.index"),
+			Type:   v.GetString("elasticsearch.search.type"),
+		},
+	}
+
+	return evaluate(e, profiles, js, languages(v), w)
+}
+
+func judgments(name string) ([]search.Judgment, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return search.ReadJudgments(f)
+}
+
+// evaluate prints a row for each profile, in name order
+func evaluate(e *search.ElasticSearch, profiles map[string]*search.Profile, js []search.Judgment, langs []language.Tag, w io.Writer) error {
+	m := language.NewMatcher(langs)
+	options := func(q string) search.Options {
+		return search.NewOptions(q, search.TimeRange{}, search.Languages(langs, langid.Detect(q), m), time.Now())
+	}
+
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "profile\tqueries\tNDCG@%d\tMRR\tP@%d\n", k, k)
+
+	for _, name := range names {
+		e.Profile = profiles[name]
+
+		ev, err := search.Evaluate(e, js, k, search.Moderate, langs[0], language.Region{}, options)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+
+		fmt.Fprintf(tw, "%v\t%d\t%.4f\t%.4f\t%.4f\n", name, ev.Queries, ev.NDCG, ev.MRR, ev.Precision)
+	}
+
+	return tw.Flush()
+}
+
+// languages are our configured languages, English if there are none
+func languages(v *viper.Viper) []language.Tag {
+	langs := []language.Tag{}
+	for _, l := range v.GetStringSlice("languages") {
+		if t, err := language.Parse(l); err == nil {
+			langs = append(langs, t)
+		}
+	}
+
+	if len(langs) == 0 {
+		return []language.Tag{language.English}
+	}
+
+	return langs
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/search"
+	"github.com/jonesrussell/jivesearch/search/document"
+	"github.com/olivere/elastic/v7"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+func TestLanguages(t *testing.T) {
+	v := viper.New()
+	setup(v)
+
+	if got := languages(v); !reflect.DeepEqual(got, []language.Tag{language.English}) {
+		t.Fatalf("got %v; want %v", got, language.English)
+	}
+
+	v.Set("languages", []string{"fr", "nope", "de"})
+	if got, want := languages(v), []language.Tag{language.French, language.German}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hits":{"total":1,"hits":[{"_id":"https://www.bobdylan.com/","_source":{"title":"Bob Dylan"}}]}}`))
+	}))
+	defer ts.Close()
+
+	client, err := elastic.NewSimpleClient(elastic.SetURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &search.ElasticSearch{
+		ElasticSearch: &document.ElasticSearch{Client: client, Index: "search"},
+	}
+
+	profiles, err := search.LoadProfiles(strings.NewReader(`{"titles": {"fields": {"title": 3}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js := []search.Judgment{{Query: "bob dylan", URL: "https://www.bobdylan.com", Grade: 3}}
+
+	var buf bytes.Buffer
+	if err := evaluate(e, profiles, js, []language.Tag{language.English}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "profile  queries  NDCG@10  MRR     P@10\n" +
+		"default  1        1.0000   1.0000  0.1000\n" +
+		"titles   1        1.0000   1.0000  0.1000\n"
+
+	if got := buf.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,13 +1,40 @@
-// Command crawler demonstrates how to run the crawler
+// Command crawler demonstrates how to run the crawler.
+//
+// Without a command it crawls from crawler.seeds for crawler.time.
+// The other commands are operational tools for the queue and index:
+//
+//	crawler enqueue <url|domain>...   add links to the queue
+//	crawler fetch <url>               crawl a link now and print the parsed document
+//	crawler queue [n]                 print the queue size and a sample of n links
+//	crawler release <url|host>...     clear a host's reservation/delay
+//	crawler robots <url>              print the robots.txt decision for a link
+//	crawler reparse <file.warc.gz>... index the documents archived in WARC files
+//	crawler synonyms                  reload elasticsearch.synonyms without reindexing
+//	crawler migrate <index> [file.warc.gz...]
+//	                                  move search, images, robots or queries to a new
+//	                                  version with the current mapping
+//	crawler cleanup <index>           delete the versions of an index older than its current one
+//
+// An index is an alias of its current version so a migration swaps in the new one
+// without downtime. The documents are reindexed from the old version or, for search,
+// parsed again from the WARC files given. Pause the crawler while migrating.
+// The old version is kept so a bad migration can be rolled back by pointing
+// the alias at it again. Run cleanup once the new version is known to be good.
+// The !bangs index is rebuilt each time the frontend starts.
+//
+// Set crawler.warc.dir to archive the raw responses of fetch and the crawl.
+// The --debug, --time and --workers flags may come before or after the command.
 package main
 
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,12 +43,16 @@ import (
 	"github.com/garyburd/redigo/redis"
 	"github.com/jonesrussell/jivesearch/config"
 	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/jonesrussell/jivesearch/search/crawler"
 	"github.com/jonesrussell/jivesearch/search/crawler/queue"
 	"github.com/jonesrussell/jivesearch/search/crawler/robots"
+	"github.com/jonesrussell/jivesearch/search/crawler/warc"
 	"github.com/jonesrussell/jivesearch/search/document"
 	img "github.com/jonesrussell/jivesearch/search/image"
+	"github.com/jonesrussell/jivesearch/suggest"
 	"github.com/olivere/elastic/v7"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -45,12 +76,18 @@ var (
 	duration time.Duration
 )
 
-func setup(v *viper.Viper) {
+// setup configures the crawler and returns the command to run and its arguments
+func setup(v *viper.Viper, osArgs []string) (string, []string, error) {
 	// v.SetEnvPrefix("PP")
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	config.SetDefaults(v)
 
+	cmd, args, err := command(v, osArgs)
+	if err != nil {
+		return "", nil, err
+	}
+
 	if v.GetBool("debug") {
 		log.Debug.SetOutput(os.Stdout)
 	}
@@ -81,11 +118,303 @@ func setup(v *viper.Viper) {
 		},
 		Timeout: v.GetDuration("crawler.timeout"),
 	}
+
+	return cmd, args, nil
+}
+
+// command parses the flags on the command line into v
+// and splits what is left into a command and its arguments
+func command(v *viper.Viper, args []string) (string, []string, error) {
+	fs := pflag.NewFlagSet("crawler", pflag.ContinueOnError)
+	fs.Int("workers", v.GetInt("crawler.workers"), "number of workers")
+	fs.Duration("time", v.GetDuration("crawler.time"), "duration the crawler should run")
+	fs.Bool("debug", v.GetBool("debug"), "turn on debug output")
+
+	for key, name := range map[string]string{
+		"crawler.workers": "workers",
+		"crawler.time":    "time",
+		"debug":           "debug",
+	} {
+		if err := v.BindPFlag(key, fs.Lookup(name)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+
+	if fs.NArg() == 0 {
+		return "", nil, nil
+	}
+
+	return fs.Arg(0), fs.Args()[1:], nil
+}
+
+// seed turns a bare domain (e.g. "example.com") into a link
+func seed(s string) string {
+	if !strings.Contains(s, "://") {
+		s = "https://" + s + "/"
+	}
+	return s
+}
+
+func enqueue(q queue.Queuer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("enqueue requires at least one url or domain")
+	}
+
+	for _, a := range args {
+		doc, err := document.New(seed(a))
+		if err != nil {
+			return fmt.Errorf("%q: %v", a, err)
+		}
+
+		if err := q.AddLink(doc.ID, ""); err != nil {
+			return err
+		}
+
+		fmt.Printf("queued %v\n", doc.ID)
+	}
+
+	return nil
+}
+
+func inspect(rds *queue.Redis, args []string) error {
+	n := 10
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("invalid sample size %q", args[0])
+		}
+	}
+
+	cnt, err := rds.CountLinks()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d links queued\n", cnt)
+
+	lnks, err := rds.Links(n)
+	if err != nil {
+		return err
+	}
+
+	for _, lnk := range lnks {
+		fmt.Println(lnk)
+	}
+
+	return nil
+}
+
+func release(rds *queue.Redis, args []string) error {
+	if len(args) == 0 {
+		return errors.New("release requires at least one url or host")
+	}
+
+	for _, a := range args {
+		doc, err := document.New(seed(a))
+		if err != nil {
+			return fmt.Errorf("%q: %v", a, err)
+		}
+
+		sh := doc.SchemeHost()
+		ttl, err := rds.HostTTL(sh)
+		if err != nil {
+			return err
+		}
+
+		if err := rds.ReleaseHost(sh); err != nil {
+			return err
+		}
+
+		fmt.Printf("released %v (%v remaining)\n", sh, ttl)
+	}
+
+	return nil
+}
+
+func fetch(args []string) error {
+	if len(args) != 1 {
+		return errors.New("fetch requires exactly one url")
+	}
+
+	doc, err := c.Crawl(seed(args[0]))
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("language: %v\n%s\n", doc.Language, b)
+	return nil
+}
+
+func decide(args []string) error {
+	if len(args) != 1 {
+		return errors.New("robots requires exactly one url")
+	}
+
+	d, err := c.Decide(seed(args[0]))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("host: %v\nstatus: %d\ncached: %v\nexpires: %v\nallowed: %v\ncrawl-delay: %v\n\n%v\n",
+		d.SchemeHost, d.StatusCode, d.Cached, d.Expires, d.Allowed, d.CrawlDelay, d.Body)
+	return nil
+}
+
+func reparse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("reparse requires at least one warc file")
+	}
+
+	for _, a := range args {
+		f, err := os.Open(a)
+		if err != nil {
+			return err
+		}
+
+		n, err := c.Reparse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", a, err)
+		}
+
+		fmt.Printf("%v: %d documents\n", a, n)
+	}
+
+	return nil
+}
+
+func migrate(v *viper.Viper, es *document.ElasticSearch, bulk *elastic.BulkProcessor, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate requires an index: search, images, robots or queries")
+	}
+
+	var idx string
+	var err error
+
+	switch args[0] {
+	case "search":
+		var fill func(*document.ElasticSearch) error
+		if len(args) > 1 {
+			// parse the archived documents again into the new version
+			fill = func(next *document.ElasticSearch) error {
+				c.Backend = &crawler.ElasticSearch{ElasticSearch: next, Bulk: bulk}
+				if err := reparse(args[1:]); err != nil {
+					return err
+				}
+				return bulk.Flush()
+			}
+		}
+
+		var n int
+		if n, err = es.Migrate(fill); err == nil {
+			idx = fmt.Sprintf("version %d of its language indices", n)
+		}
+	case "images":
+		idx, err = c.ImageBackend.(*img.ElasticSearch).Migrate()
+	case "robots":
+		idx, err = c.Robots.(*robots.ElasticSearch).Migrate()
+	case "queries":
+		q := &suggest.ElasticSearch{
+			Client: es.Client,
+			Index:  v.GetString("elasticsearch.query.index"),
+			Type:   v.GetString("elasticsearch.query.type"),
+		}
+		idx, err = q.Migrate()
+	default:
+		return fmt.Errorf("unknown index %q", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%v is now %v\n", args[0], idx)
+	fmt.Printf("the old version is kept until \"crawler cleanup %v\"\n", args[0])
+	return nil
+}
+
+func cleanup(v *viper.Viper, es *document.ElasticSearch, args []string) error {
+	if len(args) == 0 {
+		return errors.New("cleanup requires an index: search, images, robots or queries")
+	}
+
+	var old []string
+	var err error
+
+	switch args[0] {
+	case "search":
+		old, err = es.Cleanup()
+	case "images":
+		old, err = alias.Cleanup(es.Client, c.ImageBackend.(*img.ElasticSearch).Index)
+	case "robots":
+		old, err = alias.Cleanup(es.Client, c.Robots.(*robots.ElasticSearch).Index)
+	case "queries":
+		old, err = alias.Cleanup(es.Client, v.GetString("elasticsearch.query.index"))
+	default:
+		return fmt.Errorf("unknown index %q", args[0])
+	}
+
+	for _, idx := range old {
+		fmt.Println("deleted", idx)
+	}
+
+	return err
 }
 
 func main() {
 	v := viper.New()
-	setup(v)
+	cmd, args, err := setup(v, os.Args[1:])
+	if err != nil {
+		log.Info.Fatalf("%+v", err)
+	}
+
+	if err := run(v, cmd, args); err != nil {
+		log.Info.Fatalf("%+v", err)
+	}
+}
+
+func run(v *viper.Viper, cmd string, args []string) error {
+	// Setup our queue
+	rds := &queue.Redis{
+		RedisPool: &redis.Pool{
+			MaxIdle:     v.GetInt("crawler.workers"),
+			MaxActive:   v.GetInt("crawler.workers"),
+			IdleTimeout: 10 * time.Second,
+			Wait:        true,
+			Dial: func() (redis.Conn, error) {
+				cl, err := redis.Dial("tcp", fmt.Sprintf("%v:%v", v.GetString("redis.host"), v.GetString("redis.port")))
+				if err != nil {
+					return nil, err
+				}
+				return cl, err
+			},
+		},
+	}
+
+	defer rds.RedisPool.Close()
+	c.Queue = rds
+
+	// these commands only need the queue
+	switch cmd {
+	case "enqueue":
+		return enqueue(rds, args)
+	case "queue":
+		return inspect(rds, args)
+	case "release":
+		return release(rds, args)
+	case "", "fetch", "robots", "reparse", "synonyms", "migrate", "cleanup":
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
 
 	// setup Elasticsearch
 	// Note: for remote URLs I can't seem to get it to work with sniffing on
@@ -107,7 +436,7 @@ func main() {
 		elastic.SetSniff(false),
 	)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	bulk, err := client.BulkProcessor().
@@ -116,20 +445,27 @@ func main() {
 		Do(context.Background())
 
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	defer bulk.Close()
 
 	// setup our search index
+	es := &document.ElasticSearch{
+		Client:   client,
+		Index:    v.GetString("elasticsearch.search.index"),
+		Type:     v.GetString("elasticsearch.search.type"),
+		Synonyms: v.GetString("elasticsearch.synonyms"),
+	}
+
 	c.Backend = &crawler.ElasticSearch{
-		ElasticSearch: &document.ElasticSearch{Client: client, Index: v.GetString("elasticsearch.search.index"), Type: v.GetString("elasticsearch.search.type")},
+		ElasticSearch: es,
 		Bulk:          bulk,
 		Mutex:         sync.Mutex{},
 	}
 
 	if err := c.Backend.Setup(); err != nil {
-		panic(err)
+		return err
 	}
 
 	// setup our image index
@@ -141,7 +477,7 @@ func main() {
 	}
 
 	if err := c.ImageBackend.Setup(); err != nil {
-		panic(err)
+		return err
 	}
 
 	// Setup our robots.txt cache
@@ -154,38 +490,45 @@ func main() {
 
 	exists, err := c.Robots.IndexExists()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	if !exists {
 		if err := c.Robots.Setup(); err != nil {
-			panic(err)
+			return err
 		}
 	}
 
-	// Setup our queue
-	rds := &queue.Redis{
-		RedisPool: &redis.Pool{
-			MaxIdle:     v.GetInt("crawler.workers"),
-			MaxActive:   v.GetInt("crawler.workers"),
-			IdleTimeout: 10 * time.Second,
-			Wait:        true,
-			Dial: func() (redis.Conn, error) {
-				cl, err := redis.Dial("tcp", fmt.Sprintf("%v:%v", v.GetString("redis.host"), v.GetString("redis.port")))
-				if err != nil {
-					return nil, err
-				}
-				return cl, err
-			},
-		},
+	switch cmd {
+	case "robots":
+		return decide(args)
+	case "reparse":
+		return reparse(args)
+	case "synonyms":
+		return es.ReloadSynonyms()
+	case "migrate":
+		return migrate(v, es, bulk, args)
+	case "cleanup":
+		return cleanup(v, es, args)
 	}
 
-	defer rds.RedisPool.Close()
-	c.Queue = rds
+	if dir := v.GetString("crawler.warc.dir"); dir != "" {
+		w := &warc.Writer{
+			Dir:     dir,
+			Prefix:  v.GetString("crawler.useragent.short"),
+			MaxSize: v.GetInt64("crawler.warc.max.bytes"),
+			Gzip:    v.GetBool("crawler.warc.gzip"),
+		}
 
-	defer c.Close()
+		defer w.Close()
+		c.Archiver = w
+	}
 
-	if err := c.Start(duration); err != nil {
-		log.Info.Fatalf("%+v", err)
+	if cmd == "fetch" {
+		return fetch(args)
 	}
+
+	defer c.Close()
+
+	return c.Start(duration)
 }
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 func TestSetup(t *testing.T) {
 	v := viper.New()
-	setup(v)
+	if _, _, err := setup(v, nil); err != nil {
+		t.Fatal(err)
+	}
 
 	if c == nil {
 		t.Fatalf("c is nil")
@@ -18,3 +22,104 @@ func TestSetup(t *testing.T) {
 		t.Fatalf("expected non zero duration. got %v", duration)
 	}
 }
+
+func TestCommand(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+		cmd  string
+		want []string
+		time time.Duration
+		err  bool
+	}{
+		{"empty", []string{}, "", nil, 0, false},
+		{"flags only", []string{"--debug", "--time", "5m"}, "", nil, 5 * time.Minute, false},
+		{"enqueue", []string{"enqueue", "example.com", "https://www.example.org/path"}, "enqueue", []string{"example.com", "https://www.example.org/path"}, 0, false},
+		{"trailing flags", []string{"fetch", "example.com", "--debug"}, "fetch", []string{"example.com"}, 0, false},
+		{"leading flags", []string{"--time=1m", "fetch", "example.com"}, "fetch", []string{"example.com"}, time.Minute, false},
+		{"unknown flag", []string{"-x", "fetch"}, "", nil, 0, true},
+		{"no args", []string{"queue"}, "queue", []string{}, 0, false},
+		{"migrate", []string{"migrate", "search", "crawl-0.warc.gz"}, "migrate", []string{"search", "crawl-0.warc.gz"}, 0, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			v := viper.New()
+			cmd, args, err := command(v, c.args)
+			if (err != nil) != c.err {
+				t.Fatalf("got error %v; want error %v", err, c.err)
+			}
+
+			if got := v.GetDuration("crawler.time"); got != c.time {
+				t.Fatalf("got time %v; want %v", got, c.time)
+			}
+
+			if cmd != c.cmd {
+				t.Fatalf("got %q; want %q", cmd, c.cmd)
+			}
+
+			if !reflect.DeepEqual(args, c.want) {
+				t.Fatalf("got %q; want %q", args, c.want)
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want string
+	}{
+		{"example.com", "https://example.com/"},
+		{"http://www.example.com/path", "http://www.example.com/path"},
+	} {
+		t.Run(c.s, func(t *testing.T) {
+			if got := seed(c.s); got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	q := &mockQueue{}
+	if err := enqueue(q, []string{"example.com", "http://www.example.org/path#frag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"https://example.com/", "http://www.example.org/path"}
+	if !reflect.DeepEqual(q.links, want) {
+		t.Fatalf("got %q; want %q", q.links, want)
+	}
+
+	if err := enqueue(q, nil); err == nil {
+		t.Fatal("expected an error for no arguments")
+	}
+}
+
+type mockQueue struct {
+	links []string
+}
+
+func (q *mockQueue) Inlinks(lnk string) (int, error) {
+	return 0, nil
+}
+
+func (q *mockQueue) CountLinks() (int64, error) {
+	return int64(len(q.links)), nil
+}
+
+func (q *mockQueue) AddLink(lnk, from string) error {
+	q.links = append(q.links, lnk)
+	return nil
+}
+
+func (q *mockQueue) QueueLink(ttl time.Duration) (string, error) {
+	return "", nil
+}
+
+func (q *mockQueue) ReserveHost(host string, ttl time.Duration) error {
+	return nil
+}
+
+func (q *mockQueue) DelayHost(host string, ttl time.Duration) error {
+	return nil
+}
@@ -2,9 +2,12 @@
 package crawler
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,6 +19,7 @@ import (
 	"github.com/jonesrussell/jivesearch/log"
 	"github.com/jonesrussell/jivesearch/search/crawler/queue"
 	"github.com/jonesrussell/jivesearch/search/crawler/robots"
+	"github.com/jonesrussell/jivesearch/search/crawler/warc"
 	"github.com/pkg/errors"
 	"github.com/temoto/robotstxt"
 
@@ -35,8 +39,9 @@ type Crawler struct {
 	maxLinks       int           // max links to extract from a document
 	maxDomainLinks int           // max links to store for a domain by default
 	truncate
-	Robots robots.Cacher
-	Queue  queue.Queuer
+	Robots   robots.Cacher
+	Queue    queue.Queuer
+	Archiver Archiver
 	channels
 	wg    sync.WaitGroup
 	stats *Stats
@@ -45,7 +50,7 @@ type Crawler struct {
 }
 
 type channels struct {
-	links  chan string
+	links  chan link
 	images chan *img.Image
 	ch     chan string
 	cancel chan bool
@@ -77,11 +82,21 @@ type ImageBackend interface {
 	Upsert(*img.Image) error
 }
 
+// Archiver records the raw responses we crawl (e.g. to WARC files)
+type Archiver interface {
+	Archive(req *http.Request, resp *http.Response, body []byte) error
+}
+
 var now = func() time.Time { return time.Now().UTC() }
 
 // RobotsPath is robots.txt path
 var RobotsPath, _ = url.Parse("/robots.txt")
 
+// ErrDisallowed indicates robots.txt doesn't allow us to crawl a link
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+var errUnsupportedMIME = errors.New("unsupported mime type")
+
 // New creates a Crawler from a config Provider
 func New(cfg config.Provider) *Crawler {
 	return &Crawler{
@@ -103,7 +118,7 @@ func New(cfg config.Provider) *Crawler {
 			description: cfg.GetInt("crawler.truncate.description"),
 		},
 		channels: channels{
-			links:  make(chan string),
+			links:  make(chan link),
 			images: make(chan *img.Image),
 			ch:     make(chan string),
 			cancel: make(chan bool),
@@ -128,7 +143,7 @@ func (c *Crawler) Start(t time.Duration) error {
 
 	go func() {
 		for _, lnk := range c.seeds {
-			c.links <- lnk
+			c.links <- link{url: lnk}
 		}
 
 		for worker := 0; worker < c.workers; worker++ {
@@ -162,8 +177,8 @@ func (c *Crawler) Start(t time.Duration) error {
 
 func (c *Crawler) linkHandler() {
 	for lnk := range c.links {
-		if err := c.Queue.AddLink(lnk); err != nil {
-			c.err <- errors.Wrapf(err, "%q", lnk)
+		if err := c.Queue.AddLink(lnk.url, lnk.from); err != nil {
+			c.err <- errors.Wrapf(err, "%q", lnk.url)
 			return
 		}
 	}
@@ -249,21 +264,26 @@ func (c *Crawler) work(lnk string) {
 
 	doc.SetStatusCode(-1).SetCrawled(now())
 
-	rbt := c.fetchRobots(doc)
-	rbtsText, err := robotstxt.FromStatusAndString(rbt.StatusCode, rbt.Body)
+	// a robots cache error only skips this link rather than stopping the crawl
+	rbt, err := c.fetchRobots(doc)
+	if err != nil {
+		log.Info.Println(err)
+		return
+	}
+
+	d, err := c.decide(rbt, doc.URL.Path)
 	if err != nil {
 		delay = 600 * time.Second
 		return
 	}
 
-	group := rbtsText.FindGroup(c.UserAgent.Full)
-	if !group.Test(doc.URL.Path) {
+	if !d.Allowed {
 		return
 	}
 
-	delay = group.CrawlDelay
+	delay = d.CrawlDelay
 
-	resp, err := c.doRequest(doc.ID)
+	resp, err := c.fetch(doc.ID)
 	if err != nil {
 		log.Info.Println(err)
 		return
@@ -276,68 +296,320 @@ func (c *Crawler) work(lnk string) {
 	ra = resp.Header.Get("Retry-After")
 
 	if doc.StatusCode == http.StatusOK {
-		var b io.Reader = resp.Body
-		if c.maxBytes > -1 {
-			b = io.LimitReader(b, c.maxBytes)
+		maxLinks, err := c.linkLimit()
+		if err != nil {
+			log.Debug.Printf("unable to count links in queue: %v\n%v", doc.ID, err)
+			return
 		}
 
-		err = doc.SetHeader(resp.Header).
-			SetPolicyFromHeader(c.UserAgent.Short).
-			SetTokenizer(b)
-
+		doc, err = c.parse(doc, resp.Header, resp.Body, maxLinks, c.links, c.images)
 		if err != nil {
 			log.Debug.Printf("document parsing error: %v\n%v", doc.ID, err)
 			return
 		}
+	}
 
-		// TODO: image (& video?) search and extract some of the text of pdf files.
-		// Note: some html is mismarked as text/xml
-		if doc.MIME != "text/plain" && doc.MIME != "text/html" && doc.MIME != "text/xml" {
-			return
+	if doc.Inlinks, err = c.Queue.Inlinks(doc.ID); err != nil {
+		log.Debug.Printf("unable to count inbound links: %v\n%v", doc.ID, err)
+	}
+
+	if err := c.Backend.Upsert(doc); err != nil {
+		c.err <- errors.Wrapf(err, "unable to insert doc: %v", doc.ID)
+		return
+	}
+}
+
+// Crawl immediately fetches, parses and saves a single link. Unlike the
+// workers started by Start, it ignores host reservations and the recrawl
+// interval but still obeys robots.txt. Links and images found on the page
+// are added to the queue and image backend.
+func (c *Crawler) Crawl(lnk string) (*document.Document, error) {
+	doc, err := document.New(lnk)
+	if err != nil {
+		return nil, errors.Wrapf(err, "link: %q", lnk)
+	}
+
+	doc.SetStatusCode(-1).SetCrawled(now())
+
+	d, err := c.Decide(lnk)
+	if err != nil {
+		return doc, err
+	}
+
+	if !d.Allowed {
+		return doc, ErrDisallowed
+	}
+
+	resp, err := c.fetch(doc.ID)
+	if err != nil {
+		return doc, err
+	}
+
+	defer resp.Body.Close()
+
+	c.stats.Update(resp.StatusCode)
+	doc.SetStatusCode(resp.StatusCode)
+
+	if doc.StatusCode == http.StatusOK {
+		maxLinks, err := c.linkLimit()
+		if err != nil {
+			return doc, errors.Wrap(err, "unable to count links in queue")
 		}
 
-		queueCnt, err := c.Queue.CountLinks()
+		doc, err = c.parseAndCollect(doc, resp.Header, resp.Body, maxLinks, true)
 		if err != nil {
-			log.Debug.Printf("unable to count links in queue: %v\n%v", doc.ID, err)
-			return
+			return doc, err
+		}
+	}
+
+	if doc.Inlinks, err = c.Queue.Inlinks(doc.ID); err != nil {
+		return doc, errors.Wrap(err, "unable to count inbound links")
+	}
+
+	if err := c.Backend.Upsert(doc); err != nil {
+		return doc, errors.Wrapf(err, "unable to insert doc: %v", doc.ID)
+	}
+
+	return doc, nil
+}
+
+// Reparse saves the documents of the response records in a WARC file as
+// if they were just crawled, dated when they were archived. Nothing is
+// fetched and the links found are not queued. It returns the number of
+// documents saved.
+func (c *Crawler) Reparse(r io.Reader) (int, error) {
+	wr, err := warc.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for {
+		rec, err := wr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
 		}
 
-		maxLinks := c.maxLinks
-		if queueCnt > c.maxQueueLinks {
-			maxLinks = 0
+		if rec.Type() != "response" {
+			continue
 		}
 
-		if err := doc.SetContent(c.UserAgent.Short, maxLinks, c.links, c.images,
-			c.truncate.title, c.truncate.keywords, c.truncate.description); err != nil {
-			log.Debug.Printf("document parsing error: %v\n%v", doc.ID, err)
+		doc, err := c.reparse(rec)
+		if err != nil {
+			log.Debug.Printf("document parsing error: %v\n", err)
+			continue
 		}
 
-		// don't index content if not wanted or if not canonical
-		if doc.SetCanonical(c.links); !doc.Canonical || !doc.Index {
-			doc = &document.Document{
-				ID:      doc.ID,
-				Crawled: doc.Crawled,
-				Content: document.Content{
-					StatusCode: doc.StatusCode,
-					Language:   doc.Language,
-				},
+		if err := c.Backend.Upsert(doc); err != nil {
+			return n, errors.Wrapf(err, "unable to insert doc: %v", doc.ID)
+		}
+
+		n++
+	}
+}
+
+func (c *Crawler) reparse(rec *warc.Record) (*document.Document, error) {
+	lnk := rec.Header.Get("WARC-Target-URI")
+	doc, err := document.New(lnk)
+	if err != nil {
+		return nil, errors.Wrapf(err, "link: %q", lnk)
+	}
+
+	crawled, err := time.Parse(time.RFC3339, rec.Header.Get("WARC-Date"))
+	if err != nil {
+		crawled = now()
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Content)), nil)
+	if err != nil {
+		return doc, errors.Wrapf(err, "link: %q", lnk)
+	}
+
+	defer resp.Body.Close()
+
+	doc.SetStatusCode(resp.StatusCode).SetCrawled(crawled)
+
+	if doc.StatusCode != http.StatusOK {
+		return doc, nil
+	}
+
+	return c.parseAndCollect(doc, resp.Header, resp.Body, 0, false)
+}
+
+// parseAndCollect parses a document outside of the crawl loop. The images found
+// are saved and, if queueLinks is set, the links are added to our queue.
+func (c *Crawler) parseAndCollect(doc *document.Document, h http.Header, body io.Reader, maxLinks int,
+	queueLinks bool) (*document.Document, error) {
+
+	links, images := make(chan link), make(chan *img.Image)
+	collected := make(chan error)
+	go func() {
+		collected <- c.collect(links, images, queueLinks)
+	}()
+
+	doc, err := c.parse(doc, h, body, maxLinks, links, images)
+	close(links)
+	close(images)
+
+	if cErr := <-collected; cErr != nil {
+		return doc, cErr
+	}
+
+	return doc, err
+}
+
+// collect saves the links and images sent by parse until both channels are closed
+func (c *Crawler) collect(links chan link, images chan *img.Image, queueLinks bool) error {
+	var err error
+
+	for links != nil || images != nil {
+		select {
+		case lnk, ok := <-links:
+			if !ok {
+				links = nil
+				continue
+			}
+			if err == nil && queueLinks {
+				err = errors.Wrapf(c.Queue.AddLink(lnk.url, lnk.from), "%q", lnk.url)
+			}
+		case im, ok := <-images:
+			if !ok {
+				images = nil
+				continue
+			}
+			if err == nil {
+				err = errors.Wrapf(c.ImageBackend.Upsert(im), "unable to insert image: %v", im.ID)
 			}
 		}
 	}
 
-	if err := c.Backend.Upsert(doc); err != nil {
-		c.err <- errors.Wrapf(err, "unable to insert doc: %v", doc.ID)
-		return
+	return err
+}
+
+// linkLimit is the max number of links to extract from a document.
+// We stop extracting links once our queue is full.
+func (c *Crawler) linkLimit() (int, error) {
+	queueCnt, err := c.Queue.CountLinks()
+	if err != nil {
+		return 0, err
+	}
+
+	if queueCnt > c.maxQueueLinks {
+		return 0, nil
+	}
+
+	return c.maxLinks, nil
+}
+
+// link is a link to queue and the page it was found on ("" for a seed)
+type link struct {
+	url  string
+	from string
+}
+
+// parse reads the body of a 200 response into doc. Links and images found
+// are sent to the links and images channels. A non-nil error means the
+// document should not be saved.
+func (c *Crawler) parse(doc *document.Document, h http.Header, body io.Reader, maxLinks int,
+	links chan link, images chan *img.Image) (*document.Document, error) {
+
+	found, done := make(chan string), make(chan struct{})
+	go func(from string) {
+		for lnk := range found {
+			links <- link{url: lnk, from: from}
+		}
+		close(done)
+	}(doc.ID)
+
+	defer func() {
+		close(found)
+		<-done
+	}()
+
+	if c.maxBytes > -1 {
+		body = io.LimitReader(body, c.maxBytes)
+	}
+
+	err := doc.SetHeader(h).
+		SetPolicyFromHeader(c.UserAgent.Short).
+		SetTokenizer(body)
+
+	if err != nil {
+		return doc, err
+	}
+
+	// TODO: image (& video?) search and extract some of the text of pdf files.
+	// Note: some html is mismarked as text/xml
+	if doc.MIME != "text/plain" && doc.MIME != "text/html" && doc.MIME != "text/xml" {
+		return doc, errUnsupportedMIME
+	}
+
+	if err := doc.SetContent(c.UserAgent.Short, maxLinks, found, images,
+		c.truncate.title, c.truncate.keywords, c.truncate.description); err != nil {
+		log.Debug.Printf("document parsing error: %v\n%v", doc.ID, err)
+	}
+
+	// don't index content if not wanted or if not canonical
+	if doc.SetCanonical(found); !doc.Canonical || !doc.Index {
+		doc = &document.Document{
+			ID:      doc.ID,
+			Crawled: doc.Crawled,
+			Content: document.Content{
+				StatusCode: doc.StatusCode,
+				Language:   doc.Language,
+			},
+		}
 	}
+
+	return doc, nil
+}
+
+// Decision is the robots.txt verdict for a single link
+type Decision struct {
+	*robots.Robots
+	Allowed    bool
+	CrawlDelay time.Duration
+}
+
+// Decide fetches (or pulls from cache) the robots.txt file for
+// a link's host and tests the link's path against our useragent.
+func (c *Crawler) Decide(lnk string) (*Decision, error) {
+	doc, err := document.New(lnk)
+	if err != nil {
+		return nil, errors.Wrapf(err, "link: %q", lnk)
+	}
+
+	rbt, err := c.fetchRobots(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decide(rbt, doc.URL.Path)
+}
+
+func (c *Crawler) decide(rbt *robots.Robots, path string) (*Decision, error) {
+	d := &Decision{Robots: rbt}
+
+	txt, err := robotstxt.FromStatusAndString(rbt.StatusCode, rbt.Body)
+	if err != nil {
+		return d, errors.Wrapf(err, "unable to parse robots.txt for %v", rbt.SchemeHost)
+	}
+
+	group := txt.FindGroup(c.UserAgent.Full)
+	d.Allowed = group.Test(path)
+	d.CrawlDelay = group.CrawlDelay
+	return d, nil
 }
 
 // fetchRobots fetches and caches the robots.txt file
-func (c *Crawler) fetchRobots(doc *document.Document) *robots.Robots {
+func (c *Crawler) fetchRobots(doc *document.Document) (*robots.Robots, error) {
 	sh := doc.SchemeHost()
 	rbt, err := c.Robots.Get(sh)
 	if err != nil {
-		c.err <- errors.Wrapf(err, "cannot get robots.txt from cache for %v", sh)
-		return rbt
+		return rbt, errors.Wrapf(err, "cannot get robots.txt from cache for %v", sh)
 	}
 
 	// check if the robots are expired
@@ -345,8 +617,7 @@ func (c *Crawler) fetchRobots(doc *document.Document) *robots.Robots {
 	if rbt.Cached {
 		expired, err = rbt.Expired()
 		if err != nil {
-			c.err <- errors.Wrapf(err, "unable to determine expiration for cached robots.txt %v", sh)
-			return rbt
+			return rbt, errors.Wrapf(err, "unable to determine expiration for cached robots.txt %v", sh)
 		}
 	}
 
@@ -355,7 +626,7 @@ func (c *Crawler) fetchRobots(doc *document.Document) *robots.Robots {
 		resp, err := c.doRequest(u.String())
 		if err != nil {
 			log.Info.Println(err)
-			return rbt
+			return rbt, nil
 		}
 
 		defer resp.Body.Close()
@@ -370,14 +641,14 @@ func (c *Crawler) fetchRobots(doc *document.Document) *robots.Robots {
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			if err := rbt.SetBody(resp.Body); err != nil {
 				log.Debug.Println(errors.Wrapf(err, "error in reading robots.txt file for %v", sh))
-				return rbt
+				return rbt, nil
 			}
 		}
 
 		c.Robots.Put(rbt)
 	}
 
-	return rbt
+	return rbt, nil
 }
 
 func calculateHostDelay(status int, retry string, delay time.Duration) time.Duration {
@@ -431,6 +702,15 @@ func calculateHostDelay(status int, retry string, delay time.Duration) time.Dura
 }
 
 func (c *Crawler) doRequest(u string) (*http.Response, error) {
+	req, err := c.newRequest(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.HTTPClient.Do(req)
+}
+
+func (c *Crawler) newRequest(u string) (*http.Request, error) {
 	// Note: Transport automatically adds "Accept-Encoding: gzip"
 	// and transparently decodes response UNLESS you manually
 	// set the "Accept-Encoding" header.
@@ -441,7 +721,40 @@ func (c *Crawler) doRequest(u string) (*http.Response, error) {
 	}
 
 	req.Header.Set("User-Agent", c.UserAgent.Full)
-	return c.HTTPClient.Do(req)
+	return req, nil
+}
+
+// fetch requests a page and archives the response. When archiving, the body
+// (up to maxBytes) is read up front and replaced so it can still be parsed.
+func (c *Crawler) fetch(u string) (*http.Response, error) {
+	req, err := c.newRequest(u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil || c.Archiver == nil {
+		return resp, err
+	}
+
+	var b io.Reader = resp.Body
+	if c.maxBytes > -1 {
+		b = io.LimitReader(b, c.maxBytes)
+	}
+
+	body, err := ioutil.ReadAll(b)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err := c.Archiver.Archive(req, resp, body); err != nil {
+		log.Info.Println(errors.Wrapf(err, "unable to archive %v", u))
+	}
+
+	return resp, nil
 }
 
 // Close the crawler
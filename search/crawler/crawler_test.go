@@ -2,9 +2,12 @@ package crawler
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -15,6 +18,7 @@ import (
 
 	"github.com/jarcoal/httpmock"
 	"github.com/jonesrussell/jivesearch/search/crawler/robots"
+	"github.com/jonesrussell/jivesearch/search/crawler/warc"
 	"github.com/spf13/pflag"
 )
 
@@ -118,7 +122,7 @@ func TestStart(t *testing.T) {
 			description: 250,
 		},
 		channels: channels{
-			links:  make(chan string),
+			links:  make(chan link),
 			images: make(chan *img.Image),
 			ch:     make(chan string),
 			cancel: make(chan bool),
@@ -194,7 +198,7 @@ func TestWork(t *testing.T) {
 					description: 250,
 				},
 				channels: channels{
-					links:  make(chan string),
+					links:  make(chan link),
 					ch:     make(chan string),
 					cancel: make(chan bool),
 					err:    make(chan error),
@@ -246,6 +250,263 @@ func TestWork(t *testing.T) {
 	httpmock.Reset()
 }
 
+func TestWorkRobotsError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	lnk := "https://www.example.com/"
+	httpmock.RegisterResponder("GET", lnk, httpmock.NewStringResponder(200, "<html></html>"))
+
+	cr := &Crawler{
+		HTTPClient: http.DefaultClient,
+		since:      45 * 24 * time.Hour,
+		channels: channels{
+			err: make(chan error),
+		},
+		stats:   &Stats{Start: now(), StatusCodes: make(map[int]int64)},
+		Queue:   &mockQueue{},
+		Backend: &mockBackend{},
+		Robots:  &errRobotsCache{},
+	}
+
+	done := make(chan bool)
+	go func() {
+		cr.work(lnk)
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case err := <-cr.err:
+		t.Fatalf("expected the link to be skipped; got %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker is stuck")
+	}
+
+	if n := httpmock.GetCallCountInfo()["GET "+lnk]; n != 0 {
+		t.Fatalf("got %d requests; want 0", n)
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for _, c := range []struct {
+		name   string
+		lnk    string
+		rbts   string
+		body   string
+		links  int
+		images int
+		err    error
+	}{
+		{
+			name:   "allowed",
+			lnk:    "https://www.example1.com/",
+			rbts:   "User-agent: *\nAllow: /",
+			body:   `<html><head><title>hello</title></head><body><a href="/about">about</a><img src="/logo.png" alt="logo"></body></html>`,
+			links:  1,
+			images: 1,
+		},
+		{
+			name: "disallowed",
+			lnk:  "https://www.example2.com/private",
+			rbts: "User-agent: *\nDisallow: /private",
+			err:  ErrDisallowed,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cr := &Crawler{
+				HTTPClient: http.DefaultClient,
+				UserAgent: UserAgent{
+					Full:  "test-bot-full",
+					Short: "test-bot-short",
+				},
+				maxQueueLinks: 1000,
+				maxLinks:      10,
+				maxBytes:      int64(len(c.body)), // httpmock rewinds the body after EOF
+
+				truncate: truncate{
+					title:       100,
+					keywords:    25,
+					description: 250,
+				},
+				stats: &Stats{Start: now(), StatusCodes: make(map[int]int64)},
+			}
+
+			q := &mockQueue{}
+			ib := &mockImageBackend{}
+			cr.Queue = q
+			cr.Backend = &mockBackend{}
+			cr.ImageBackend = ib
+			cr.Robots = &MockRobotsCache{m: make(map[string]*robots.Robots)}
+
+			u, err := url.Parse(c.lnk)
+			if err != nil {
+				t.Fatalf("expected nil error; got %v", err)
+			}
+
+			httpmock.RegisterResponder(
+				"GET",
+				u.ResolveReference(RobotsPath).String(),
+				httpmock.NewStringResponder(200, c.rbts),
+			)
+
+			httpmock.RegisterResponder(
+				"GET",
+				c.lnk,
+				httpmock.NewStringResponder(200, c.body),
+			)
+
+			doc, err := cr.Crawl(c.lnk)
+			if err != c.err {
+				t.Fatalf("got err %v; want %v", err, c.err)
+			}
+
+			if doc.ID != c.lnk {
+				t.Fatalf("got %q; want %q", doc.ID, c.lnk)
+			}
+
+			if len(q.added) != c.links {
+				t.Fatalf("got %d links; want %d", len(q.added), c.links)
+			}
+
+			// the page they were found on counts toward their inlinks
+			for _, from := range q.from {
+				if from != c.lnk {
+					t.Fatalf("got a link from %q; want %q", from, c.lnk)
+				}
+			}
+
+			if len(ib.images) != c.images {
+				t.Fatalf("got %d images; want %d", len(ib.images), c.images)
+			}
+		})
+
+		httpmock.Reset()
+	}
+}
+
+func TestReparse(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	lnk := "https://www.example.com/"
+	body := `<html><head><title>hello</title></head><body><img src="/logo.png" alt="logo"></body></html>`
+
+	httpmock.RegisterResponder("GET", "https://www.example.com/robots.txt", httpmock.NewStringResponder(200, ""))
+	httpmock.RegisterResponder("GET", lnk, httpmock.NewStringResponder(200, body))
+
+	dir := t.TempDir()
+	w := &warc.Writer{Dir: dir, Prefix: "test"}
+
+	cr := &Crawler{
+		HTTPClient: http.DefaultClient,
+		UserAgent: UserAgent{
+			Full:  "test-bot-full",
+			Short: "test-bot-short",
+		},
+		maxQueueLinks: 1000,
+		maxLinks:      10,
+		maxBytes:      int64(len(body)), // httpmock rewinds the body after EOF
+		truncate: truncate{
+			title:       100,
+			keywords:    25,
+			description: 250,
+		},
+		stats:        &Stats{Start: now(), StatusCodes: make(map[int]int64)},
+		Queue:        &mockQueue{},
+		Backend:      &mockBackend{},
+		ImageBackend: &mockImageBackend{},
+		Robots:       &MockRobotsCache{m: make(map[string]*robots.Robots)},
+		Archiver:     w,
+	}
+
+	if _, err := cr.Crawl(lnk); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.warc"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 warc file; got %v (%v)", files, err)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b := &docBackend{}
+	ib := &mockImageBackend{}
+	cr.Backend, cr.ImageBackend = b, ib
+
+	n, err := cr.Reparse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 || len(b.docs) != 1 {
+		t.Fatalf("got %d docs; want 1", n)
+	}
+
+	if b.docs[0].ID != lnk || b.docs[0].Title != "hello" {
+		t.Fatalf("got %+v", b.docs[0])
+	}
+
+	if len(ib.images) != 1 {
+		t.Fatalf("got %d images; want 1", len(ib.images))
+	}
+}
+
+func TestDecide(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	cr := &Crawler{
+		HTTPClient: http.DefaultClient,
+		UserAgent: UserAgent{
+			Full:  "test-bot-full",
+			Short: "test-bot-short",
+		},
+		Robots: &MockRobotsCache{m: make(map[string]*robots.Robots)},
+	}
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://www.example.com/robots.txt",
+		httpmock.NewStringResponder(200, "User-agent: *\nDisallow: /private\nCrawl-delay: 5"),
+	)
+
+	for _, c := range []struct {
+		lnk  string
+		want bool
+	}{
+		{"https://www.example.com/public", true},
+		{"https://www.example.com/private", false},
+	} {
+		t.Run(c.lnk, func(t *testing.T) {
+			d, err := cr.Decide(c.lnk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d.Allowed != c.want {
+				t.Fatalf("got %v; want %v", d.Allowed, c.want)
+			}
+
+			if d.CrawlDelay != 5*time.Second {
+				t.Fatalf("got %v; want %v", d.CrawlDelay, 5*time.Second)
+			}
+		})
+	}
+}
+
 func TestCalculateHostDelay(t *testing.T) {
 	type retryAfter struct {
 		value  string
@@ -331,12 +592,21 @@ func TestCalculateHostDelay(t *testing.T) {
 	}
 }
 
-type mockQueue struct{}
+type mockQueue struct {
+	added []string
+	from  []string
+}
 
-func (q *mockQueue) AddLink(lnk string) error {
+func (q *mockQueue) AddLink(lnk, from string) error {
+	q.added = append(q.added, lnk)
+	q.from = append(q.from, from)
 	return nil
 }
 
+func (q *mockQueue) Inlinks(lnk string) (int, error) {
+	return 0, nil
+}
+
 func (q *mockQueue) CountLinks() (int64, error) {
 	return 100, nil
 }
@@ -371,11 +641,42 @@ func (m *mockBackend) Upsert(*document.Document) error {
 	return nil
 }
 
+type docBackend struct {
+	mockBackend
+	docs []*document.Document
+}
+
+func (m *docBackend) Upsert(doc *document.Document) error {
+	m.docs = append(m.docs, doc)
+	return nil
+}
+
+type mockImageBackend struct {
+	images []*img.Image
+}
+
+func (m *mockImageBackend) Setup() error {
+	return nil
+}
+
+func (m *mockImageBackend) Upsert(i *img.Image) error {
+	m.images = append(m.images, i)
+	return nil
+}
+
 type MockRobotsCache struct {
 	sync.Mutex
 	m map[string]*robots.Robots
 }
 
+type errRobotsCache struct {
+	MockRobotsCache
+}
+
+func (c *errRobotsCache) Get(sh string) (*robots.Robots, error) {
+	return nil, errors.New("robots cache is down")
+}
+
 func (c *MockRobotsCache) IndexExists() (bool, error) {
 	return true, nil
 }
@@ -9,7 +9,8 @@ import (
 // Queuer is handles links and our crawling queue
 type Queuer interface {
 	CountLinks() (int64, error)
-	AddLink(lnk string) error
+	AddLink(lnk, from string) error
+	Inlinks(lnk string) (int, error)
 	QueueLink(ttl time.Duration) (string, error)
 	ReserveHost(host string, ttl time.Duration) error
 	DelayHost(host string, ttl time.Duration) error
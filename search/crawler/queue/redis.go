@@ -1,9 +1,11 @@
 package queue
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
+	"golang.org/x/net/publicsuffix"
 )
 
 const (
@@ -11,8 +13,13 @@ const (
 	hostPrefix  = "h:"
 	queuePrefix = "q:"
 	links       = prefix + "links"
+	inlinks     = prefix + "inlinks:"
 )
 
+// inlinksTTL is how long we remember the sites that link to a page.
+// A page no one links to again in that time loses its inlinks.
+const inlinksTTL = 90 * 24 * time.Hour
+
 // Redis implements the Queuer interface
 type Redis struct {
 	RedisPool *redis.Pool
@@ -36,12 +43,50 @@ func (r *Redis) CountLinks() (int64, error) {
 	return cnt, err
 }
 
-// AddLink adds a link to our redis set
-func (r *Redis) AddLink(lnk string) error {
-	_, err := r.do("SADD", links, lnk)
+// AddLink adds a link to our redis set. The site of the page it was found on (from)
+// counts toward its inlinks unless it is the link's own site.
+// Each site counts once, however many of its pages link or how often we crawl them.
+func (r *Redis) AddLink(lnk, from string) error {
+	if _, err := r.do("SADD", links, lnk); err != nil {
+		return err
+	}
+
+	src := site(from)
+	if src == "" || src == site(lnk) {
+		return nil
+	}
+
+	// a HyperLogLog estimates the distinct sites without storing them
+	k := inlinks + lnk
+	if _, err := r.do("PFADD", k, src); err != nil {
+		return err
+	}
+
+	_, err := r.do("EXPIRE", k, seconds(inlinksTTL))
 	return err
 }
 
+// Inlinks is (about) how many other sites link to a page
+func (r *Redis) Inlinks(lnk string) (int, error) {
+	return redis.Int(r.do("PFCOUNT", inlinks+lnk))
+}
+
+// site is the registered domain of a link, e.g. "example.com" for
+// "https://blog.example.com/a", so a site's subdomains are one site.
+func site(lnk string) string {
+	u, err := url.Parse(lnk)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+
+	d, err := publicsuffix.EffectiveTLDPlusOne(u.Hostname())
+	if err != nil {
+		return u.Hostname()
+	}
+
+	return d
+}
+
 // QueueLink pops a link from our set
 func (r *Redis) QueueLink(ttl time.Duration) (string, error) {
 	lnk, err := redis.String(r.do("SPOP", links))
@@ -110,3 +155,25 @@ func (r *Redis) DelayHost(host string, ttl time.Duration) error {
 func seconds(ttl time.Duration) int {
 	return int(ttl / time.Second)
 }
+
+// Links returns up to n random links from our queue without removing them
+func (r *Redis) Links(n int) ([]string, error) {
+	return redis.Strings(r.do("SRANDMEMBER", links, n))
+}
+
+// HostTTL returns the time remaining on a host's reservation or delay.
+// A host that is free to be crawled has a TTL of 0.
+func (r *Redis) HostTTL(host string) (time.Duration, error) {
+	ttl, err := redis.Int(r.do("TTL", r.prefixKey(hostPrefix+host)))
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+
+	return time.Duration(ttl) * time.Second, nil
+}
+
+// ReleaseHost removes a host's reservation or delay so it can be crawled right away
+func (r *Redis) ReleaseHost(host string) error {
+	_, err := r.do("DEL", r.prefixKey(hostPrefix+host))
+	return err
+}
@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -10,20 +11,22 @@ import (
 
 func TestAddLink(t *testing.T) {
 	for _, c := range []struct {
-		name string
-		link string
+		name    string
+		link    string
+		from    string
+		counted string
 	}{
-		{
-			"first", "http://www.example.com",
-		},
-		{
-			"second", "https://www.somelink.com/and/a/path/?for=fun",
-		},
+		{"seed", "http://www.example.com", "", ""},
+		{"other site", "https://www.somelink.com/and/a/path/?for=fun", "https://blog.example.com/post", "example.com"},
+		{"same site", "https://www.example.com/about", "https://www.example.com/", ""},
+		{"subdomain", "https://www.example.com/about", "https://blog.example.com/post", ""},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			r := &Redis{}
 			conn := redigomock.NewConn()
 			conn.Command("SADD", links, c.link).Expect("OK")
+			pfadd := conn.Command("PFADD", inlinks+c.link, c.counted).Expect(int64(1))
+			expire := conn.Command("EXPIRE", inlinks+c.link, seconds(inlinksTTL)).Expect(int64(1))
 
 			r.RedisPool = &redis.Pool{
 				Dial: func() (redis.Conn, error) {
@@ -32,9 +35,56 @@ func TestAddLink(t *testing.T) {
 			}
 			defer r.RedisPool.Close()
 
-			if err := r.AddLink(c.link); err != nil {
+			if err := r.AddLink(c.link, c.from); err != nil {
 				t.Fatal(err)
 			}
+
+			want := 0
+			if c.counted != "" {
+				want = 1
+			}
+
+			if got := conn.Stats(pfadd); got != want {
+				t.Fatalf("counted %d inlinks; want %d", got, want)
+			}
+
+			if got := conn.Stats(expire); got != want {
+				t.Fatalf("expired %d times; want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestInlinks(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		link  string
+		reply interface{}
+		want  int
+	}{
+		{"linked", "http://www.example.com", int64(3), 3},
+		{"never linked", "https://www.example.com/path", int64(0), 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := &Redis{}
+			conn := redigomock.NewConn()
+			conn.Command("PFCOUNT", inlinks+c.link).Expect(c.reply)
+
+			r.RedisPool = &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			}
+			defer r.RedisPool.Close()
+
+			got, err := r.Inlinks(c.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != c.want {
+				t.Fatalf("got %v; want: %v", got, c.want)
+			}
 		})
 	}
 }
@@ -143,3 +193,82 @@ func TestDelayHost(t *testing.T) {
 		})
 	}
 }
+
+func TestLinks(t *testing.T) {
+	r := &Redis{}
+	conn := redigomock.NewConn()
+	want := []string{"http://www.example.com", "https://api.somewebsite.org"}
+	conn.Command("SRANDMEMBER", links, 2).Expect([]interface{}{[]byte(want[0]), []byte(want[1])})
+
+	r.RedisPool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+	defer r.RedisPool.Close()
+
+	got, err := r.Links(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want: %v", got, want)
+	}
+}
+
+func TestHostTTL(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		host  string
+		reply int64
+		want  time.Duration
+	}{
+		{"reserved", "http://www.example.com", 600, 10 * time.Minute},
+		{"free", "https://api.somewebsite.org", -2, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := &Redis{}
+			conn := redigomock.NewConn()
+			conn.Command("TTL", r.prefixKey(hostPrefix+c.host)).Expect(c.reply)
+
+			r.RedisPool = &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			}
+			defer r.RedisPool.Close()
+
+			got, err := r.HostTTL(c.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != c.want {
+				t.Fatalf("got %v; want: %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestReleaseHost(t *testing.T) {
+	r := &Redis{}
+	conn := redigomock.NewConn()
+	host := "http://www.example.com"
+	cmd := conn.Command("DEL", r.prefixKey(hostPrefix+host)).Expect(int64(1))
+
+	r.RedisPool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+	defer r.RedisPool.Close()
+
+	if err := r.ReleaseHost(host); err != nil {
+		t.Fatal(err)
+	}
+
+	if conn.Stats(cmd) != 1 {
+		t.Fatalf("expected DEL to be called once")
+	}
+}
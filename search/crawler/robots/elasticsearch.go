@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/olivere/elastic/v7"
 )
 
@@ -76,8 +77,12 @@ func (e *ElasticSearch) Mapping() string {
 
 // Setup creates an index for caching robots.txt files
 func (e *ElasticSearch) Setup() error {
-	_, err := e.Client.CreateIndex(e.Index).Body(e.Mapping()).Do(context.TODO())
-	return err
+	return alias.Create(e.Client, e.Index, e.Mapping())
+}
+
+// Migrate moves the cache to a new version of the index with the current mapping
+func (e *ElasticSearch) Migrate() (string, error) {
+	return alias.Migrate(e.Client, e.Index, e.Mapping())
 }
 
 // IndexExists returns true if the index exists
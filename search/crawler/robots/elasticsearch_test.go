@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
@@ -223,9 +224,14 @@ func TestSetup(t *testing.T) {
 			}))
 			defer ts.Close()
 
-			handler = func(w http.ResponseWriter, _ *http.Request) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				resp := c.resp
+				if strings.HasSuffix(r.URL.Path, "/_alias") {
+					resp = `{"robots-v1": {"aliases": {"robots": {}}}}`
+				}
+
 				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
+				if _, err := w.Write([]byte(resp)); err != nil {
 					t.Fatal(err)
 				}
 			}
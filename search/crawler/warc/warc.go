@@ -0,0 +1,300 @@
+// Package warc writes and reads Web ARChive (WARC) files of crawled responses
+// so documents can be reparsed without fetching them again.
+// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.0/
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const version = "WARC/1.0"
+
+// ErrInvalidRecord indicates a malformed WARC record
+var ErrInvalidRecord = errors.New("invalid warc record")
+
+var now = func() time.Time { return time.Now().UTC() }
+
+// Record is a single WARC record
+type Record struct {
+	Header  textproto.MIMEHeader
+	Content []byte
+}
+
+// Type is the WARC-Type of the record (e.g. "response")
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// Writer writes request/response pairs to WARC files in Dir. A new file is
+// started once the current one reaches MaxSize bytes (0 for no limit).
+// If Gzip is set each record is compressed as its own gzip member,
+// the usual layout of a .warc.gz file.
+type Writer struct {
+	Dir     string
+	Prefix  string
+	MaxSize int64
+	Gzip    bool
+	sync.Mutex
+	f      *os.File
+	size   int64
+	serial int
+}
+
+// Archive records the request and its (already read) response body.
+// The response is stored decoded so the Content-Length of the stored
+// HTTP headers matches the body we kept, which may have been truncated.
+func (w *Writer) Archive(req *http.Request, resp *http.Response, body []byte) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.f == nil || (w.MaxSize > 0 && w.size >= w.MaxSize) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	t := now().Format(time.RFC3339)
+	reqID, respID := recordID(), recordID()
+
+	h := textproto.MIMEHeader{}
+	h.Set("WARC-Type", "request")
+	h.Set("WARC-Record-ID", reqID)
+	h.Set("WARC-Date", t)
+	h.Set("WARC-Target-URI", req.URL.String())
+	h.Set("WARC-Concurrent-To", respID)
+	h.Set("Content-Type", "application/http; msgtype=request")
+
+	if err := w.write(&Record{Header: h, Content: requestBlock(req)}); err != nil {
+		return err
+	}
+
+	h = textproto.MIMEHeader{}
+	h.Set("WARC-Type", "response")
+	h.Set("WARC-Record-ID", respID)
+	h.Set("WARC-Date", t)
+	h.Set("WARC-Target-URI", req.URL.String())
+	h.Set("WARC-Payload-Digest", digest(body))
+	h.Set("Content-Type", "application/http; msgtype=response")
+
+	return w.write(&Record{Header: h, Content: responseBlock(resp, body)})
+}
+
+// Close closes the current file
+func (w *Writer) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+func (w *Writer) rotate() error {
+	if w.f != nil {
+		if err := w.f.Close(); err != nil {
+			return err
+		}
+	}
+
+	w.serial++
+	name := fmt.Sprintf("%v-%v-%05d.warc", w.Prefix, now().Format("20060102150405"), w.serial)
+	if w.Gzip {
+		name += ".gz"
+	}
+
+	f, err := os.Create(filepath.Join(w.Dir, name))
+	if err != nil {
+		return err
+	}
+
+	w.f, w.size = f, 0
+
+	h := textproto.MIMEHeader{}
+	h.Set("WARC-Type", "warcinfo")
+	h.Set("WARC-Record-ID", recordID())
+	h.Set("WARC-Date", now().Format(time.RFC3339))
+	h.Set("WARC-Filename", name)
+	h.Set("Content-Type", "application/warc-fields")
+
+	return w.write(&Record{Header: h, Content: []byte("software: jivesearch\r\nformat: WARC File Format 1.0\r\n")})
+}
+
+func (w *Writer) write(r *Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(version + "\r\n")
+
+	r.Header.Set("Content-Length", strconv.Itoa(len(r.Content)))
+	for _, k := range []string{"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Filename",
+		"WARC-Target-URI", "WARC-Concurrent-To", "WARC-Payload-Digest", "Content-Type", "Content-Length"} {
+		if v := r.Header.Get(k); v != "" {
+			fmt.Fprintf(&buf, "%v: %v\r\n", k, v)
+		}
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(r.Content)
+	buf.WriteString("\r\n\r\n")
+
+	cw := &countingWriter{w: w.f}
+	var out io.Writer = cw
+	var gz *gzip.Writer
+
+	if w.Gzip {
+		gz = gzip.NewWriter(cw)
+		out = gz
+	}
+
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	w.size += cw.n
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func requestBlock(req *http.Request) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%v %v HTTP/1.1\r\nHost: %v\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	req.Header.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// the Transport has already removed any Content-Encoding and we may have
+// truncated the body so we rewrite the headers that describe the body
+func responseBlock(resp *http.Response, body []byte) []byte {
+	major, minor := resp.ProtoMajor, resp.ProtoMinor
+	if major == 0 {
+		major, minor = 1, 1
+	}
+
+	status := resp.Status
+	if status == "" || !strings.HasPrefix(status, strconv.Itoa(resp.StatusCode)) {
+		status = fmt.Sprintf("%d %v", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	h := resp.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+
+	h.Del("Content-Encoding")
+	h.Del("Transfer-Encoding")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/%d.%d %v\r\n", major, minor, status)
+	h.Write(&b)
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.Bytes()
+}
+
+func recordID() string {
+	u := make([]byte, 16)
+	rand.Read(u)
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+func digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// Reader reads records from a WARC file, gzipped or not
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader creates a Reader. Gzipped files are detected automatically.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br) // reads all members of a multistream file
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record or io.EOF when there are no more records
+func (r *Reader) Next() (*Record, error) {
+	tp := textproto.NewReader(r.r)
+
+	var line string
+	var err error
+	for line == "" { // skip the blank lines between records
+		if line, err = tp.ReadLine(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, ErrInvalidRecord
+	}
+
+	h, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, ErrInvalidRecord
+	}
+
+	rec := &Record{Header: h, Content: make([]byte, n)}
+	if _, err := io.ReadFull(r.r, rec.Content); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
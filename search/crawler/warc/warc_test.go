@@ -0,0 +1,165 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2018, 02, 06, 20, 34, 58, 0, time.UTC)
+	}
+
+	for _, c := range []struct {
+		name string
+		gzip bool
+	}{
+		{"plain", false},
+		{"gzip", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := &Writer{Dir: dir, Prefix: "test", Gzip: c.gzip}
+
+			req, err := http.NewRequest("GET", "https://www.example.com/path?q=1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("User-Agent", "jivesearchbot")
+
+			resp := &http.Response{
+				StatusCode: 200,
+				Status:     "200 OK",
+				Header: http.Header{
+					"Content-Type":     []string{"text/html"},
+					"Content-Length":   []string{"100000"},
+					"Content-Encoding": []string{"gzip"},
+				},
+			}
+			body := []byte("<html><title>hello</title></html>")
+
+			if err := w.Archive(req, resp, body); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(dir, "*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := "test-20180206203458-00001.warc"
+			if c.gzip {
+				want += ".gz"
+			}
+
+			if len(files) != 1 || filepath.Base(files[0]) != want {
+				t.Fatalf("got %v; want %v", files, want)
+			}
+
+			f, err := os.Open(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			r, err := NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			types := []string{}
+			var rec *Record
+			for {
+				rr, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				types = append(types, rr.Type())
+				if rr.Type() == "response" {
+					rec = rr
+				}
+			}
+
+			if !reflect.DeepEqual(types, []string{"warcinfo", "request", "response"}) {
+				t.Fatalf("got %v", types)
+			}
+
+			if got := rec.Header.Get("WARC-Target-URI"); got != req.URL.String() {
+				t.Fatalf("got %q; want %q", got, req.URL.String())
+			}
+
+			res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Content)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b, body) {
+				t.Fatalf("got %q; want %q", b, body)
+			}
+
+			if res.Header.Get("Content-Encoding") != "" {
+				t.Fatalf("expected Content-Encoding to be removed")
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	w := &Writer{Dir: dir, Prefix: "test", MaxSize: 1}
+
+	req, err := http.NewRequest("GET", "https://www.example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.Archive(req, &http.Response{StatusCode: 200}, []byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.warc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 3 {
+		t.Fatalf("got %d files; want 3", len(files))
+	}
+}
+
+func TestNextInvalid(t *testing.T) {
+	r, err := NewReader(bytes.NewReader([]byte("not a warc file\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Next(); err != ErrInvalidRecord {
+		t.Fatalf("got %v; want %v", err, ErrInvalidRecord)
+	}
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"net/url"
+
+	"github.com/jonesrussell/jivesearch/search/document"
+)
+
+// Diversify keeps at most n documents of each domain, in their order.
+// The last document kept of a domain that had more is noted in More
+// so we can link to the rest of its results. n < 1 keeps everything.
+func (r *Results) Diversify(n int) *Results {
+	if n < 1 {
+		return r
+	}
+
+	seen := map[string]int{}
+	last := map[string]string{}
+	docs := []*document.Document{}
+
+	for _, d := range r.Documents {
+		dom := domain(d)
+		if dom == "" {
+			docs = append(docs, d)
+			continue
+		}
+
+		if seen[dom] == n {
+			r.more(last[dom], dom)
+			continue
+		}
+
+		seen[dom]++
+		last[dom] = d.ID
+		docs = append(docs, d)
+	}
+
+	r.Documents = docs
+	return r
+}
+
+func (r *Results) more(id, domain string) {
+	if r.More == nil {
+		r.More = map[string]string{}
+	}
+	r.More[id] = domain
+}
+
+// domain is the domain of a document or, if it wasn't set, of its url
+func domain(d *document.Document) string {
+	if d.Domain != "" {
+		return d.Domain
+	}
+
+	u, err := url.Parse(d.ID)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	dom, err := document.ExtractDomain(u)
+	if err != nil {
+		return u.Hostname()
+	}
+
+	return dom
+}
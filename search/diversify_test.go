@@ -0,0 +1,80 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/search/document"
+)
+
+func TestDiversify(t *testing.T) {
+	docs := func(ids ...string) []*document.Document {
+		ds := []*document.Document{}
+		for _, id := range ids {
+			ds = append(ds, &document.Document{ID: id})
+		}
+		return ds
+	}
+
+	for _, c := range []struct {
+		name string
+		n    int
+		docs []*document.Document
+		want []string
+		more map[string]string
+	}{
+		{
+			name: "no limit",
+			n:    0,
+			docs: docs("https://example.com/a", "https://example.com/b"),
+			want: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name: "clustered",
+			n:    2,
+			docs: docs(
+				"https://example.com/a",
+				"https://www.example.com/b",
+				"https://example.org/a",
+				"https://sub.example.com/c",
+				"https://example.com/d",
+				"https://example.org/b",
+			),
+			want: []string{
+				"https://example.com/a",
+				"https://www.example.com/b",
+				"https://example.org/a",
+				"https://example.org/b",
+			},
+			more: map[string]string{"https://www.example.com/b": "example.com"},
+		},
+		{
+			name: "domain set",
+			n:    1,
+			docs: []*document.Document{
+				{ID: "https://a.example.com", Domain: "example.com"},
+				{ID: "https://b.example.com", Domain: "example.com"},
+				{ID: "not a url"},
+			},
+			want: []string{"https://a.example.com", "not a url"},
+			more: map[string]string{"https://a.example.com": "example.com"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := (&Results{Documents: c.docs}).Diversify(c.n)
+
+			got := []string{}
+			for _, d := range r.Documents {
+				got = append(got, d.ID)
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+
+			if !reflect.DeepEqual(r.More, c.more) {
+				t.Fatalf("got %v; want %v", r.More, c.more)
+			}
+		})
+	}
+}
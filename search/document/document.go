@@ -28,14 +28,17 @@ var (
 // (Scheme, Host) we explicitly set those. Much easier than
 // a custom MarshalJSON method.
 type Document struct {
-	ID        string   `json:"id"` // store ID also as a field as sorting on document ID is not advised in Elasticsearch
-	URL       *url.URL `json:"-"`
-	Scheme    string   `json:"scheme,omitempty"`
-	Host      string   `json:"host,omitempty"`       // not HostName()...we want the port for the robots.txt file
-	Domain    string   `json:"domain,omitempty"`     // tld+1 -> example.com
-	TLD       string   `json:"tld,omitempty"`        // com, org, uk, etc (we don't want co.uk just uk)
-	PathParts string   `json:"path_parts,omitempty"` // https://api.example.com/path/to/something -> "path to something"
-	Crawled   string   `json:"crawled,omitempty"`
+	ID        string      `json:"id"` // store ID also as a field as sorting on document ID is not advised in Elasticsearch
+	URL       *url.URL    `json:"-"`
+	Scheme    string      `json:"scheme,omitempty"`
+	Host      string      `json:"host,omitempty"`       // not HostName()...we want the port for the robots.txt file
+	Domain    string      `json:"domain,omitempty"`     // tld+1 -> example.com
+	TLD       string      `json:"tld,omitempty"`        // com, org, uk, etc (we don't want co.uk just uk)
+	PathParts string      `json:"path_parts,omitempty"` // https://api.example.com/path/to/something -> "path to something"
+	Crawled   string      `json:"crawled,omitempty"`
+	Inlinks   int         `json:"inlinks,omitempty"`   // how many other sites link to it (link authority)
+	Sitelinks []*Document `json:"sitelinks,omitempty"` // key pages of the site for navigational queries. Not indexed.
+	Lang      string      `json:"lang,omitempty"`      // the language of the index it was found in. Not indexed.
 	header    http.Header
 	MIME      string `json:"mime,omitempty"`
 	tokenizer *html.Tokenizer
@@ -223,16 +226,41 @@ func (d *Document) SetContent(bot string, maxLinks int, links chan string, image
 	var tt html.TokenType
 	var title bool
 
+	// images inside a link are held back until we have the link's text
+	var anchor *imageAnchor
+	flush := func() {
+		if anchor == nil {
+			return
+		}
+
+		txt := strings.Join(anchor.text, " ")
+		if strings.TrimSpace(txt) == "" {
+			txt = anchor.title
+		}
+
+		for _, im := range anchor.images {
+			im.Anchor = d.extractText(txt, maxAnchor)
+			images <- im
+		}
+
+		anchor = nil
+	}
+
 	for {
 		tt = d.tokenizer.Next()
 
 		switch tt {
 		case html.ErrorToken:
+			flush()
 			return nil
 		case html.TextToken:
 			if title {
 				d.Title = d.extractText(string(d.tokenizer.Text()), truncateTitle)
 			}
+
+			if anchor != nil && len(anchor.text) < maxAnchor {
+				anchor.text = append(anchor.text, string(d.tokenizer.Text()))
+			}
 		case html.StartTagToken, html.SelfClosingTagToken:
 			t := d.tokenizer.Token()
 
@@ -280,6 +308,11 @@ func (d *Document) SetContent(bot string, maxLinks int, links chan string, image
 						d.Description = d.extractText(des, truncateDescription)
 					}
 				}
+				if publishedMeta(t) {
+					if dt, ok := parseDate(t, "content"); ok {
+						d.Date = dt // overrides any <time> tag
+					}
+				}
 				name, _ := getAttribute(t, "name")
 				// TODO: Like SetPolicyFromHeader(), we need to process bot directive
 				if strings.EqualFold(name, "robots") || strings.EqualFold(name, bot) {
@@ -287,6 +320,12 @@ func (d *Document) SetContent(bot string, maxLinks int, links chan string, image
 					d.setPolicy(bot, content)
 				}
 			case atom.A:
+				flush() // a link inside a link isn't valid html
+				if tt == html.StartTagToken {
+					anchor = &imageAnchor{}
+					anchor.title, _ = getAttribute(t, "title")
+				}
+
 				if d.Policy.follow && (maxLinks == -1 || collected < maxLinks) {
 					rel, _ := getAttribute(t, "rel")
 					if !contains(strings.Fields(rel), "nofollow") {
@@ -311,8 +350,21 @@ func (d *Document) SetContent(bot string, maxLinks int, links chan string, image
 				}
 
 				img.Alt, _ = getAttribute(t, "alt")
+				img.Title = d.Title // the <title> usually comes before any images
+
+				if anchor != nil {
+					anchor.images = append(anchor.images, img)
+					continue
+				}
+
 				images <- img
 			case atom.Time:
+				if d.Date == "" {
+					if dt, ok := parseDate(t, "datetime"); ok {
+						d.Date = dt
+					}
+				}
+
 				// There are a few ways to get the creation date (or modified) date of the document:
 
 				// the "created" or "last-modified" meta tags
@@ -339,11 +391,68 @@ func (d *Document) SetContent(bot string, maxLinks int, links chan string, image
 			switch t.DataAtom {
 			case atom.Title:
 				title = false
+			case atom.A:
+				flush()
 			}
 		}
 	}
 }
 
+// publishedMeta tells us if a meta tag holds the publication date
+func publishedMeta(t html.Token) bool {
+	for _, attr := range []string{"property", "name", "itemprop"} {
+		v, _ := getAttribute(t, attr)
+		switch strings.ToLower(v) {
+		case "article:published_time", "date", "dc.date", "dc.date.issued", "dcterms.created", "pubdate", "datepublished":
+			return true
+		}
+	}
+
+	return false
+}
+
+var now = time.Now
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseDate reads a date from an attribute. Dates in the future are ignored.
+func parseDate(t html.Token, attr string) (string, bool) {
+	v, _ := getAttribute(t, attr)
+	v = strings.TrimSpace(v)
+
+	for _, l := range dateLayouts {
+		dt, err := time.Parse(l, v)
+		if err != nil {
+			continue
+		}
+
+		if dt.After(now().Add(24 * time.Hour)) {
+			return "", false
+		}
+
+		return dt.UTC().Format(time.RFC3339), true
+	}
+
+	return "", false
+}
+
+// maxAnchor is the longest link text we keep for an image
+const maxAnchor = 100
+
+// imageAnchor is the context of the link that images are in
+type imageAnchor struct {
+	title  string
+	text   []string
+	images []*img.Image
+}
+
 var canonicalHeader = regexp.MustCompile(`<(.*?)>; rel="canonical"`)
 
 // SetCanonical sets Canonical to true if the Document's ID is the canonical URL
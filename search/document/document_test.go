@@ -294,6 +294,7 @@ func TestSetContent(t *testing.T) {
 		maxLinks            int
 		ch                  chan string
 		images              chan *img.Image
+		wantImages          []*img.Image
 		truncateTitle       int
 		truncateKeywords    int
 		truncateDescription int
@@ -395,10 +396,85 @@ func TestSetContent(t *testing.T) {
 				Policy:      Policy{Index: true, follow: true},
 			},
 		},
+		{
+			name:   "date",
+			url:    "https://www.example.com",
+			status: http.StatusOK,
+			body: `<html>
+				     <head>
+					   <title>News</title>
+					 </head>
+					 <body>
+					   <time datetime="2018-01-02">January 2nd</time>
+					   <meta property="article:published_time" content="2018-01-01T10:00:00+02:00">
+					   <time datetime="2017-12-31">later time tags are ignored</time>
+					 </body>
+				   </html>`,
+			links:               []string{},
+			maxLinks:            10,
+			ch:                  make(chan string),
+			truncateTitle:       100,
+			truncateKeywords:    5,
+			truncateDescription: 14,
+			want: Content{
+				StatusCode: http.StatusOK,
+				Language:   language.English,
+				Title:      "News",
+				Date:       "2018-01-01T08:00:00Z",
+				Policy:     Policy{Index: true, follow: true},
+			},
+		},
+		{
+			name:   "images",
+			url:    "https://www.example.com",
+			status: http.StatusOK,
+			body: `<html>
+				     <head>
+					   <title>Cats of the world</title>
+					 </head>
+					 <body>
+					   <img src="/logo.png" alt="logo">
+					   <a href="/tabby" title="All about tabbies"><img src="/1.jpg" alt="IMG_0001"> A striped tabby</a>
+					   <a href="/siamese" title="Siamese cats"><img src="/2.jpg"></a>
+					 </body>
+				   </html>`,
+			links: []string{
+				"https://www.example.com/tabby",
+				"https://www.example.com/siamese",
+			},
+			maxLinks: 10,
+			ch:       make(chan string),
+			images:   make(chan *img.Image),
+			wantImages: []*img.Image{
+				{ID: "https://www.example.com/logo.png", Domain: "example.com", Alt: "logo", Title: "Cats of the world"},
+				{ID: "https://www.example.com/1.jpg", Domain: "example.com", Alt: "IMG_0001", Title: "Cats of the world", Anchor: "A striped tabby"},
+				{ID: "https://www.example.com/2.jpg", Domain: "example.com", Title: "Cats of the world", Anchor: "Siamese cats"},
+			},
+			truncateTitle:       100,
+			truncateKeywords:    5,
+			truncateDescription: 14,
+			want: Content{
+				StatusCode: http.StatusOK,
+				Language:   language.English,
+				Title:      "Cats of the world",
+				Policy:     Policy{Index: true, follow: true},
+			},
+		},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			collected := make(chan []string)
 
+			images := make(chan []*img.Image)
+			go func() {
+				var imgs []*img.Image
+				if c.images != nil {
+					for im := range c.images {
+						imgs = append(imgs, im)
+					}
+				}
+				images <- imgs
+			}()
+
 			go func() {
 				lnks := []string{}
 				for lnk := range c.ch {
@@ -443,6 +519,14 @@ func TestSetContent(t *testing.T) {
 			close(c.ch)
 			got := <-collected
 
+			if c.images != nil {
+				close(c.images)
+			}
+
+			if gotImages := <-images; !reflect.DeepEqual(gotImages, c.wantImages) {
+				t.Fatalf("got %+v images; want %+v", gotImages, c.wantImages)
+			}
+
 			if !reflect.DeepEqual(got, c.links) {
 				t.Fatalf("got %v links; want %v", got, c.links)
 			}
@@ -2,25 +2,36 @@ package document
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/olivere/elastic/v7"
 	"golang.org/x/text/language"
 )
 
 // ElasticSearch hold connection and index settings
 type ElasticSearch struct {
-	Client *elastic.Client
-	Index  string
-	Type   string
+	Client   *elastic.Client
+	Index    string
+	Type     string
+	Synonyms string // synonyms file, relative to the Elasticsearch config directory. Empty for none.
+	Version  int    // 0 for the aliases. Otherwise the version of the indices we use (e.g. during a migration).
 }
 
+// analyzers are the analyzers we create an index for.
+// This is a list of all elasticsearch analyzers.
+var analyzers = []string{"english"}
+
 var langAnalyzer = make(map[language.Tag]string)
 
 // IndexName returns the language-specific index
-// e.g. "search-english", "search-french"
+// e.g. "search-english", "search-french" or, with a Version, "search-english-v2"
 func (e *ElasticSearch) IndexName(a string) string {
+	if e.Version > 0 {
+		return alias.Version(e.Index+"-"+a, e.Version)
+	}
 	return e.Index + "-" + a
 }
 
@@ -41,33 +52,110 @@ func (e *ElasticSearch) Analyzer(lang language.Tag) (string, error) {
 }
 
 // Setup will create our main search index
-// and language-specific indices for the content
+// and language-specific indices for the content.
+// Each is an alias of a versioned index so its mapping can be migrated.
 func (e *ElasticSearch) Setup() error {
 	// We create one index per analyzer: search-english, search-spanish, etc...
-	// This is a list of all elasticsearch analyzers
-	analyzers := []string{"english"}
+	for _, a := range analyzers {
+		if err := alias.Create(e.Client, e.IndexName(a), e.mapping(a)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Migrate moves the language-specific indices to a new version with the current mapping.
+// fill copies the documents into the new version, e.g. by reparsing them, with
+// IndexName returning the new indices. A nil fill reindexes the current documents.
+// The aliases are only swapped once every index is filled.
+func (e *ElasticSearch) Migrate(fill func(*ElasticSearch) error) (int, error) {
+	names := map[string]string{}
+	current := map[string]string{}
+	var version int
 
 	for _, a := range analyzers {
-		idx := e.IndexName(a)
-		exists, err := e.Client.IndexExists(idx).Do(context.TODO())
+		names[a] = e.IndexName(a)
+		idx, n, err := alias.Current(e.Client, names[a])
 		if err != nil {
-			return err
+			return 0, err
+		}
+		current[a] = idx
+		if n > version {
+			version = n
+		}
+	}
+
+	// the languages share a version so fill can write to all of them
+	next := *e
+	next.Version = version + 1
+
+	for _, a := range analyzers {
+		if _, err := alias.Next(e.Client, names[a], e.mapping(a), next.Version); err != nil {
+			return 0, err
 		}
+	}
 
-		if !exists {
-			log.Info.Println("Creating index:", idx)
-			if _, err = e.Client.CreateIndex(idx).Body(e.mapping(a)).Do(context.TODO()); err != nil {
-				return err
+	if fill != nil {
+		if err := fill(&next); err != nil {
+			return 0, err
+		}
+	} else {
+		for _, a := range analyzers {
+			if current[a] == "" {
+				continue
 			}
+
+			cnt, err := alias.Reindex(e.Client, current[a], next.IndexName(a))
+			if err != nil {
+				return 0, err
+			}
+			log.Info.Printf("Reindexed %d documents from %v to %v\n", cnt, current[a], next.IndexName(a))
 		}
 	}
 
-	return nil
+	for _, a := range analyzers {
+		if err := alias.Swap(e.Client, names[a], current[a], next.IndexName(a)); err != nil {
+			return 0, err
+		}
+	}
+
+	return next.Version, nil
+}
+
+// Cleanup deletes the versions of the language indices older than the current one
+// once a migration is known to be good.
+func (e *ElasticSearch) Cleanup() ([]string, error) {
+	var old []string
+	for _, a := range analyzers {
+		o, err := alias.Cleanup(e.Client, e.IndexName(a))
+		if err != nil {
+			return old, err
+		}
+		old = append(old, o...)
+	}
+
+	return old, nil
 }
 
 // mapping is the mapping of our main search Index.
 // https://www.elastic.co/guide/en/elasticsearch/guide/current/one-lang-docs.html
+// Synonyms are only applied at search time so they can be reloaded.
+// Without a Synonyms file the filter is left out, so setting one later takes a migration.
 func (e *ElasticSearch) mapping(a string) string {
+	synonyms, filters := "", `"lowercase"`
+	if e.Synonyms != "" {
+		b, _ := json.Marshal(e.Synonyms)
+		synonyms = fmt.Sprintf(`,
+									"synonyms": {
+											"type": "synonym_graph",
+											"updateable": true,
+											"synonyms_path": %s
+									}`, b)
+		filters = `"lowercase",
+													"synonyms"`
+	}
+
 	m := fmt.Sprintf(`{
 			"settings": {
 					"analysis": {
@@ -77,7 +165,7 @@ func (e *ElasticSearch) mapping(a string) string {
 											"min_shingle_size": 2,
 											"max_shingle_size": 2,
 											"output_unigrams": false
-									}
+									}%v
 							},
 							"analyzer": {
 									"my_shingle_analyzer": {
@@ -88,6 +176,13 @@ func (e *ElasticSearch) mapping(a string) string {
 													"my_shingle_filter"
 											]
 									},
+									"synonym_analyzer": {
+											"type": "custom",
+											"tokenizer": "standard",
+											"filter": [
+													%v
+											]
+									},
 									"domain_name_analyzer": {
 											"tokenizer": "domain_name_tokenizer"
 									},
@@ -112,6 +207,7 @@ func (e *ElasticSearch) mapping(a string) string {
 					"properties": {
 							"title": {
 									"type": "text",
+									"search_analyzer": "synonym_analyzer",
 									"fields": {
 											"lang": {
 													"type": "text",
@@ -125,6 +221,7 @@ func (e *ElasticSearch) mapping(a string) string {
 							},
 							"description": {
 									"type": "text",
+									"search_analyzer": "synonym_analyzer",
 									"fields": {
 											"lang": {
 													"type": "text",
@@ -147,7 +244,12 @@ func (e *ElasticSearch) mapping(a string) string {
 							},
 							"domain": {
 									"type": "text",
-									"analyzer": "domain_name_analyzer"
+									"analyzer": "domain_name_analyzer",
+									"fields": {
+											"keyword": {
+													"type": "keyword"
+											}
+									}
 							},
 							"tld": {
 									"type": "keyword"
@@ -162,6 +264,9 @@ func (e *ElasticSearch) mapping(a string) string {
 							"index": {
 									"type": "boolean"
 							},
+							"inlinks": {
+									"type": "integer"
+							},
 							"crawled": {
 									"type": "date",
 									"format": "basic_date"
@@ -182,13 +287,26 @@ func (e *ElasticSearch) mapping(a string) string {
 							}
 					}
 			}
-	}`, a, a)
-
-	fmt.Println(m)
+	}`, synonyms, filters, a, a)
 
 	return m
 }
 
+// ReloadSynonyms picks up changes to the Synonyms file without reindexing.
+// Only the search analyzers use synonyms so the documents don't change.
+func (e *ElasticSearch) ReloadSynonyms() error {
+	if e.Synonyms == "" {
+		return fmt.Errorf("no synonyms to reload: elasticsearch.synonyms isn't set")
+	}
+
+	_, err := e.Client.PerformRequest(context.TODO(), elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   "/" + e.Index + "-*/_reload_search_analyzers",
+	})
+
+	return err
+}
+
 func init() {
 	// These are the most commonly used languages mapped to an elasticsearch analyzer
 	// TODO: fill in the rest of this map. Also, we haven't mapped the Basque, Galician,
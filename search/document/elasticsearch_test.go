@@ -1,8 +1,12 @@
 package document
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
@@ -53,42 +57,207 @@ func TestAnalyzer(t *testing.T) {
 
 func TestSetup(t *testing.T) {
 	for _, c := range []struct {
-		name   string
-		status int
-		resp   string
+		name      string
+		responses map[string]string // "METHOD path" -> response. Anything else is a 404.
+		want      []string
 	}{
 		{
-			name:   "ok",
-			status: http.StatusOK,
-			resp:   `{"acknowledged": true}`,
+			name: "new",
+			responses: map[string]string{
+				"PUT /search-english-v1": `{"acknowledged": true}`,
+				"POST /_aliases":         `{"acknowledged": true}`,
+			},
+			want: []string{"HEAD /search-english", "PUT /search-english-v1", "POST /_aliases"},
+		},
+		{
+			name: "exists",
+			responses: map[string]string{
+				"HEAD /search-english":       ``,
+				"GET /search-english/_alias": `{"search-english-v3": {"aliases": {"search-english": {}}}}`,
+			},
+			want: []string{"HEAD /search-english", "GET /search-english/_alias"},
 		},
 	} {
 		t.Run(c.name, func(t *testing.T) {
-			handler := http.NotFound
+			got := []string{}
 			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				handler(w, r)
+				req := r.Method + " " + r.URL.Path
+				got = append(got, req)
+
+				resp, ok := c.responses[req]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(resp))
 			}))
 			defer ts.Close()
 
-			handler = func(w http.ResponseWriter, _ *http.Request) {
-				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
-					t.Fatal(err)
-				}
+			e, err := MockService(ts.URL)
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			if err := e.Setup(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fill bool
+		want []string
+	}{
+		{
+			name: "reindex",
+			want: []string{
+				"HEAD /search-english", "GET /search-english/_alias",
+				"PUT /search-english-v3",
+				"POST /_reindex",
+				"POST /_aliases",
+			},
+		},
+		{
+			name: "fill",
+			fill: true,
+			want: []string{
+				"HEAD /search-english", "GET /search-english/_alias",
+				"PUT /search-english-v3",
+				"POST /_aliases",
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := []string{}
+			var actions string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = append(got, r.Method+" "+r.URL.Path)
+
+				switch r.URL.Path {
+				case "/search-english/_alias":
+					w.Write([]byte(`{"search-english-v2": {"aliases": {"search-english": {}}}}`))
+				case "/_reindex":
+					w.Write([]byte(`{"created": 5, "failures": []}`))
+				case "/_aliases":
+					b, _ := io.ReadAll(r.Body)
+					actions = string(b)
+					w.Write([]byte(`{"acknowledged": true}`))
+				default:
+					w.Write([]byte(`{"acknowledged": true}`))
+				}
+			}))
+			defer ts.Close()
+
 			e, err := MockService(ts.URL)
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			if err := e.Setup(); err != nil {
+			var fill func(*ElasticSearch) error
+			var filled string
+			if c.fill {
+				fill = func(next *ElasticSearch) error {
+					filled = next.IndexName("english")
+					return nil
+				}
+			}
+
+			v, err := e.Migrate(fill)
+			if err != nil {
 				t.Fatal(err)
 			}
+
+			if v != 3 {
+				t.Fatalf("got version %d; want 3", v)
+			}
+
+			if c.fill && filled != "search-english-v3" {
+				t.Fatalf("filled %q; want %q", filled, "search-english-v3")
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+
+			want := `{"actions":[{"remove":{"alias":"search-english","index":"search-english-v2"}},{"add":{"alias":"search-english","index":"search-english-v3"}}]}`
+			if actions != want {
+				t.Fatalf("got %v; want %v", actions, want)
+			}
 		})
 	}
 }
 
+func TestMapping(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		synonyms string
+		want     string
+		not      string
+	}{
+		{"no synonyms", "", `"synonym_analyzer":{"filter":["lowercase"]`, `"synonyms"`},
+		{"synonyms file", "analysis/synonyms.txt", `"synonyms_path":"analysis/synonyms.txt"`, `"synonyms":[]`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e := &ElasticSearch{Index: "search", Synonyms: c.synonyms}
+
+			m := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(e.mapping("english")), &m); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := json.Marshal(m["settings"])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(b), c.want) {
+				t.Fatalf("got %s; want it to contain %s", b, c.want)
+			}
+
+			if strings.Contains(string(b), c.not) {
+				t.Fatalf("got %s; want it not to contain %s", b, c.not)
+			}
+		})
+	}
+}
+
+func TestReloadSynonyms(t *testing.T) {
+	var method, path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		if _, err := w.Write([]byte(`{"_shards":{"total":1,"successful":1,"failed":0}}`)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the indices have no synonyms filter to reload
+	if err := e.ReloadSynonyms(); err == nil || path != "" {
+		t.Fatalf("expected an error without synonyms; got %v (%v)", err, path)
+	}
+
+	e.Synonyms = "analysis/synonyms.txt"
+	if err := e.ReloadSynonyms(); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPost || path != "/search-*/_reload_search_analyzers" {
+		t.Fatalf("got %v %v", method, path)
+	}
+}
+
 func MockService(url string) (*ElasticSearch, error) {
 	client, err := elastic.NewSimpleClient(elastic.SetURL(url))
 	if err != nil {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
+	"github.com/jonesrussell/jivesearch/log"
 	"github.com/jonesrussell/jivesearch/search/document"
 	"github.com/olivere/elastic/v7"
 	"golang.org/x/text/language"
@@ -13,6 +15,8 @@ import (
 // ElasticSearch embeds our main Elasticsearch instance
 type ElasticSearch struct {
 	*document.ElasticSearch
+	Profile  *Profile // nil for the DefaultProfile
+	Expander Expander // query-time synonyms. nil for none.
 }
 
 // Fetch returns search results for a search query
@@ -21,75 +25,328 @@ type ElasticSearch struct {
 // The idea here is to first filter out docs that do not want to be indexed.
 // We then search multiple fields for the search query, giving more weight to certain fields.
 // We also are searching the standard analyzer and the language-specific analyzer.
-// We weight the domain > path, path > title, title > description.
+// The fields, their weights and any other signals come from the ranking Profile.
+// Synonyms from the index's synonyms file apply to the title and description. The Expander
+// adds alternative wordings of the query.
+// Results are clustered by domain with field collapsing when opts.PerDomain is set.
+// The homepage of a navigational query gets Sitelinks.
+// opts.Languages are searched too, in the same query, with their results weighted lower.
 // We also give extra weight for bigram matches (need trigram????):
 // https://www.elastic.co/guide/en/elasticsearch/guide/current/shingles.html
 // Note: "It is not useful to mix not_analyzed fields with analyzed fields in multi_match queries."
 // TODO: A better domain name method...we could use regex ('.*hendrix'), prefix query, etc.
-func (e *ElasticSearch) Fetch(q string, filter Filter, lang language.Tag, region language.Region, number int, offset int) (*Results, error) {
+func (e *ElasticSearch) Fetch(q string, filter Filter, lang language.Tag, region language.Region, number int, offset int, opts Options) (*Results, error) {
 	res := &Results{}
 
+	p := e.Profile
+	if p == nil {
+		p = DefaultProfile
+	}
+
+	pq := ParseQuery(q)
+
 	qu := elastic.NewBoolQuery().
-		Filter(elastic.NewTermQuery("index", true)).
-		Must(
-			elastic.NewMultiMatchQuery(
-				q,
-				"domain^3", "path^2",
-				"title^1.5", "title.lang^1.5",
-				"description", "description.lang",
-			).Type("cross_fields").MinimumShouldMatch("-25%"),
-		).
-		Should(
-			elastic.NewMultiMatchQuery(
-				q,
-				"title.shingles",
-				"description.shingles",
-			).Type("cross_fields"),
-		)
+		Filter(elastic.NewTermQuery("index", true))
+
+	// a query of only operators (e.g. "site:example.com") matches everything they allow
+	if txt := pq.Text(); txt != "" {
+		match := func(txt string) *elastic.MultiMatchQuery {
+			mm := elastic.NewMultiMatchQuery(txt, p.fields()...).Type("cross_fields")
+			if p.MinimumShouldMatch != "" {
+				mm = mm.MinimumShouldMatch(p.MinimumShouldMatch)
+			}
+			return mm
+		}
+
+		// the query or, with a lower score, one of its alternative wordings
+		if alts := e.expansions(pq.Terms, lang); len(alts) > 0 {
+			main := elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(match(txt))
+			for _, alt := range alts {
+				main = main.Should(match(alt).Boost(expansionBoost))
+			}
+			qu = qu.Must(main)
+		} else {
+			qu = qu.Must(match(txt))
+		}
+
+		if p.Shingles > 0 {
+			qu = qu.Should(
+				elastic.NewMultiMatchQuery(
+					txt,
+					"title.shingles",
+					"description.shingles",
+				).Type("cross_fields").Boost(p.Shingles),
+			)
+		}
+
+		if p.Phrase > 0 {
+			qu = qu.Should(elastic.NewMatchPhraseQuery("title", txt).Boost(p.Phrase))
+		}
+	}
+
+	qu = operators(qu, pq)
 
 	// Boost results for regional queries (except for .me, .tv, etc. that are used for other purposes sometimes)
 	// https://support.google.com/webmasters/answer/182192#1
 	if t, err := region.TLD(); err == nil {
 		tld := strings.ToLower(t.String())
-		if tld != "us" && tld != "tv" && tld != "me" && tld != "co" && tld != "io" {
-			qu = qu.Should(elastic.NewMatchQuery("tld", tld))
+		if p.TLD > 0 && tld != "us" && tld != "tv" && tld != "me" && tld != "co" && tld != "io" {
+			qu = qu.Should(elastic.NewMatchQuery("tld", tld).Boost(p.TLD))
 		}
 	}
 
-	a, err := e.Analyzer(lang)
+	if !opts.Time.IsZero() {
+		qu = qu.Filter(timeRange(opts.Time))
+	}
+
+	query := p.score(qu)
+	if opts.Freshness {
+		query = fresh(query)
+	}
+
+	indices, err := e.indices(lang, opts.Languages)
 	if err != nil {
 		return res, err
 	}
 
-	idx := e.IndexName(a)
+	names := []string{}
+	for _, idx := range indices {
+		names = append(names, idx.name)
+		res.Languages = append(res.Languages, idx.lang.String())
+	}
+
+	src := elastic.NewSearchSource().Query(query).From(offset).Size(number)
+
+	// the more preferred a language the higher its results rank
+	if len(indices) > 1 {
+		for i, idx := range indices {
+			src = src.IndexBoost(idx.name, languageWeights[i])
+		}
+	}
+
+	// With field collapsing a page is number domains each with up to PerDomain results.
+	// The count is then of domains so the pagination lines up.
+	if opts.PerDomain > 0 {
+		src = src.Collapse(
+			elastic.NewCollapseBuilder("domain.keyword").
+				InnerHit(elastic.NewInnerHit().Name("domain").Size(opts.PerDomain)),
+		).Aggregation("domains", elastic.NewCardinalityAggregation().Field("domain.keyword"))
+	}
 
-	out, err := e.Client.Search().Index(idx).Query(qu).From(offset).Size(number).Do(context.TODO())
+	// we don't create an index for every language
+	out, err := e.Client.Search().Index(names...).SearchSource(src).
+		IgnoreUnavailable(true).AllowNoIndices(true).
+		Do(context.TODO())
 	if err != nil {
 		return res, err
 	}
 
 	res.Count = out.TotalHits()
 
+	if agg, ok := out.Aggregations.Cardinality("domains"); ok && agg.Value != nil {
+		res.Count = int64(*agg.Value)
+	}
+
 	for _, u := range out.Hits.Hits {
-		doc := &document.Document{}
-		err := json.Unmarshal(u.Source, doc)
-		if err != nil {
-			return res, err
+		ih, ok := u.InnerHits["domain"]
+		if !ok || ih.Hits == nil {
+			doc, err := hitDocument(u)
+			if err != nil {
+				return res, err
+			}
+			doc.Lang = indexLanguage(indices, u.Index)
+			res.Documents = append(res.Documents, doc)
+			continue
+		}
+
+		var doc *document.Document
+		for _, h := range ih.Hits.Hits {
+			if doc, err = hitDocument(h); err != nil {
+				return res, err
+			}
+			doc.Lang = indexLanguage(indices, h.Index)
+			res.Documents = append(res.Documents, doc)
 		}
 
-		// Rather than have the highlighting done here in elasticsearch
-		// we should have a method on doc to highlight so we get consistent
-		// highlighting regardless of the backend used???
-		// For now, we have moved highlighting to a javascript function
-		// if des, ok := u.Highlight["description"]; ok {
-		//	for _, v := range des {
-		//		doc.Description = v
-		//	}
-		//}
+		if doc != nil && ih.Hits.TotalHits != nil && ih.Hits.TotalHits.Value > int64(len(ih.Hits.Hits)) {
+			res.more(doc.ID, doc.Domain)
+		}
+	}
 
-		doc.ID = u.Id
-		res.Documents = append(res.Documents, doc)
+	// expand the site someone is looking for with its key pages
+	if offset == 0 && len(res.Documents) > 0 && !pq.HasOperators() && Navigational(q, res.Documents[0]) {
+		top := res.Documents[0]
+		if top.Sitelinks, err = e.sitelinks(names, top, res.Documents); err != nil {
+			log.Info.Printf("unable to fetch sitelinks for %v: %v\n", top.ID, err)
+			err = nil
+		}
 	}
 
 	return res, err
 }
+
+func hitDocument(u *elastic.SearchHit) (*document.Document, error) {
+	doc := &document.Document{}
+	if err := json.Unmarshal(u.Source, doc); err != nil {
+		return nil, err
+	}
+
+	// Rather than have the highlighting done here in elasticsearch
+	// we should have a method on doc to highlight so we get consistent
+	// highlighting regardless of the backend used???
+	// For now, we have moved highlighting to a javascript function
+	// if des, ok := u.Highlight["description"]; ok {
+	//	for _, v := range des {
+	//		doc.Description = v
+	//	}
+	//}
+
+	doc.ID = u.Id
+	return doc, nil
+}
+
+// MaxLanguages is the most languages we search at once
+const MaxLanguages = 3
+
+// languageWeights boost the results of each language by preference
+var languageWeights = [MaxLanguages]float64{1, .7, .5}
+
+// languageIndex is the index of a language
+type languageIndex struct {
+	name string
+	lang language.Tag
+}
+
+// indices are the indices of lang and then the other languages, up to MaxLanguages.
+// Languages sharing an analyzer share an index so are only searched once.
+func (e *ElasticSearch) indices(lang language.Tag, others []language.Tag) ([]languageIndex, error) {
+	a, err := e.Analyzer(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := []languageIndex{{e.IndexName(a), lang}}
+	seen := map[string]bool{a: true}
+
+	for _, l := range others {
+		if len(indices) == MaxLanguages {
+			break
+		}
+
+		a, err := e.Analyzer(l)
+		if err != nil || seen[a] {
+			continue
+		}
+
+		seen[a] = true
+		indices = append(indices, languageIndex{e.IndexName(a), l})
+	}
+
+	return indices, nil
+}
+
+// indexLanguage is the language of the index a result came from.
+// The index may be versioned (e.g. "search-english-v2").
+func indexLanguage(indices []languageIndex, index string) string {
+	for _, idx := range indices {
+		if index == idx.name || strings.HasPrefix(index, idx.name+"-") {
+			return idx.lang.String()
+		}
+	}
+
+	return ""
+}
+
+// operators translates the operators of a query into bool clauses
+func operators(qu *elastic.BoolQuery, q *Query) *elastic.BoolQuery {
+	for _, p := range q.Phrases {
+		qu = qu.Must(
+			elastic.NewMultiMatchQuery(p, "title", "title.lang", "description", "description.lang").Type("phrase"),
+		)
+	}
+
+	for _, e := range q.Exclude {
+		qu = qu.MustNot(
+			elastic.NewMultiMatchQuery(e, "domain", "title", "title.lang", "description", "description.lang").Type("phrase"),
+		)
+	}
+
+	// site:example.com includes subdomains. site:www.example.com doesn't.
+	if len(q.Sites) > 0 {
+		sites := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+		for _, s := range q.Sites {
+			sites = sites.Should(elastic.NewTermQuery("host", s), elastic.NewTermQuery("domain", s))
+		}
+		qu = qu.Filter(sites)
+	}
+
+	for _, s := range q.ExcludeSites {
+		qu = qu.MustNot(elastic.NewTermQuery("host", s), elastic.NewTermQuery("domain", s), elastic.NewTermQuery("tld", s))
+	}
+
+	if len(q.TLDs) > 0 {
+		qu = qu.Filter(elastic.NewTermsQueryFromStrings("tld", q.TLDs...))
+	}
+
+	// an unknown filetype matches nothing, as it should
+	if len(q.FileTypes) > 0 {
+		mimes := []string{}
+		for _, ft := range q.FileTypes {
+			if m, ok := MIME(ft); ok {
+				ft = m
+			}
+			mimes = append(mimes, ft)
+		}
+		qu = qu.Filter(elastic.NewTermsQueryFromStrings("mime", mimes...))
+	}
+
+	for _, t := range q.InTitle {
+		qu = qu.Must(elastic.NewMatchQuery("title", t).Operator("and"))
+	}
+
+	for _, u := range q.InURL {
+		u = wildcardEscaper.Replace(u)
+		qu = qu.Filter(elastic.NewWildcardQuery("id", "*"+u+"*").CaseInsensitive(true))
+	}
+
+	return qu
+}
+
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`)
+
+// timeRange filters on the publication date of a document or,
+// if we couldn't find one, the date it was crawled
+func timeRange(tr TimeRange) elastic.Query {
+	date := elastic.NewRangeQuery("date").Format("strict_date_optional_time")
+	crawled := elastic.NewRangeQuery("crawled").Format("basic_date")
+
+	if !tr.From.IsZero() {
+		date = date.Gte(tr.From.UTC().Format(time.RFC3339))
+		crawled = crawled.Gte(tr.From.UTC().Format("20060102"))
+	}
+
+	if !tr.To.IsZero() {
+		date = date.Lte(tr.To.UTC().Format(time.RFC3339))
+		crawled = crawled.Lte(tr.To.UTC().Format("20060102"))
+	}
+
+	return elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+		date,
+		elastic.NewBoolQuery().
+			MustNot(elastic.NewExistsQuery("date")).
+			Filter(crawled),
+	)
+}
+
+// fresh decays the score of older documents. The score halves at 8 days old.
+// Undated documents are scored as if they were about 10 days old.
+func fresh(qu elastic.Query) elastic.Query {
+	return elastic.NewFunctionScoreQuery().
+		Query(qu).
+		Add(elastic.NewExistsQuery("date"),
+			elastic.NewGaussDecayFunction().FieldName("date").Origin("now").Scale("7d").Offset("1d").Decay(.5)).
+		Add(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("date")),
+			elastic.NewWeightFactorFunction(.25)).
+		ScoreMode("first").
+		BoostMode("multiply")
+}
@@ -1,10 +1,14 @@
 package search
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jonesrussell/jivesearch/search/document"
 	"github.com/olivere/elastic/v7"
@@ -75,7 +79,8 @@ func TestFetch(t *testing.T) {
 			}`,
 			want: want{
 				&Results{
-					Count: 2,
+					Count:     2,
+					Languages: []string{"en"},
 					Documents: []*document.Document{
 						{
 							ID: "http://example.com/articles/is-bob-dylan-literature-1476401068",
@@ -142,7 +147,8 @@ func TestFetch(t *testing.T) {
 			}`,
 			want: want{
 				&Results{
-					Count: 2500,
+					Count:     2500,
+					Languages: []string{"pt-BR"},
 					Documents: []*document.Document{
 						{
 							ID: "http://example.com.br/articles/is-bob-dylan-literature-1476401068",
@@ -183,7 +189,7 @@ func TestFetch(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			got, err := e.Fetch(c.query, c.filter, c.lang, c.region, c.number, c.page)
+			got, err := e.Fetch(c.query, c.filter, c.lang, c.region, c.number, c.page, Options{})
 			if err != c.want.err {
 				t.Fatalf("got err %q; want %q", err, c.want.err)
 			}
@@ -213,3 +219,211 @@ func MockService(url string) (*ElasticSearch, error) {
 
 	return e, nil
 }
+
+func TestOperators(t *testing.T) {
+	for _, c := range []struct {
+		raw  string
+		want string
+	}{
+		{
+			raw:  "bob dylan",
+			want: `{"bool":{}}`,
+		},
+		{
+			raw:  `"bob dylan" -live`,
+			want: `{"bool":{"must":{"multi_match":{"fields":["title","title.lang","description","description.lang"],"query":"bob dylan","type":"phrase"}},"must_not":{"multi_match":{"fields":["domain","title","title.lang","description","description.lang"],"query":"live","type":"phrase"}}}}`,
+		},
+		{
+			raw:  "site:example.com site:uk filetype:pdf",
+			want: `{"bool":{"filter":[{"bool":{"minimum_should_match":"1","should":[{"term":{"host":"example.com"}},{"term":{"domain":"example.com"}}]}},{"terms":{"tld":["uk"]}},{"terms":{"mime":["application/pdf"]}}]}}`,
+		},
+		{
+			raw:  "intitle:dylan inurl:music*",
+			want: `{"bool":{"filter":{"wildcard":{"id":{"case_insensitive":true,"value":"*music\\**"}}},"must":{"match":{"title":{"operator":"and","query":"dylan"}}}}}`,
+		},
+	} {
+		t.Run(c.raw, func(t *testing.T) {
+			src, err := operators(elastic.NewBoolQuery(), ParseQuery(c.raw)).Source()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != c.want {
+				t.Fatalf("got %s; want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	tr := TimeRange{
+		From: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2018, 1, 31, 23, 59, 59, 0, time.UTC),
+	}
+
+	src, err := timeRange(tr).Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"bool":{"minimum_should_match":"1","should":[` +
+		`{"range":{"date":{"format":"strict_date_optional_time","from":"2018-01-01T00:00:00Z","include_lower":true,"include_upper":true,"to":"2018-01-31T23:59:59Z"}}},` +
+		`{"bool":{"filter":{"range":{"crawled":{"format":"basic_date","from":"20180101","include_lower":true,"include_upper":true,"to":"20180131"}}},"must_not":{"exists":{"field":"date"}}}}]}}`
+
+	if string(got) != want {
+		t.Fatalf("got %s; want %s", got, want)
+	}
+}
+
+func TestFetchPerDomain(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = string(b)
+
+		resp := `{
+			"hits": {
+				"total": {"value": 5},
+				"hits": [
+					{
+						"_id": "https://www.example.com/a",
+						"_source": {"domain": "example.com"},
+						"inner_hits": {
+							"domain": {
+								"hits": {
+									"total": {"value": 3},
+									"hits": [
+										{"_id": "https://www.example.com/a", "_source": {"domain": "example.com"}},
+										{"_id": "https://www.example.com/b", "_source": {"domain": "example.com"}}
+									]
+								}
+							}
+						}
+					},
+					{
+						"_id": "https://example.org/",
+						"_source": {"domain": "example.org"},
+						"inner_hits": {
+							"domain": {
+								"hits": {
+									"total": {"value": 1},
+									"hits": [
+										{"_id": "https://example.org/", "_source": {"domain": "example.org"}}
+									]
+								}
+							}
+						}
+					}
+				]
+			},
+			"aggregations": {"domains": {"value": 2}}
+		}`
+
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Fetch("example pages", Off, language.English, language.Region{}, 10, 0, Options{PerDomain: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(body, `"collapse":{"field":"domain.keyword","inner_hits":[{"name":"domain","size":2}]}`) {
+		t.Fatalf("no collapse in request %s", body)
+	}
+
+	ids := []string{}
+	for _, d := range got.Documents {
+		ids = append(ids, d.ID)
+	}
+
+	want := []string{"https://www.example.com/a", "https://www.example.com/b", "https://example.org/"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v; want %v", ids, want)
+	}
+
+	if got.Count != 2 {
+		t.Fatalf("got count %d; want 2", got.Count)
+	}
+
+	more := map[string]string{"https://www.example.com/b": "example.com"}
+	if !reflect.DeepEqual(got.More, more) {
+		t.Fatalf("got %v; want %v", got.More, more)
+	}
+}
+
+func TestFetchLanguages(t *testing.T) {
+	var path, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, body = r.URL.Path, string(b)
+
+		resp := `{"hits":{"total":2,"hits":[
+			{"_index":"search-german","_id":"https://www.example.de/","_source":{"title":"Beispiel"}},
+			{"_index":"search-english-v2","_id":"https://www.example.com/","_source":{"title":"Example"}}
+		]}}`
+
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		Languages: []language.Tag{language.German, language.AmericanEnglish, language.English, language.French, language.Spanish},
+	}
+
+	got, err := e.Fetch("beispiel seite", Off, language.German, language.Region{}, 10, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "/search-german,search-english,search-french/_search"; path != want {
+		t.Fatalf("got path %q; want %q", path, want)
+	}
+
+	if !strings.Contains(body, `"indices_boost":[{"search-german":1},{"search-english":0.7},{"search-french":0.5}]`) {
+		t.Fatalf("no indices_boost in request %s", body)
+	}
+
+	if want := []string{"de", "en-US", "fr"}; !reflect.DeepEqual(got.Languages, want) {
+		t.Fatalf("got languages %v; want %v", got.Languages, want)
+	}
+
+	langs := []string{}
+	for _, d := range got.Documents {
+		langs = append(langs, d.Lang)
+	}
+
+	if want := []string{"de", "en-US"}; !reflect.DeepEqual(langs, want) {
+		t.Fatalf("got %v; want %v", langs, want)
+	}
+}
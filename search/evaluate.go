@@ -0,0 +1,184 @@
+package search
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Judgment is how relevant a url is for a query.
+// 0 is not relevant. Higher grades are more relevant.
+type Judgment struct {
+	Query string
+	URL   string
+	Grade int
+}
+
+// ReadJudgments reads a judged query set from csv: query,url,grade.
+// Lines starting with # are comments.
+func ReadJudgments(r io.Reader) ([]Judgment, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+
+	js := []Judgment{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		g, err := strconv.Atoi(strings.TrimSpace(rec[2]))
+		if err != nil || g < 0 {
+			return nil, fmt.Errorf("invalid grade %q for %q", rec[2], rec[0])
+		}
+
+		js = append(js, Judgment{
+			Query: strings.TrimSpace(rec[0]),
+			URL:   normalizeURL(rec[1]),
+			Grade: g,
+		})
+	}
+
+	return js, nil
+}
+
+// Evaluation is how well a ranking did on a judged query set (averaged over the queries)
+type Evaluation struct {
+	Queries   int
+	K         int
+	NDCG      float64 // normalized discounted cumulative gain @K
+	MRR       float64 // mean reciprocal rank of the first relevant result
+	Precision float64 // precision @K
+}
+
+// Evaluate runs each judged query through the Fetcher and scores the top k results.
+// The queries are searched the way the frontend does: with the safe search filter
+// and the options of each query (see NewOptions), in the first of their languages.
+func Evaluate(f Fetcher, js []Judgment, k int, filter Filter, lang language.Tag, region language.Region, options func(q string) Options) (*Evaluation, error) {
+	queries := []string{}
+	grades := map[string]map[string]int{}
+
+	for _, j := range js {
+		if _, ok := grades[j.Query]; !ok {
+			queries = append(queries, j.Query)
+			grades[j.Query] = map[string]int{}
+		}
+		grades[j.Query][j.URL] = j.Grade
+	}
+
+	ev := &Evaluation{K: k}
+
+	for _, q := range queries {
+		opts := options(q)
+		l := lang
+		if len(opts.Languages) > 0 {
+			l = opts.Languages[0]
+		}
+
+		res, err := f.Fetch(q, filter, l, region, k, 0, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// for providers that don't cluster by domain themselves
+		res = res.Diversify(opts.PerDomain)
+
+		ranked := []string{}
+		for _, d := range res.Documents {
+			ranked = append(ranked, normalizeURL(d.ID))
+		}
+
+		ev.NDCG += NDCG(ranked, grades[q], k)
+		ev.MRR += ReciprocalRank(ranked, grades[q])
+		ev.Precision += PrecisionAt(ranked, grades[q], k)
+		ev.Queries++
+	}
+
+	if ev.Queries > 0 {
+		n := float64(ev.Queries)
+		ev.NDCG, ev.MRR, ev.Precision = ev.NDCG/n, ev.MRR/n, ev.Precision/n
+	}
+
+	return ev, nil
+}
+
+// NDCG is the discounted cumulative gain of the top k ranked urls
+// divided by that of the best possible ranking of the judged urls.
+// Unjudged urls are treated as not relevant.
+func NDCG(ranked []string, grades map[string]int, k int) float64 {
+	ideal := []int{}
+	for _, g := range grades {
+		ideal = append(ideal, g)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ideal)))
+
+	idcg := dcg(ideal, k)
+	if idcg == 0 {
+		return 0
+	}
+
+	gs := []int{}
+	for _, u := range ranked {
+		gs = append(gs, grades[u])
+	}
+
+	return dcg(gs, k) / idcg
+}
+
+func dcg(grades []int, k int) float64 {
+	var sum float64
+	for i, g := range grades {
+		if i == k {
+			break
+		}
+		sum += (math.Pow(2, float64(g)) - 1) / math.Log2(float64(i+2))
+	}
+
+	return sum
+}
+
+// ReciprocalRank is 1/rank of the first relevant url or 0 if there isn't one
+func ReciprocalRank(ranked []string, grades map[string]int) float64 {
+	for i, u := range ranked {
+		if grades[u] > 0 {
+			return 1 / float64(i+1)
+		}
+	}
+
+	return 0
+}
+
+// PrecisionAt is the fraction of the top k ranked urls that are relevant
+func PrecisionAt(ranked []string, grades map[string]int, k int) float64 {
+	if k == 0 {
+		return 0
+	}
+
+	var relevant int
+	for i, u := range ranked {
+		if i == k {
+			break
+		}
+		if grades[u] > 0 {
+			relevant++
+		}
+	}
+
+	return float64(relevant) / float64(k)
+}
+
+// normalizeURL lets "https://example.com" match "https://example.com/"
+func normalizeURL(u string) string {
+	return strings.TrimSuffix(strings.TrimSpace(u), "/")
+}
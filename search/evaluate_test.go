@@ -0,0 +1,142 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonesrussell/jivesearch/search/document"
+	"golang.org/x/text/language"
+)
+
+func TestReadJudgments(t *testing.T) {
+	raw := `# query,url,grade
+bob dylan,https://www.bobdylan.com/,3
+bob dylan, https://en.wikipedia.org/wiki/Bob_Dylan, 2
+"jimi hendrix, guitar",https://www.jimihendrix.com,0
+`
+
+	got, err := ReadJudgments(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Judgment{
+		{"bob dylan", "https://www.bobdylan.com", 3},
+		{"bob dylan", "https://en.wikipedia.org/wiki/Bob_Dylan", 2},
+		{"jimi hendrix, guitar", "https://www.jimihendrix.com", 0},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	for _, bad := range []string{"bob dylan,https://www.bobdylan.com,great\n", "bob dylan,https://www.bobdylan.com\n"} {
+		if _, err := ReadJudgments(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	grades := map[string]int{"a": 3, "b": 2, "c": 0, "d": 1}
+
+	for _, c := range []struct {
+		name      string
+		ranked    []string
+		ndcg      float64
+		rr        float64
+		precision float64
+	}{
+		{"ideal", []string{"a", "b", "d", "c"}, 1, 1, .75},
+		{"reversed", []string{"c", "d", "b", "a"}, .5478, .5, .75},
+		{"unjudged", []string{"x", "y"}, 0, 0, 0},
+		{"empty", []string{}, 0, 0, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := NDCG(c.ranked, grades, 4); math.Abs(got-c.ndcg) > .0001 {
+				t.Errorf("NDCG got %v; want %v", got, c.ndcg)
+			}
+
+			if got := ReciprocalRank(c.ranked, grades); got != c.rr {
+				t.Errorf("ReciprocalRank got %v; want %v", got, c.rr)
+			}
+
+			if got := PrecisionAt(c.ranked, grades, 4); got != c.precision {
+				t.Errorf("PrecisionAt got %v; want %v", got, c.precision)
+			}
+		})
+	}
+}
+
+type mockFetcher map[string][]string
+
+func (m mockFetcher) Fetch(q string, s Filter, lang language.Tag, region language.Region, number int, offset int, opts Options) (*Results, error) {
+	res := &Results{}
+	for _, u := range m[q] {
+		res.Documents = append(res.Documents, &document.Document{ID: u})
+	}
+
+	return res, nil
+}
+
+// optionsFetcher remembers what it was asked to search with
+type optionsFetcher struct {
+	mockFetcher
+	filters []Filter
+	langs   []language.Tag
+	opts    []Options
+}
+
+func (o *optionsFetcher) Fetch(q string, s Filter, lang language.Tag, region language.Region, number int, offset int, opts Options) (*Results, error) {
+	o.filters, o.langs, o.opts = append(o.filters, s), append(o.langs, lang), append(o.opts, opts)
+	return o.mockFetcher.Fetch(q, s, lang, region, number, offset, opts)
+}
+
+func TestEvaluate(t *testing.T) {
+	js := []Judgment{
+		{"bob dylan", "https://www.bobdylan.com", 3},
+		{"jimi hendrix", "https://www.jimihendrix.com", 1},
+	}
+
+	f := &optionsFetcher{
+		mockFetcher: mockFetcher{
+			"bob dylan":    {"https://www.bobdylan.com/", "https://www.example.com"},
+			"jimi hendrix": {"https://www.example.com", "https://www.jimihendrix.com"},
+		},
+	}
+
+	now := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	options := func(q string) Options {
+		return NewOptions(q, TimeRange{}, []language.Tag{language.French, language.English}, now)
+	}
+
+	got, err := Evaluate(f, js, 2, Moderate, language.English, language.Region{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range f.opts {
+		if f.filters[i] != Moderate || f.langs[i] != language.French || f.opts[i].PerDomain != PerDomain {
+			t.Fatalf("searched with %v, %v and %+v", f.filters[i], f.langs[i], f.opts[i])
+		}
+	}
+
+	if got.Queries != 2 || got.K != 2 {
+		t.Fatalf("got %+v", got)
+	}
+
+	if math.Abs(got.NDCG-(1+1/math.Log2(3))/2) > .0001 {
+		t.Errorf("NDCG got %v", got.NDCG)
+	}
+
+	if got.MRR != .75 {
+		t.Errorf("MRR got %v; want .75", got.MRR)
+	}
+
+	if got.Precision != .5 {
+		t.Errorf("Precision got %v; want .5", got.Precision)
+	}
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/jonesrussell/jivesearch/log"
+	"golang.org/x/text/language"
+)
+
+// Expander looks up the synonyms of words (e.g. from Wiktionary)
+type Expander interface {
+	Synonyms(words []string, lang language.Tag) (map[string][]string, error)
+}
+
+// maxExpansions is the most alternative wordings of a query we search
+const maxExpansions = 4
+
+// expansionBoost weighs matches of an alternative wording below the query's own
+const expansionBoost = .5
+
+// Expand rewords the terms of a query with their synonyms, one term at a time:
+// "car rental" with car -> auto, automobile is "auto rental" and "automobile rental".
+func Expand(terms []string, synonyms map[string][]string, max int) []string {
+	alts := []string{}
+	seen := map[string]bool{strings.ToLower(strings.Join(terms, " ")): true}
+
+	for i, t := range terms {
+		for _, sy := range synonyms[strings.ToLower(t)] {
+			if len(alts) == max {
+				return alts
+			}
+
+			words := append(append(append([]string{}, terms[:i]...), sy), terms[i+1:]...)
+			alt := strings.ToLower(strings.Join(words, " "))
+			if seen[alt] {
+				continue
+			}
+
+			seen[alt] = true
+			alts = append(alts, alt)
+		}
+	}
+
+	return alts
+}
+
+// expansions are the alternative wordings of a query's terms
+func (e *ElasticSearch) expansions(terms []string, lang language.Tag) []string {
+	if e.Expander == nil || len(terms) == 0 {
+		return nil
+	}
+
+	synonyms, err := e.Expander.Synonyms(terms, lang)
+	if err != nil {
+		log.Info.Printf("unable to expand %q: %v\n", terms, err)
+		return nil
+	}
+
+	return Expand(terms, synonyms, maxExpansions)
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestExpand(t *testing.T) {
+	synonyms := map[string][]string{
+		"nyc": {"new york city", "new york"},
+		"car": {"auto", "automobile"},
+	}
+
+	for _, c := range []struct {
+		terms []string
+		max   int
+		want  []string
+	}{
+		{[]string{"NYC", "apartments"}, 4, []string{"new york city apartments", "new york apartments"}},
+		{[]string{"nyc", "car", "rental"}, 3, []string{"new york city car rental", "new york car rental", "nyc auto rental"}},
+		{[]string{"bob", "dylan"}, 4, []string{}},
+	} {
+		t.Run(strings.Join(c.terms, " "), func(t *testing.T) {
+			if got := Expand(c.terms, synonyms, c.max); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+type mockExpander struct {
+	synonyms map[string][]string
+	err      error
+}
+
+func (m *mockExpander) Synonyms(words []string, lang language.Tag) (map[string][]string, error) {
+	return m.synonyms, m.err
+}
+
+func TestFetchExpanded(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = string(b)
+
+		if _, err := w.Write([]byte(`{"hits":{"total":0,"hits":[]}}`)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		expander Expander
+		want     bool
+	}{
+		{"none", nil, false},
+		{"synonyms", &mockExpander{synonyms: map[string][]string{"nyc": {"new york city"}}}, true},
+		{"error", &mockExpander{err: errors.New("no table")}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e.Expander = c.expander
+
+			if _, err := e.Fetch("nyc apartments", Off, language.English, language.Region{}, 10, 0, Options{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(body, `"boost":0.5`) && strings.Contains(body, `"query":"new york city apartments"`); got != c.want {
+				t.Fatalf("got expanded %v; want %v in %s", got, c.want, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Classifier scores an image for NSFW content and labels what it shows.
+// It sets the NSFW and Classification fields of the image.
+type Classifier interface {
+	Classify(i *Image, b []byte) error
+}
+
+// HTTPClassifier uses the Caffe/TensorFlow server in classifier.py.
+// The server fetches the image itself so the raw image is unused.
+type HTTPClassifier struct {
+	Client *http.Client
+	Host   string
+}
+
+// Classify asks the server to score the image
+func (h *HTTPClassifier) Classify(i *Image, _ []byte) error {
+	resp, err := h.Client.Get(h.Host + "?image=" + url.QueryEscape(i.ID))
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("classifier returned status %d for %v", resp.StatusCode, i.ID)
+	}
+
+	im := &Image{}
+	if err := json.NewDecoder(resp.Body).Decode(im); err != nil {
+		return err
+	}
+
+	i.NSFW = math.Round(im.NSFW/.0001) / 10000
+	i.Classification = separateKeys(im.Classification)
+	return nil
+}
+
+// minLabelScore is the lowest classification score we index as a label
+const minLabelScore = .05
+
+// SetLabels sets the labels from the classification, best first.
+// Labels are searchable text while the scores weight the match.
+func (i *Image) SetLabels() *Image {
+	i.Labels = nil
+	for k, v := range i.Classification {
+		if v >= minLabelScore && k != "" {
+			i.Labels = append(i.Labels, k)
+		}
+	}
+
+	sort.Slice(i.Labels, func(a, b int) bool {
+		if i.Classification[i.Labels[a]] == i.Classification[i.Labels[b]] {
+			return i.Labels[a] < i.Labels[b]
+		}
+		return i.Classification[i.Labels[a]] > i.Classification[i.Labels[b]]
+	})
+
+	return i
+}
+
+// separateKeys turns "punching bag, punch bag" to 2 items
+// In case of duplicate keys we take that with highest value
+func separateKeys(c map[string]float64) map[string]float64 {
+	m := map[string]float64{}
+	for key, val := range c {
+		rounded := math.Round(val/.001) / 1000
+		for _, s := range strings.Split(key, ",") {
+			s = strings.TrimSpace(s)
+			if v, ok := m[s]; ok {
+				if v >= rounded {
+					continue
+				}
+			}
+			m[s] = rounded
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,49 @@
+package image
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestHTTPClassifier(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:8080?image=https%3A%2F%2Fwww.example.com%2Fcat.jpg",
+		httpmock.NewStringResponder(200, `{"nsfw_score":0.012345678,"classification":{"tabby, tabby cat":0.81234,"tiger cat":0.1,"tabby":0.2}}`))
+
+	c := &HTTPClassifier{Client: &http.Client{}, Host: "http://127.0.0.1:8080"}
+	i := &Image{ID: "https://www.example.com/cat.jpg"}
+
+	if err := c.Classify(i, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if i.NSFW != 0.0123 {
+		t.Fatalf("got %v; want %v", i.NSFW, 0.0123)
+	}
+
+	want := map[string]float64{"tabby": .812, "tabby cat": .812, "tiger cat": .1}
+	if !reflect.DeepEqual(i.Classification, want) {
+		t.Fatalf("got %+v; want %+v", i.Classification, want)
+	}
+}
+
+func TestSetLabels(t *testing.T) {
+	i := &Image{
+		Classification: map[string]float64{
+			"tabby":        .5,
+			"tiger cat":    .3,
+			"egyptian cat": .3,
+			"carton":       .01,
+		},
+	}
+
+	want := []string{"tabby", "egyptian cat", "tiger cat"}
+	if got := i.SetLabels().Labels; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
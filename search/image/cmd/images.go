@@ -1,15 +1,18 @@
+// Command images fetches the images found by the crawler and saves their
+// dimensions, MIME, EXIF data and perceptual hash. NSFW scores and labels
+// come from the classifier server at nsfw.host, if set.
 package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
-	"math"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
-	"encoding/json"
 	"sync"
 
 	"github.com/jonesrussell/jivesearch/config"
@@ -20,14 +23,18 @@ import (
 )
 
 type conf struct {
-	e       *img.ElasticSearch
-	workers int
-	client  *http.Client
-	host    string
-	since   time.Time
-	ch      chan *img.Image
+	e          *img.ElasticSearch
+	workers    int
+	client     *http.Client
+	useragent  string
+	maxBytes   int64
+	classifier img.Classifier
+	since      time.Time
+	ch         chan *img.Image
 }
 
+var now = func() time.Time { return time.Now().UTC() }
+
 var c *conf
 
 func afterFn(executionID int64, requests []elastic.BulkableRequest, resp *elastic.BulkResponse, err error) {
@@ -60,9 +67,15 @@ func setup(v *viper.Viper) {
 		client: &http.Client{
 			Timeout: 25 * time.Second,
 		},
-		host:  v.GetString("nsfw.host"),
-		since: v.GetTime("nsfw.since"),
-		ch:    make(chan *img.Image),
+		useragent: v.GetString("useragent"),
+		maxBytes:  v.GetInt64("nsfw.max.bytes"),
+		since:     v.GetTime("nsfw.since"),
+		ch:        make(chan *img.Image),
+	}
+
+	// the classifier server is optional
+	if host := v.GetString("nsfw.host"); host != "" {
+		c.classifier = &img.HTTPClassifier{Client: c.client, Host: host}
 	}
 }
 
@@ -151,58 +164,39 @@ func main() {
 }
 
 func (c *conf) fetchImage(i *img.Image) (*img.Image, error) {
-	i.Crawled = time.Now().Format("20060102")
+	i.Crawled = now().Format("20060102")
 
-	// The following is a pure Go attempt to cut out the Python server altogether
-	// but doesn't seem to work very well:
-	// https://gist.github.com/brentadamson/e601d8a2704e10dcd2d3ea5c31301994
-	u := c.host + "?image=" + i.ID
-	resp, err := c.client.Get(u)
+	req, err := http.NewRequest("GET", i.ID, nil)
 	if err != nil {
 		return i, err
 	}
 
-	defer resp.Body.Close()
+	req.Header.Set("User-Agent", c.useragent)
 
-	if resp.StatusCode == 200 {
-		bdy, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return i, err
-		}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return i, err
+	}
 
-		im := &img.Image{}
+	defer resp.Body.Close()
 
-		if err := json.Unmarshal(bdy, &im); err != nil {
-			return i, err
-		}
+	if resp.StatusCode != http.StatusOK {
+		return i, fmt.Errorf("status %d for %v", resp.StatusCode, i.ID)
+	}
 
-		i.NSFW = math.Round(im.NSFW/.0001) / 10000
-		i.Width = im.Width
-		i.Height = im.Height
-		i.MIME = im.MIME
-		i.EXIF = im.EXIF
-		i.Classification = separateKeys(im.Classification)
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, c.maxBytes))
+	if err != nil {
+		return i, err
 	}
 
-	return i, err
-}
+	if err := i.SetMetadata(b); err != nil {
+		return i, err
+	}
 
-// separateKeys turns "punching bag, punch bag" to 2 items
-// In case of duplicate keys we take that with highest value
-func separateKeys(c map[string]float64) map[string]float64 {
-	m := map[string]float64{}
-	for key, val := range c {
-		rounded := math.Round(val/.001) / 1000
-		for _, s := range strings.Split(key, ",") {
-			s = strings.TrimSpace(s)
-			if v, ok := m[s]; ok {
-				if v >= rounded {
-					continue
-				}
-			}
-			m[s] = rounded
-		}
+	if c.classifier != nil {
+		err = c.classifier.Classify(i, b)
+		i.SetLabels()
 	}
 
-	return m
+	return i, err
 }
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/jarcoal/httpmock"
+	img "github.com/jonesrussell/jivesearch/search/image"
 	"github.com/spf13/viper"
 )
 
@@ -14,3 +23,74 @@ func TestSetup(t *testing.T) {
 		t.Fatalf("c is nil")
 	}
 }
+
+type stubClassifier struct{}
+
+func (s *stubClassifier) Classify(i *img.Image, b []byte) error {
+	i.NSFW = 0.5
+	i.Classification = map[string]float64{"square": 1}
+	return nil
+}
+
+func TestFetchImage(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	now = func() time.Time {
+		return time.Date(2018, 02, 06, 20, 34, 58, 0, time.UTC)
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 10; y++ {
+			m.Set(x, y, color.RGBA{R: uint8(x * 10), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	u := "https://www.example.com/square.png"
+	httpmock.RegisterResponder("GET", u, httpmock.NewBytesResponder(200, buf.Bytes()))
+
+	for _, cl := range []struct {
+		name       string
+		classifier img.Classifier
+		nsfw       float64
+		labels     []string
+	}{
+		{"without classifier", nil, 0, nil},
+		{"with classifier", &stubClassifier{}, 0.5, []string{"square"}},
+	} {
+		t.Run(cl.name, func(t *testing.T) {
+			cf := &conf{
+				client:     &http.Client{},
+				maxBytes:   int64(buf.Len()), // httpmock rewinds the body after EOF
+				classifier: cl.classifier,
+			}
+
+			got, err := cf.fetchImage(&img.Image{ID: u})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Width != 20 || got.Height != 10 || got.MIME != "image/png" || got.Hash == "" {
+				t.Fatalf("unexpected metadata %+v", got)
+			}
+
+			if got.Crawled != "20180206" {
+				t.Fatalf("got %q; want %q", got.Crawled, "20180206")
+			}
+
+			if got.NSFW != cl.nsfw {
+				t.Fatalf("got %v; want %v", got.NSFW, cl.nsfw)
+			}
+
+			if !reflect.DeepEqual(got.Labels, cl.labels) {
+				t.Fatalf("got %v; want %v", got.Labels, cl.labels)
+			}
+		})
+	}
+}
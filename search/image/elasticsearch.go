@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/jonesrussell/jivesearch/log"
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/olivere/elastic/v7"
 )
 
@@ -17,21 +19,35 @@ type ElasticSearch struct {
 	Type          string
 	Bulk          *elastic.BulkProcessor
 	NSFWThreshold float64
+	Classifier    bool // images are scored by a classifier (see nsfw.host)
 }
 
 // Fetch returns image results for a search query
-func (e *ElasticSearch) Fetch(q string, safe bool, number int, offset int) (*Results, error) {
+func (e *ElasticSearch) Fetch(q string, safe bool, number int, offset int, f Filters) (*Results, error) {
 	res := &Results{}
 
-	var safeQuery string
+	filter, err := json.Marshal(f.queries())
+	if err != nil {
+		return res, err
+	}
+
+	nsfw, err := json.Marshal(nsfwQuery(safe, e.NSFWThreshold, e.Classifier))
+	if err != nil {
+		return res, err
+	}
+
+	match, err := json.Marshal(q)
+	if err != nil {
+		return res, err
+	}
 
-	switch safe {
-	case false:
-		safeQuery = fmt.Sprintf(`"gte": %v`, e.NSFWThreshold)
-	default:
-		safeQuery = fmt.Sprintf(`"lt": %v`, e.NSFWThreshold)
+	functions, err := json.Marshal(labelFunctions(q))
+	if err != nil {
+		return res, err
 	}
 
+	// Images with poor alt text can still be found by the text of the
+	// link they are in, the title of their page or what they show.
 	qu := fmt.Sprintf(`{
 		"query": {
 			"function_score": {
@@ -40,43 +56,52 @@ func (e *ElasticSearch) Fetch(q string, safe bool, number int, offset int) (*Res
 						"should": [
 							{
 								"multi_match": {
-									"query": "%v",
+									"query": %s,
 									"fields": [
-										"alt"
+										"alt^3",
+										"anchor^2",
+										"labels^2",
+										"title"
 									]
 								}
 							}
 						],
+						"minimum_should_match": 1,
 						"must": [
-							{
-								"range": {
-									"nsfw_score": {
-										%v
-									}
-								}
-							}
-						]
+							%s
+						],
+						"filter": %s
 					}
 				},
-				"field_value_factor": {
-					"field": "classification.%v",
-					"modifier": "log1p",
-					"missing": 0.0,
-					"factor": 2
-				},
+				"functions": %s,
+				"score_mode": "sum",
 				"boost_mode": "sum"
 			}
 		},
+		"collapse": {
+			"field": "copy"
+		},
+		"aggs": {
+			"copies": {
+				"cardinality": {
+					"field": "copy"
+				}
+			}
+		},
 		"from": %d,
 		"size": %d
-	}`, q, safeQuery, q, offset, number)
+	}`, match, nsfw, filter, functions, offset, number)
 
 	out, err := e.Client.Search(e.Index).Source(qu).Do(context.TODO())
 	if err != nil {
 		return res, err
 	}
 
+	// Copies of an image are collapsed so count them once too.
 	res.Count = out.TotalHits()
+	if agg, ok := out.Aggregations.Cardinality("copies"); ok && agg.Value != nil {
+		res.Count = int64(*agg.Value)
+	}
 
 	for _, h := range out.Hits.Hits {
 		img := &Image{
@@ -93,15 +118,182 @@ func (e *ElasticSearch) Fetch(q string, safe bool, number int, offset int) (*Res
 	return res, err
 }
 
+// nsfwQuery limits images to those on the safe (or not) side of the nsfw threshold.
+// With a classifier, safe search only shows images it scored. Without one
+// (see nsfw.host) no image has a score so they pass and the frontend
+// checks their alt text instead.
+func nsfwQuery(safe bool, threshold float64, classifier bool) interface{} {
+	type m map[string]interface{}
+
+	rng := m{"gte": threshold}
+	if safe {
+		rng = m{"lt": threshold}
+		if classifier {
+			return m{"range": m{"nsfw_score": rng}}
+		}
+	}
+
+	return m{
+		"bool": m{
+			"should": []interface{}{
+				m{"range": m{"nsfw_score": rng}},
+				m{"bool": m{"must_not": m{"exists": m{"field": "nsfw_score"}}}},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// maxLabels is the most labels of a query we boost by their classification score
+const maxLabels = 10
+
+// labelFunctions boost images by the classification score of the labels in a query.
+// "black cat" could be the label "black cat", "black" or "cat".
+func labelFunctions(q string) []interface{} {
+	type m map[string]interface{}
+
+	fields := strings.Fields(strings.ToLower(q))
+	labels := []string{strings.Join(fields, " ")}
+	for i := range fields {
+		labels = append(labels, fields[i])
+		if i > 0 {
+			labels = append(labels, fields[i-1]+" "+fields[i])
+		}
+	}
+
+	fns := []interface{}{}
+	seen := map[string]bool{}
+
+	for _, l := range labels {
+		if l == "" || seen[l] || len(fns) == maxLabels {
+			continue
+		}
+
+		seen[l] = true
+		fns = append(fns, m{
+			"field_value_factor": m{
+				"field":    "classification." + l,
+				"modifier": "log1p",
+				"missing":  0.0,
+				"factor":   2,
+			},
+		})
+	}
+
+	return fns
+}
+
+// Get retrieves an image by its url
+func (e *ElasticSearch) Get(id string) (*Image, error) {
+	out, err := e.Client.Get().Index(e.Index).Id(id).Do(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{}
+	if err := json.Unmarshal(out.Source, img); err != nil {
+		return nil, err
+	}
+
+	img.ID = out.Id
+	return img, nil
+}
+
+// Similar finds images that look like the image with the given hash, nearest first.
+// Any image sharing a part of the hash is a candidate. Candidates
+// further than SimilarDistance from the hash are dropped.
+func (e *ElasticSearch) Similar(hash string, safe bool, number int) (*Results, error) {
+	res := &Results{}
+
+	parts, err := HashParts(hash)
+	if err != nil {
+		return res, err
+	}
+
+	terms, err := json.Marshal(parts)
+	if err != nil {
+		return res, err
+	}
+
+	nsfw, err := json.Marshal(nsfwQuery(safe, e.NSFWThreshold, e.Classifier))
+	if err != nil {
+		return res, err
+	}
+
+	// ask for more than we need since most candidates won't be close enough
+	qu := fmt.Sprintf(`{
+		"query": {
+			"bool": {
+				"must": [
+					{
+						"terms": {
+							"hash_parts": %s
+						}
+					},
+					%s
+				]
+			}
+		},
+		"collapse": {
+			"field": "hash"
+		},
+		"size": %d
+	}`, terms, nsfw, number*10)
+
+	out, err := e.Client.Search(e.Index).Source(qu).Do(context.TODO())
+	if err != nil {
+		return res, err
+	}
+
+	distances := map[string]int{}
+
+	for _, h := range out.Hits.Hits {
+		img := &Image{
+			ID: h.Id,
+		}
+		if err := json.Unmarshal(h.Source, img); err != nil {
+			return res, err
+		}
+
+		d, err := Distance(hash, img.Hash)
+		if err != nil || d > SimilarDistance {
+			continue
+		}
+
+		distances[img.ID] = d
+		res.Images = append(res.Images, img)
+	}
+
+	sort.SliceStable(res.Images, func(i, j int) bool {
+		return distances[res.Images[i].ID] < distances[res.Images[j].ID]
+	})
+
+	res.Images = Dedupe(res.Images, DuplicateDistance)
+	if len(res.Images) > number {
+		res.Images = res.Images[:number]
+	}
+
+	res.Count = int64(len(res.Images))
+	return res, nil
+}
+
 // Upsert updates an image link or inserts it if it doesn't exist
 // NOTE: Elasticsearch has a 512-byte limit on an insert operation.
 // Upsert does not have that limit.
+// An image is its own copy until it is crawled and hashed. An update without
+// a hash leaves the copy of an existing image alone.
 func (e *ElasticSearch) Upsert(img *Image) error {
+	doc, up := *img, *img
+	doc.Copy, up.Copy = img.Hash, img.Hash
+	if up.Copy == "" {
+		up.Copy = img.ID
+	}
+
 	item := elastic.NewBulkUpdateRequest().
 		Index(e.Index).
 		Id(img.ID).
-		DocAsUpsert(true).
-		Doc(img)
+		Doc(&doc).
+		Upsert(&up)
 
 	e.Bulk.Add(item)
 	return nil
@@ -204,42 +396,143 @@ func (e *ElasticSearch) Uncrawled(number int, since time.Time) ([]*Image, error)
 
 // Setup will create our image index
 func (e *ElasticSearch) Setup() error {
-	exists, err := e.Client.IndexExists(e.Index).Do(context.TODO())
+	return alias.Create(e.Client, e.Index, e.mapping())
+}
+
+// Migrate moves our images to a new version of the index with the current mapping
+// and backfills the copies of the older images.
+func (e *ElasticSearch) Migrate() (string, error) {
+	idx, err := alias.Migrate(e.Client, e.Index, e.mapping())
 	if err != nil {
-		return err
+		return idx, err
 	}
 
-	if !exists {
-		log.Info.Println("Creating index:", e.Index)
-		if _, err = e.Client.CreateIndex(e.Index).Body(e.mapping()).Do(context.TODO()); err != nil {
-			return err
-		}
-	}
+	_, err = e.Client.UpdateByQuery(idx).
+		Query(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("copy"))).
+		Script(elastic.NewScriptInline(backfill)).
+		ProceedOnVersionConflict().
+		Do(context.TODO())
 
-	return nil
+	return idx, err
 }
 
+// backfill sets the copy of an image indexed before it existed
+const backfill = `ctx._source.copy = ctx._source.hash != null ? ctx._source.hash : ctx._source.id`
+
 // mapping is the mapping of our image Index.
+// The hash is a keyword so Similar can collapse copies of an image
+// and the copy so Fetch can (see Upsert).
+// Existing indices pick up changes with "crawler migrate images".
 func (e *ElasticSearch) mapping() string {
-	m := `{
-			"mappings": {
-					"properties": {
-							"expires": {
-									"type": "date",
-									"format": "yyyyMMddHHmm"
-							},
-							"body": {
-									"type": "text",
-									"index": false
-							},
-							"status": {
-									"type": "short"
-							}
-					}
+	return `{
+		"mappings": {
+			"properties": {
+				"id": {
+					"type": "keyword"
+				},
+				"domain": {
+					"type": "keyword"
+				},
+				"alt": {
+					"type": "text"
+				},
+				"anchor": {
+					"type": "text"
+				},
+				"title": {
+					"type": "text"
+				},
+				"labels": {
+					"type": "text"
+				},
+				"classification": {
+					"type": "object"
+				},
+				"nsfw_score": {
+					"type": "float"
+				},
+				"width": {
+					"type": "integer"
+				},
+				"height": {
+					"type": "integer"
+				},
+				"hash": {
+					"type": "keyword"
+				},
+				"hash_parts": {
+					"type": "keyword"
+				},
+				"copy": {
+					"type": "keyword"
+				},
+				"mime": {
+					"type": "keyword"
+				},
+				"color": {
+					"type": "keyword"
+				},
+				"copyright": {
+					"type": "keyword"
+				},
+				"crawled": {
+					"type": "date",
+					"format": "yyyyMMdd"
+				}
 			}
+		}
 	}`
+}
+
+// queries are the Elasticsearch filters. The size and aspect classes are derived from
+// the width and height. The MIME may have been stored simplified or not.
+func (f Filters) queries() []interface{} {
+	type m map[string]interface{}
 
-	fmt.Println(m)
+	qs := []interface{}{}
+
+	switch f.Size {
+	case Small:
+		qs = append(qs,
+			m{"range": m{"width": m{"lt": mediumSide}}},
+			m{"range": m{"height": m{"lt": mediumSide}}},
+		)
+	case Medium:
+		qs = append(qs,
+			m{"range": m{"width": m{"lt": largeSide}}},
+			m{"range": m{"height": m{"lt": largeSide}}},
+			m{"bool": m{"should": []interface{}{
+				m{"range": m{"width": m{"gte": mediumSide}}},
+				m{"range": m{"height": m{"gte": mediumSide}}},
+			}}},
+		)
+	case Large:
+		qs = append(qs, m{"bool": m{"should": []interface{}{
+			m{"range": m{"width": m{"gte": largeSide}}},
+			m{"range": m{"height": m{"gte": largeSide}}},
+		}}})
+	}
+
+	if f.Aspect != "" {
+		min, max := f.Aspect.ratios()
+		qs = append(qs, m{"script": m{"script": m{
+			"source": "if (doc['width'].size() == 0 || doc['height'].size() == 0 || doc['height'].value == 0) { return false; } " +
+				"double r = (double)doc['width'].value / doc['height'].value; return r >= params.min && r < params.max;",
+			"params": m{"min": min, "max": max},
+		}}})
+	}
+
+	if f.MIME != "" {
+		qs = append(qs, m{"terms": m{"mime": []string{f.MIME, "image/" + f.MIME}}})
+	}
+
+	if f.Color != "" {
+		qs = append(qs, m{"terms": m{"color": f.Color.colors()}})
+	}
+
+	if f.License {
+		qs = append(qs, m{"exists": m{"field": "copyright"}})
+	}
 
-	return m
+	return qs
 }
@@ -2,9 +2,12 @@ package image
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -129,7 +132,7 @@ func TestFetch(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			got, err := e.Fetch(c.query, c.safe, c.number, c.offset)
+			got, err := e.Fetch(c.query, c.safe, c.number, c.offset, Filters{})
 			if err != c.want.err {
 				t.Fatalf("got err %q; want %q", err, c.want.err)
 			}
@@ -141,6 +144,147 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+// TestFetchCopies tests that copies of an image are collapsed in the query
+// and counted once, while images that weren't crawled yet (no hash) stay apart.
+func TestFetchCopies(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{
+			"hits": {"total": 5, "hits": [
+				{"_id": "https://example.com/a.jpg", "_source": {"alt": "a cat", "copy": "https://example.com/a.jpg"}},
+				{"_id": "https://example.com/b.jpg", "_source": {"alt": "another cat", "copy": "https://example.com/b.jpg"}},
+				{"_id": "https://example.com/c.jpg", "_source": {"alt": "a crawled cat", "hash": "00000000000000ff", "copy": "00000000000000ff"}}
+			]},
+			"aggregations": {"copies": {"value": 3}}
+		}`))
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Fetch("cat", true, 25, 0, Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Images) != 3 {
+		t.Fatalf("got %d images; want 3", len(got.Images))
+	}
+
+	if got.Count != 3 {
+		t.Fatalf("got count %d; want 3", got.Count)
+	}
+
+	q := strings.Join(strings.Fields(body), "")
+	for _, s := range []string{`"collapse":{"field":"copy"}`, `"cardinality":{"field":"copy"}`} {
+		if !strings.Contains(q, s) {
+			t.Fatalf("query is missing %s: %s", s, body)
+		}
+	}
+}
+
+func TestUpsertCopy(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		img  *Image
+		doc  string
+		up   string
+	}{
+		{
+			"unhashed",
+			&Image{ID: "https://example.com/a.jpg", Domain: "example.com"},
+			`{"id":"https://example.com/a.jpg","domain":"example.com"}`,
+			`{"id":"https://example.com/a.jpg","domain":"example.com","copy":"https://example.com/a.jpg"}`,
+		},
+		{
+			"hashed",
+			&Image{ID: "https://example.com/a.jpg", Domain: "example.com", Hash: "00000000000000ff"},
+			`{"id":"https://example.com/a.jpg","domain":"example.com","hash":"00000000000000ff","copy":"00000000000000ff"}`,
+			`{"id":"https://example.com/a.jpg","domain":"example.com","hash":"00000000000000ff","copy":"00000000000000ff"}`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var body string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.Write([]byte(`{"errors": false, "items": [{"update": {"_id": "a", "status": 201}}]}`))
+			}))
+			defer ts.Close()
+
+			e, err := MockService(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.Upsert(c.img); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.Bulk.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := `{"doc":` + c.doc + `,"upsert":` + c.up + `}`
+			if !strings.Contains(body, want) {
+				t.Fatalf("got %s; want %s", body, want)
+			}
+
+			if c.img.Copy != "" {
+				t.Fatalf("the image was changed: %+v", c.img)
+			}
+		})
+	}
+}
+
+func TestLabelFunctions(t *testing.T) {
+	got, err := json.Marshal(labelFunctions("Black  Cat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(l string) string {
+		return `{"field_value_factor":{"factor":2,"field":"classification.` + l + `","missing":0,"modifier":"log1p"}}`
+	}
+
+	want := "[" + fn("black cat") + "," + fn("black") + "," + fn("cat") + "]"
+	if string(got) != want {
+		t.Fatalf("got %s; want %s", got, want)
+	}
+}
+
+func TestNSFWQuery(t *testing.T) {
+	unscored := func(rng string) string {
+		return `{"bool":{"minimum_should_match":1,"should":[{"range":{"nsfw_score":` + rng + `}},{"bool":{"must_not":{"exists":{"field":"nsfw_score"}}}}]}}`
+	}
+
+	for _, c := range []struct {
+		name       string
+		safe       bool
+		classifier bool
+		want       string
+	}{
+		{"safe", true, true, `{"range":{"nsfw_score":{"lt":0.8}}}`},
+		{"unsafe", false, true, unscored(`{"gte":0.8}`)},
+		{"safe without a classifier", true, false, unscored(`{"lt":0.8}`)},
+		{"unsafe without a classifier", false, false, unscored(`{"gte":0.8}`)},
+	} {
+		got, err := json.Marshal(nsfwQuery(c.safe, .8, c.classifier))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != c.want {
+			t.Fatalf("%v: got %s; want %s", c.name, got, c.want)
+		}
+	}
+}
+
 func TestUpsert(t *testing.T) {
 	for _, c := range []struct {
 		name   string
@@ -357,6 +501,114 @@ func TestUncrawled(t *testing.T) {
 	}
 }
 
+func TestSimilar(t *testing.T) {
+	resp := `{
+		"took": 2,
+		"timed_out": false,
+		"hits": {
+			"total": 3,
+			"max_score": 1,
+			"hits": [
+				{
+					"_index": "test-images",
+					"_id": "https://www.example.com/far.jpg",
+					"_score": 1,
+					"_source": {
+						"id": "https://www.example.com/far.jpg",
+						"hash": "00000000000000ff"
+					}
+				},
+				{
+					"_index": "test-images",
+					"_id": "https://www.example.com/near.jpg",
+					"_score": 1,
+					"_source": {
+						"id": "https://www.example.com/near.jpg",
+						"hash": "0000000000000007"
+					}
+				},
+				{
+					"_index": "test-images",
+					"_id": "https://www.example.com/same.jpg",
+					"_score": 1,
+					"_source": {
+						"id": "https://www.example.com/same.jpg",
+						"hash": "0000000000000000"
+					}
+				}
+			]
+		}
+	}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Similar("0000000000000000", true, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// far.jpg is 8 bits away and near.jpg is a duplicate of same.jpg
+	want := &Results{
+		Count: 1,
+		Images: []*Image{
+			{ID: "https://www.example.com/same.jpg", Hash: "0000000000000000"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	if _, err := e.Similar("invalid", true, 10); err == nil {
+		t.Fatal("expected an error for an invalid hash")
+	}
+}
+
+func TestGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := `{
+			"_index": "test-images",
+			"_id": "https://www.example.com/a.jpg",
+			"_version": 1,
+			"found": true,
+			"_source": {
+				"id": "https://www.example.com/a.jpg",
+				"domain": "example.com",
+				"hash": "0123456789abcdef"
+			}
+		}`
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Get("https://www.example.com/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Image{ID: "https://www.example.com/a.jpg", Domain: "example.com", Hash: "0123456789abcdef"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+}
+
 func TestSetup(t *testing.T) {
 	for _, c := range []struct {
 		name   string
@@ -376,9 +628,14 @@ func TestSetup(t *testing.T) {
 			}))
 			defer ts.Close()
 
-			handler = func(w http.ResponseWriter, _ *http.Request) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				resp := c.resp
+				if strings.HasSuffix(r.URL.Path, "/_alias") {
+					resp = `{"images-v1": {"aliases": {"images": {}}}}`
+				}
+
 				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
+				if _, err := w.Write([]byte(resp)); err != nil {
 					t.Fatal(err)
 				}
 			}
@@ -0,0 +1,193 @@
+package image
+
+import (
+	"strings"
+)
+
+// Filters narrow down image results. The zero value doesn't filter anything.
+type Filters struct {
+	Size    Size
+	Aspect  Aspect
+	MIME    string // simplified (e.g. "png")
+	Color   Color
+	License bool // only images with copyright info
+}
+
+// Size is a size class of an image, derived from its longest side
+type Size string
+
+// Image sizes
+const (
+	Small  Size = "small"  // < 400px
+	Medium Size = "medium" // 400px - 1199px
+	Large  Size = "large"  // >= 1200px
+)
+
+const (
+	mediumSide = 400
+	largeSide  = 1200
+)
+
+// Aspect is the shape of an image
+type Aspect string
+
+// Aspect ratios (width / height)
+const (
+	Tall      Aspect = "tall"      // < 0.8
+	Square    Aspect = "square"    // 0.8 - 1.25
+	Wide      Aspect = "wide"      // 1.25 - 2
+	Panoramic Aspect = "panoramic" // >= 2
+)
+
+// ratios returns the [min, max) range of width / height for an aspect
+func (a Aspect) ratios() (float64, float64) {
+	switch a {
+	case Tall:
+		return 0, .8
+	case Square:
+		return .8, 1.25
+	case Wide:
+		return 1.25, 2
+	case Panoramic:
+		return 2, 1 << 20
+	}
+
+	return 0, 0
+}
+
+// Color is the dominant color of an image
+type Color string
+
+// Colors. The names are the same as the Pixabay API.
+const (
+	Red       Color = "red"
+	Orange    Color = "orange"
+	Yellow    Color = "yellow"
+	Green     Color = "green"
+	Turquoise Color = "turquoise"
+	Blue      Color = "blue"
+	Lilac     Color = "lilac"
+	Pink      Color = "pink"
+	White     Color = "white"
+	Gray      Color = "gray"
+	Black     Color = "black"
+	Brown     Color = "brown"
+	Grayscale Color = "grayscale" // a filter only: white, gray or black
+)
+
+// colors returns the dominant colors that match a color filter
+func (c Color) colors() []Color {
+	if c == Grayscale {
+		return []Color{White, Gray, Black}
+	}
+
+	return []Color{c}
+}
+
+// ParseFilters returns the valid filters from raw input. Unknown values are ignored.
+func ParseFilters(size, aspect, mime, color, license string) Filters {
+	f := Filters{}
+
+	switch s := Size(strings.ToLower(size)); s {
+	case Small, Medium, Large:
+		f.Size = s
+	}
+
+	switch a := Aspect(strings.ToLower(aspect)); a {
+	case Tall, Square, Wide, Panoramic:
+		f.Aspect = a
+	}
+
+	switch m := strings.ToLower(mime); m {
+	case "jpeg", "png", "gif", "bmp", "tiff", "webp":
+		f.MIME = m
+	case "jpg":
+		f.MIME = "jpeg"
+	}
+
+	switch c := Color(strings.ToLower(color)); c {
+	case Red, Orange, Yellow, Green, Turquoise, Blue, Lilac, Pink, White, Gray, Black, Brown, Grayscale:
+		f.Color = c
+	}
+
+	switch strings.ToLower(license) {
+	case "1", "t", "true", "on":
+		f.License = true
+	}
+
+	return f
+}
+
+// SizeClass is the size class of the image or "" if we don't know its dimensions
+func (i *Image) SizeClass() Size {
+	if i.Width == 0 || i.Height == 0 {
+		return ""
+	}
+
+	side := i.Width
+	if i.Height > side {
+		side = i.Height
+	}
+
+	switch {
+	case side >= largeSide:
+		return Large
+	case side >= mediumSide:
+		return Medium
+	}
+
+	return Small
+}
+
+// AspectClass is the shape of the image or "" if we don't know its dimensions
+func (i *Image) AspectClass() Aspect {
+	if i.Width == 0 || i.Height == 0 {
+		return ""
+	}
+
+	r := float64(i.Width) / float64(i.Height)
+	for _, a := range []Aspect{Tall, Square, Wide, Panoramic} {
+		if min, max := a.ratios(); r >= min && r < max {
+			return a
+		}
+	}
+
+	return ""
+}
+
+// Match tells us if the image passes the filters
+func (f Filters) Match(i *Image) bool {
+	if f.Size != "" && i.SizeClass() != f.Size {
+		return false
+	}
+
+	if f.Aspect != "" && i.AspectClass() != f.Aspect {
+		return false
+	}
+
+	if f.MIME != "" {
+		m := &Image{MIME: i.MIME}
+		if m.SimplifyMIME().MIME != f.MIME {
+			return false
+		}
+	}
+
+	if f.Color != "" {
+		found := false
+		for _, c := range f.Color.colors() {
+			if i.Color == c {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.License && i.Copyright == "" {
+		return false
+	}
+
+	return true
+}
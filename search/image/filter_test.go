@@ -0,0 +1,107 @@
+package image
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	for _, c := range []struct {
+		name                               string
+		size, aspect, mime, color, license string
+		want                               Filters
+	}{
+		{"empty", "", "", "", "", "", Filters{}},
+		{"all", "Large", "wide", "jpg", "grayscale", "1", Filters{Large, Wide, "jpeg", Grayscale, true}},
+		{"invalid", "huge", "round", "exe", "plaid", "maybe", Filters{}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := ParseFilters(c.size, c.aspect, c.mime, c.color, c.license)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %+v; want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClasses(t *testing.T) {
+	for _, c := range []struct {
+		width, height int
+		size          Size
+		aspect        Aspect
+	}{
+		{0, 0, "", ""},
+		{24, 24, Small, Square},
+		{300, 450, Medium, Tall},
+		{1600, 900, Large, Wide},
+		{3000, 1000, Large, Panoramic},
+	} {
+		i := &Image{Width: c.width, Height: c.height}
+		if got := i.SizeClass(); got != c.size {
+			t.Errorf("%dx%d: got size %q; want %q", c.width, c.height, got, c.size)
+		}
+		if got := i.AspectClass(); got != c.aspect {
+			t.Errorf("%dx%d: got aspect %q; want %q", c.width, c.height, got, c.aspect)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	i := &Image{
+		Width:  1600,
+		Height: 900,
+		MIME:   "image/jpeg",
+		Color:  Gray,
+		EXIF:   EXIF{Copyright: "Jane Doe"},
+	}
+
+	for _, c := range []struct {
+		name string
+		Filters
+		want bool
+	}{
+		{"none", Filters{}, true},
+		{"all", Filters{Large, Wide, "jpeg", Grayscale, true}, true},
+		{"size", Filters{Size: Small}, false},
+		{"aspect", Filters{Aspect: Tall}, false},
+		{"mime", Filters{MIME: "png"}, false},
+		{"color", Filters{Color: Blue}, false},
+		{"license", Filters{License: true}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.Filters.Match(i); got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+
+	if (Filters{License: true}).Match(&Image{}) {
+		t.Fatal("expected an image without copyright info to not match")
+	}
+}
+
+func TestQueries(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		Filters
+		want string
+	}{
+		{"none", Filters{}, `[]`},
+		{"small", Filters{Size: Small}, `[{"range":{"width":{"lt":400}}},{"range":{"height":{"lt":400}}}]`},
+		{"mime", Filters{MIME: "png"}, `[{"terms":{"mime":["png","image/png"]}}]`},
+		{"grayscale", Filters{Color: Grayscale}, `[{"terms":{"color":["white","gray","black"]}}]`},
+		{"license", Filters{License: true}, `[{"exists":{"field":"copyright"}}]`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := json.Marshal(c.Filters.queries())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != c.want {
+				t.Fatalf("got %s; want %s", b, c.want)
+			}
+		})
+	}
+}
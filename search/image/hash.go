@@ -0,0 +1,73 @@
+package image
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+)
+
+// DuplicateDistance is the largest Hamming distance between the hashes of
+// two images that we still consider to be the same picture (e.g. resized or recompressed).
+const DuplicateDistance = 4
+
+// SimilarDistance is the largest Hamming distance of a "similar image".
+// Candidates are found by matching one of the 8 byte-sized parts of the hash so
+// any image within 7 bits is guaranteed to be a candidate (pigeonhole principle).
+const SimilarDistance = 7
+
+// HashParts splits a 64-bit hex hash into its 8 bytes, prefixed with their position.
+// "0123456789abcdef" -> ["0:01", "1:23", ... "7:ef"].
+// Searching the parts lets Elasticsearch find near matches of a hash.
+func HashParts(hash string) ([]string, error) {
+	if _, err := strconv.ParseUint(hash, 16, 64); err != nil || len(hash) != 16 {
+		return nil, fmt.Errorf("invalid hash %q", hash)
+	}
+
+	parts := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		parts = append(parts, fmt.Sprintf("%d:%v", i, hash[i*2:i*2+2]))
+	}
+
+	return parts, nil
+}
+
+// Distance is the Hamming distance between two hex hashes
+func Distance(a, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return bits.OnesCount64(x ^ y), nil
+}
+
+// Dedupe removes images that are within distance of an image earlier in the list.
+// Images without a hash (not crawled yet) are always kept.
+func Dedupe(images []*Image, distance int) []*Image {
+	kept := []*Image{}
+
+	for _, i := range images {
+		dup := false
+		for _, k := range kept {
+			if i.Hash == "" || k.Hash == "" {
+				continue
+			}
+
+			if d, err := Distance(i.Hash, k.Hash); err == nil && d <= distance {
+				dup = true
+				break
+			}
+		}
+
+		if !dup {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,76 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashParts(t *testing.T) {
+	for _, c := range []struct {
+		hash string
+		want []string
+		err  bool
+	}{
+		{
+			"0123456789abcdef",
+			[]string{"0:01", "1:23", "2:45", "3:67", "4:89", "5:ab", "6:cd", "7:ef"},
+			false,
+		},
+		{"abc", nil, true},
+		{"not a hash here!", nil, true},
+	} {
+		t.Run(c.hash, func(t *testing.T) {
+			got, err := HashParts(c.hash)
+			if (err != nil) != c.err {
+				t.Fatalf("got err %v", err)
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"0000000000000000", "0000000000000000", 0},
+		{"0000000000000000", "0000000000000001", 1},
+		{"00000000000000ff", "0000000000000000", 8},
+		{"ffffffffffffffff", "0000000000000000", 64},
+	} {
+		t.Run(c.a+c.b, func(t *testing.T) {
+			got, err := Distance(c.a, c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != c.want {
+				t.Fatalf("got %d; want %d", got, c.want)
+			}
+		})
+	}
+
+	if _, err := Distance("xyz", "0000000000000000"); err == nil {
+		t.Fatal("expected an error for an invalid hash")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	images := []*Image{
+		{ID: "https://cdn1.example.com/a.jpg", Hash: "0000000000000000"},
+		{ID: "https://cdn2.example.com/a.jpg", Hash: "0000000000000003"}, // resized copy
+		{ID: "https://www.example.com/b.jpg", Hash: "ffffffffffffffff"},
+		{ID: "https://www.example.com/uncrawled.jpg"},
+		{ID: "https://www.example.com/uncrawled2.jpg"},
+	}
+
+	want := []*Image{images[0], images[2], images[3], images[4]}
+
+	if got := Dedupe(images, DuplicateDistance); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+}
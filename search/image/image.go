@@ -10,12 +10,19 @@ import (
 
 // Image is a link to an image
 type Image struct {
-	ID     string  `json:"id"`
-	Domain string  `json:"domain"`
-	Alt    string  `json:"alt,omitempty"`
-	NSFW   float64 `json:"nsfw_score,omitempty"`
-	Width  int     `json:"width,omitempty"`
-	Height int     `json:"height,omitempty"`
+	ID        string   `json:"id"`
+	Domain    string   `json:"domain"`
+	Alt       string   `json:"alt,omitempty"`
+	NSFW      float64  `json:"nsfw_score,omitempty"`
+	Width     int      `json:"width,omitempty"`
+	Height    int      `json:"height,omitempty"`
+	Hash      string   `json:"hash,omitempty"`       // perceptual hash (hex)
+	HashParts []string `json:"hash_parts,omitempty"` // for finding similar hashes
+	Copy      string   `json:"copy,omitempty"`       // shared by copies of an image: its hash or else its id
+	Color     Color    `json:"color,omitempty"`      // dominant color
+	Title     string   `json:"title,omitempty"`      // title of the page the image was found on
+	Anchor    string   `json:"anchor,omitempty"`     // text of the link around the image
+	Labels    []string `json:"labels,omitempty"`     // classification labels, best first
 	EXIF
 	Classification map[string]float64 `json:"classification,omitempty"`
 	MIME           string             `json:"mime,omitempty"`
@@ -24,14 +31,23 @@ type Image struct {
 }
 
 // EXIF is the metadata of an image
-// What other fields do we need???
+// Note: GPS data is never stored.
 type EXIF struct {
-	Copyright string `json:"copyright,omitempty"`
+	Copyright   string `json:"copyright,omitempty"`
+	Make        string `json:"make,omitempty"`  // camera manufacturer
+	Model       string `json:"model,omitempty"` // camera model
+	Orientation int    `json:"orientation,omitempty"`
 }
 
 // Fetcher outlines the methods used to retrieve the image results
 type Fetcher interface {
-	Fetch(q string, safe bool, number int, offset int) (*Results, error)
+	Fetch(q string, safe bool, number int, offset int, f Filters) (*Results, error)
+}
+
+// Finder outlines the methods used to find images similar to another image
+type Finder interface {
+	Get(id string) (*Image, error)
+	Similar(hash string, safe bool, number int) (*Results, error)
 }
 
 // Provider is an image source
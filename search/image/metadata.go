@@ -0,0 +1,209 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	goimage "image"
+	"math"
+	"net/http"
+	"strings"
+
+	// register the formats we can decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"golang.org/x/image/draw"
+)
+
+// MaxPixels is the most pixels an image we decode can have. A small file
+// can claim huge dimensions and run us out of memory (a decompression bomb).
+const MaxPixels = 50 * 1000 * 1000
+
+// Decode decodes an image after checking that its dimensions are within MaxPixels
+func Decode(b []byte) (goimage.Image, error) {
+	cfg, _, err := goimage.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxPixels/cfg.Height {
+		return nil, fmt.Errorf("image is too large: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	m, _, err := goimage.Decode(bytes.NewReader(b))
+	return m, err
+}
+
+// SetMetadata extracts the MIME, dimensions, EXIF and perceptual hash from the raw image.
+// Only a few EXIF fields are kept. Location (GPS) data is intentionally never stored.
+func (i *Image) SetMetadata(b []byte) error {
+	i.MIME = strings.Split(http.DetectContentType(b), ";")[0]
+	if !strings.HasPrefix(i.MIME, "image/") {
+		return fmt.Errorf("%v is not an image: %v", i.ID, i.MIME)
+	}
+
+	m, err := Decode(b)
+	if err != nil {
+		return fmt.Errorf("%v: %v", i.ID, err)
+	}
+
+	i.Width, i.Height = m.Bounds().Dx(), m.Bounds().Dy()
+	i.Hash = fmt.Sprintf("%016x", DHash(m))
+	if i.HashParts, err = HashParts(i.Hash); err != nil {
+		return err
+	}
+	i.Color = DominantColor(m)
+
+	// most images don't have EXIF data
+	if x, err := exif.Decode(bytes.NewReader(b)); err == nil {
+		i.EXIF = newEXIF(x)
+	}
+
+	return nil
+}
+
+func newEXIF(x *exif.Exif) EXIF {
+	str := func(name exif.FieldName) string {
+		tag, err := x.Get(name)
+		if err != nil {
+			return ""
+		}
+
+		s, err := tag.StringVal()
+		if err != nil {
+			return ""
+		}
+
+		return strings.TrimSpace(strings.Trim(s, "\x00"))
+	}
+
+	e := EXIF{
+		Copyright: str(exif.Copyright),
+		Make:      str(exif.Make),
+		Model:     str(exif.Model),
+	}
+
+	if tag, err := x.Get(exif.Orientation); err == nil {
+		e.Orientation, _ = tag.Int(0)
+	}
+
+	return e
+}
+
+// DHash is the difference hash of an image. Each bit tells us if a pixel
+// of a 9x8 grayscale thumbnail is brighter than its neighbor on the right.
+// Similar images have hashes with a small Hamming distance.
+// http://www.hackerfactor.com/blog/index.php?/archives/529-Kind-of-Like-That.html
+func DHash(m goimage.Image) uint64 {
+	g := goimage.NewGray(goimage.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(g, g.Bounds(), m, m.Bounds(), draw.Src, nil)
+
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if g.GrayAt(x, y).Y > g.GrayAt(x+1, y).Y {
+				h |= 1
+			}
+		}
+	}
+
+	return h
+}
+
+// DominantColor is the most common named color of a 16x16 thumbnail of the image.
+// Transparent pixels are ignored.
+func DominantColor(m goimage.Image) Color {
+	t := goimage.NewRGBA(goimage.Rect(0, 0, 16, 16))
+	draw.ApproxBiLinear.Scale(t, t.Bounds(), m, m.Bounds(), draw.Src, nil)
+
+	counts := map[Color]int{}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			px := t.RGBAAt(x, y)
+			if px.A < 128 {
+				continue
+			}
+			counts[nameColor(px.R, px.G, px.B)]++
+		}
+	}
+
+	var dominant Color
+	for _, c := range []Color{Red, Orange, Yellow, Green, Turquoise, Blue, Lilac, Pink, White, Gray, Black, Brown} {
+		if counts[c] > counts[dominant] {
+			dominant = c
+		}
+	}
+
+	return dominant
+}
+
+// nameColor buckets a color by its hue, saturation and lightness
+func nameColor(r8, g8, b8 uint8) Color {
+	r, g, b := float64(r8)/255, float64(g8)/255, float64(b8)/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l := (max + min) / 2
+
+	var sat float64
+	if max != min {
+		sat = (max - min) / (1 - math.Abs(2*l-1))
+	}
+
+	switch {
+	case l < .12:
+		return Black
+	case l > .92:
+		return White
+	case sat < .15:
+		if l < .25 {
+			return Black
+		} else if l > .85 {
+			return White
+		}
+		return Gray
+	}
+
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/(max-min), 6)
+	case g:
+		h = (b-r)/(max-min) + 2
+	default:
+		h = (r-g)/(max-min) + 4
+	}
+
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	switch {
+	case h < 15 || h >= 340:
+		return Red
+	case h < 40:
+		if l < .4 {
+			return Brown
+		}
+		return Orange
+	case h < 65:
+		return Yellow
+	case h < 160:
+		return Green
+	case h < 195:
+		return Turquoise
+	case h < 255:
+		return Blue
+	case h < 290:
+		return Lilac
+	}
+
+	return Pink
+}
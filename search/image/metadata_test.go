@@ -0,0 +1,135 @@
+package image
+
+import (
+	"bytes"
+	goimage "image"
+	"image/color"
+	"image/png"
+	"math/bits"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gradient is a simple test image that gets brighter from left to right
+func gradient(w, h int) goimage.Image {
+	m := goimage.NewGray(goimage.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			m.SetGray(x, y, color.Gray{Y: uint8(255 - x*255/w)})
+		}
+	}
+	return m
+}
+
+func encodePNG(t *testing.T, m goimage.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// bomb is the start of a GIF that claims to be 65535x65535
+var bomb = []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+
+func TestDecode(t *testing.T) {
+	m, err := Decode(encodePNG(t, gradient(90, 40)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.Bounds().Dx(); got != 90 {
+		t.Fatalf("got width %d; want 90", got)
+	}
+
+	if _, err := Decode(bomb); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("got %v; want an error for a decompression bomb", err)
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	i := &Image{ID: "https://www.example.com/gradient.png"}
+	if err := i.SetMetadata(encodePNG(t, gradient(90, 40))); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Image{
+		ID:     "https://www.example.com/gradient.png",
+		MIME:   "image/png",
+		Width:  90,
+		Height: 40,
+		Hash:   "ffffffffffffffff", // every pixel is brighter than its right neighbor
+		Color:  Gray,
+		HashParts: []string{
+			"0:ff", "1:ff", "2:ff", "3:ff", "4:ff", "5:ff", "6:ff", "7:ff",
+		},
+	}
+
+	if !reflect.DeepEqual(i, want) {
+		t.Fatalf("got %+v; want %+v", i, want)
+	}
+
+	i = &Image{ID: "https://www.example.com/"}
+	if err := i.SetMetadata([]byte("<html></html>")); err == nil {
+		t.Fatal("expected an error for a non-image")
+	}
+
+	i = &Image{ID: "https://www.example.com/bomb.gif"}
+	if err := i.SetMetadata(bomb); err == nil || i.Width != 0 {
+		t.Fatalf("expected an error for a decompression bomb; got %v", err)
+	}
+}
+
+func TestDHash(t *testing.T) {
+	small, large := DHash(gradient(90, 40)), DHash(gradient(900, 400))
+	if d := bits.OnesCount64(small ^ large); d > 2 {
+		t.Fatalf("expected resized images to have similar hashes; distance %d", d)
+	}
+
+	flipped := goimage.NewGray(goimage.Rect(0, 0, 90, 40))
+	g := gradient(90, 40)
+	for x := 0; x < 90; x++ {
+		for y := 0; y < 40; y++ {
+			flipped.Set(89-x, y, g.At(x, y))
+		}
+	}
+
+	if d := bits.OnesCount64(small ^ DHash(flipped)); d < 32 {
+		t.Fatalf("expected different images to have different hashes; distance %d", d)
+	}
+}
+
+func TestDominantColor(t *testing.T) {
+	for _, c := range []struct {
+		color.Color
+		want Color
+	}{
+		{color.RGBA{230, 20, 20, 255}, Red},
+		{color.RGBA{250, 140, 10, 255}, Orange},
+		{color.RGBA{120, 70, 20, 255}, Brown},
+		{color.RGBA{240, 230, 30, 255}, Yellow},
+		{color.RGBA{30, 180, 40, 255}, Green},
+		{color.RGBA{30, 200, 200, 255}, Turquoise},
+		{color.RGBA{20, 40, 220, 255}, Blue},
+		{color.RGBA{140, 60, 200, 255}, Lilac},
+		{color.RGBA{240, 100, 180, 255}, Pink},
+		{color.RGBA{255, 255, 255, 255}, White},
+		{color.RGBA{128, 128, 128, 255}, Gray},
+		{color.RGBA{5, 5, 5, 255}, Black},
+		{color.RGBA{0, 0, 0, 0}, ""}, // transparent
+	} {
+		t.Run(string(c.want), func(t *testing.T) {
+			m := goimage.NewRGBA(goimage.Rect(0, 0, 50, 50))
+			for x := 0; x < 50; x++ {
+				for y := 0; y < 50; y++ {
+					m.Set(x, y, c.Color)
+				}
+			}
+
+			if got := DominantColor(m); got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -18,8 +18,14 @@ type Pixabay struct {
 // PixabayProvider is an image provider
 const PixabayProvider Provider = "Pixabay"
 
-// Fetch returns image results for a search query
-func (p *Pixabay) Fetch(query string, safe bool, number int, offset int) (*Results, error) {
+// Fetch returns image results for a search query.
+// The filters are passed on to Pixabay so its count and pages stay right.
+// All Pixabay images are released under the Pixabay License so the license filter is a no-op.
+func (p *Pixabay) Fetch(query string, safe bool, number int, offset int, f Filters) (*Results, error) {
+	res := &Results{
+		Provider: PixabayProvider,
+	}
+
 	u, err := url.Parse("https://pixabay.com/api/")
 	if err != nil {
 		return nil, err
@@ -36,6 +42,11 @@ func (p *Pixabay) Fetch(query string, safe bool, number int, offset int) (*Resul
 	q.Set("per_page", strconv.Itoa(number))
 	q.Set("page", strconv.Itoa((offset+number)/number))
 	q.Set("safesearch", safeSearch)
+
+	if !pixabayFilters(q, f) {
+		return res, nil
+	}
+
 	u.RawQuery = q.Encode()
 
 	resp, err := p.HTTPClient.Get(u.String())
@@ -61,10 +72,8 @@ func (p *Pixabay) Fetch(query string, safe bool, number int, offset int) (*Resul
 		return nil, err
 	}
 
-	res := &Results{
-		Provider: PixabayProvider,
-		Count:    pr.Total,
-	}
+	// totalHits is how many of the results the API lets us page through
+	res.Count = pr.TotalHits
 
 	for _, h := range pr.Hits {
 		img := &Image{
@@ -76,6 +85,48 @@ func (p *Pixabay) Fetch(query string, safe bool, number int, offset int) (*Resul
 	return res, err
 }
 
+// pixabayFilters sets the params of the filters on q. Pixabay only has a minimum size,
+// so small images and the upper bound of a size class aren't filtered, and it has no
+// square orientation. It only serves jpeg photos and png vector graphics so
+// other formats can't match anything and we return false.
+func pixabayFilters(q url.Values, f Filters) bool {
+	switch f.MIME {
+	case "":
+	case "jpeg":
+		q.Set("image_type", "photo")
+	case "png":
+		q.Set("image_type", "vector")
+	default:
+		return false
+	}
+
+	if f.Color != "" {
+		q.Set("colors", string(f.Color))
+	}
+
+	switch f.Aspect {
+	case Tall:
+		q.Set("orientation", "vertical")
+	case Wide, Panoramic:
+		q.Set("orientation", "horizontal")
+	}
+
+	// our size class is of the longest side
+	side := "min_width"
+	if f.Aspect == Tall {
+		side = "min_height"
+	}
+
+	switch f.Size {
+	case Medium:
+		q.Set(side, strconv.Itoa(mediumSide))
+	case Large:
+		q.Set(side, strconv.Itoa(largeSide))
+	}
+
+	return true
+}
+
 // PixabayResponse is the raw API response from Pixabay
 type PixabayResponse struct {
 	TotalHits int64 `json:"totalHits"`
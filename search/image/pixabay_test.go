@@ -2,6 +2,7 @@ package image
 
 import (
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 
@@ -17,6 +18,7 @@ func TestPixabayFetch(t *testing.T) {
 		safe   bool
 		number int
 		offset int
+		Filters
 	}
 
 	for _, tt := range []struct {
@@ -29,7 +31,7 @@ func TestPixabayFetch(t *testing.T) {
 	}{
 		{
 			name:   "cat",
-			args:   args{"cat", true, 100, 100},
+			args:   args{"cat", true, 100, 100, Filters{}},
 			u:      `https://pixabay.com/api/?key=test&page=2&per_page=100&q=cat&safesearch=true`,
 			status: 200,
 			resp: `{"totalHits":500,"hits":[
@@ -40,7 +42,7 @@ func TestPixabayFetch(t *testing.T) {
 			}`,
 			want: &Results{
 				Provider: PixabayProvider,
-				Count:    4156,
+				Count:    500,
 				Images: []*Image{
 					{
 						ID: "https://pixabay.com/get/ea33b90e28f5053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_640.jpg",
@@ -51,16 +53,45 @@ func TestPixabayFetch(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "format pixabay doesn't have",
+			args: args{"cat", true, 100, 0, Filters{Aspect: Wide, Color: Gray, MIME: "gif"}},
+			want: &Results{
+				Provider: PixabayProvider,
+			},
+		},
+		{
+			name:   "filtered",
+			args:   args{"cat", true, 100, 100, Filters{Size: Large, Aspect: Wide, Color: Gray, MIME: "jpeg", License: true}},
+			u:      `https://pixabay.com/api/?colors=gray&image_type=photo&key=test&min_width=1200&orientation=horizontal&page=2&per_page=100&q=cat&safesearch=true`,
+			status: 200,
+			resp: `{"totalHits":500,"hits":[
+					{"largeImageURL":"https://pixabay.com/get/ea33b90e28f5053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_1280.jpg","webformatHeight":426,"webformatWidth":640,"likes":42,"imageWidth":4896,"id":3681014,"user_id":1195798,"views":1570,"comments":21,"pageURL":"https://pixabay.com/photos/milk-can-old-pot-deformed-3681014/","imageHeight":3264,"webformatURL":"https://pixabay.com/get/ea33b90e28f5053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_640.jpg","type":"photo","previewHeight":99,"tags":"milk can, old, pot","downloads":826,"user":"Couleur","favorites":21,"imageSize":2862898,"previewWidth":150,"userImageURL":"https://cdn.pixabay.com/user/2019/02/12/21-34-01-586_250x250.jpg","previewURL":"https://cdn.pixabay.com/photo/2018/09/16/09/59/milk-can-3681014_150.jpg"},
+					{"largeImageURL":"https://pixabay.com/get/e837b90e2ef7053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_1280.jpg","webformatHeight":398,"webformatWidth":640,"likes":27,"imageWidth":3119,"id":1281634,"user_id":2286921,"views":9643,"comments":0,"pageURL":"https://pixabay.com/photos/person-sport-bike-bicycle-cyclist-1281634/","imageHeight":1943,"webformatURL":"https://pixabay.com/get/e837b90e2ef7053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_640.jpg","type":"photo","previewHeight":93,"tags":"person, sport, bike","downloads":4105,"user":"Pexels","favorites":43,"imageSize":1400111,"previewWidth":150,"userImageURL":"https://cdn.pixabay.com/user/2016/03/26/22-06-36-459_250x250.jpg","previewURL":"https://cdn.pixabay.com/photo/2016/03/26/22/33/person-1281634_150.jpg"}
+				],
+				"total":4156
+			}`,
+			want: &Results{
+				Provider: PixabayProvider,
+				Count:    500,
+				Images: []*Image{
+					{ID: "https://pixabay.com/get/ea33b90e28f5053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_640.jpg"},
+					{ID: "https://pixabay.com/get/e837b90e2ef7053ed1584d05fb1d4797ea70e7d610b00c4090f5c27ea7e4b6bfda_640.jpg"},
+				},
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			responder := httpmock.NewStringResponder(tt.status, tt.resp)
-			httpmock.RegisterResponder("GET", tt.u, responder)
+			if tt.u != "" {
+				responder := httpmock.NewStringResponder(tt.status, tt.resp)
+				httpmock.RegisterResponder("GET", tt.u, responder)
+			}
 
 			p := &Pixabay{
 				Key:        "test",
 				HTTPClient: &http.Client{},
 			}
-			got, err := p.Fetch(tt.args.query, tt.args.safe, tt.args.number, tt.args.offset)
+			got, err := p.Fetch(tt.args.query, tt.args.safe, tt.args.number, tt.args.offset, tt.args.Filters)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -73,3 +104,29 @@ func TestPixabayFetch(t *testing.T) {
 
 	httpmock.Reset()
 }
+
+func TestPixabayFilters(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		Filters
+		want string
+		ok   bool
+	}{
+		{"none", Filters{}, "", true},
+		{"tall and medium", Filters{Size: Medium, Aspect: Tall}, "min_height=400&orientation=vertical", true},
+		{"small", Filters{Size: Small, Aspect: Square}, "", true},
+		{"grayscale vectors", Filters{Color: Grayscale, MIME: "png"}, "colors=grayscale&image_type=vector", true},
+		{"webp", Filters{MIME: "webp"}, "", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			q := url.Values{}
+			if ok := pixabayFilters(q, c.Filters); ok != c.ok {
+				t.Fatalf("got %v; want %v", ok, c.ok)
+			}
+
+			if got := q.Encode(); got != c.want {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package langid identifies the language of short texts, such as search queries.
+// Languages with a script of their own are told by their script. The others are
+// told by their character n-grams, but we only trust those when several words of
+// the text are common words of the language: a name like "le mans" or "carbonara"
+// in an English query looks foreign to the n-grams alone.
+// The profiles are built in so it works offline.
+package langid
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// Guess is the language we think a text is in
+type Guess struct {
+	Language   language.Tag `json:"language"`
+	Confidence float64      `json:"confidence"` // 0 to 1
+}
+
+// maxN is the longest n-gram we use
+const maxN = 3
+
+// smoothing is added to the count of each n-gram so unseen ones aren't impossible
+const smoothing = .5
+
+// buckets is roughly how many distinct n-grams a language has
+const buckets = 5000
+
+// temperature keeps the confidence of short texts honest.
+// The n-grams of a word aren't independent so their log probabilities overstate the evidence.
+const temperature = 3
+
+// minWords is how many common words of a language a text needs before we are sure of it
+const minWords = 2
+
+// unsure is the most confidence we have without enough common words
+const unsure = .5
+
+type profile struct {
+	lang   language.Tag
+	grams  map[string]float64 // log probability of each n-gram
+	unseen float64            // log probability of an n-gram not in grams
+	words  map[string]bool    // the common words of the language
+}
+
+var profiles = []*profile{}
+
+// Detect guesses the language of text. An empty text is und with no confidence.
+func Detect(text string) Guess {
+	if g, ok := byScript(text); ok {
+		return g
+	}
+
+	gs := grams(text)
+	if len(gs) == 0 {
+		return Guess{Language: language.Und}
+	}
+
+	scores := make([]float64, len(profiles))
+	for i, p := range profiles {
+		for _, g := range gs {
+			lp, ok := p.grams[g]
+			if !ok {
+				lp = p.unseen
+			}
+			scores[i] += lp
+		}
+		scores[i] /= temperature
+	}
+
+	best, max := 0, math.Inf(-1)
+	for i, s := range scores {
+		if s > max {
+			best, max = i, s
+		}
+	}
+
+	var sum float64
+	for _, s := range scores {
+		sum += math.Exp(s - max)
+	}
+
+	posterior := func(i int) float64 { return math.Exp(scores[i]-max) / sum }
+
+	// the n-grams only get a say once enough of the words back them up
+	w, share := byWords(text)
+	if w < 0 {
+		return Guess{Language: profiles[best].lang, Confidence: math.Min(posterior(best), unsure)}
+	}
+
+	return Guess{Language: profiles[w].lang, Confidence: math.Max(posterior(w), share)}
+}
+
+// byWords is the profile with the most common words in text and the share of the words
+// they are. It is -1 unless it has at least minWords of them and more than any other profile.
+func byWords(text string) (int, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	best, most, tied := -1, 0, false
+	for i, p := range profiles {
+		var n int
+		for _, w := range words {
+			if p.words[w] {
+				n++
+			}
+		}
+
+		switch {
+		case n > most:
+			best, most, tied = i, n, false
+		case n == most:
+			tied = true
+		}
+	}
+
+	if most < minWords || tied {
+		return -1, 0
+	}
+
+	return best, float64(most) / float64(len(words))
+}
+
+// grams are the n-grams of the words of text, padded with a space
+// so the start and end of a word count: "le" -> " ", "l", "e", " l", "le", "e ", " le", "le "
+func grams(text string) []string {
+	gs := []string{}
+
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		r := []rune(" " + strings.Trim(w, "'") + " ")
+		if len(r) == 2 {
+			continue
+		}
+
+		for n := 1; n <= maxN; n++ {
+			for i := 0; i+n <= len(r); i++ {
+				if n == 1 && r[i] == ' ' {
+					continue
+				}
+				gs = append(gs, string(r[i:i+n]))
+			}
+		}
+	}
+
+	return gs
+}
+
+// scripts are the languages we tell by their script, checked in order.
+// Japanese mixes kana with Han so kana comes first.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  language.Tag
+}{
+	{unicode.Hiragana, language.Japanese},
+	{unicode.Katakana, language.Japanese},
+	{unicode.Hangul, language.Korean},
+	{unicode.Han, language.Chinese},
+	{unicode.Thai, language.Thai},
+	{unicode.Greek, language.Greek},
+	{unicode.Armenian, language.Armenian},
+	{unicode.Devanagari, language.Hindi},
+	{unicode.Hebrew, language.Hebrew},
+	{unicode.Arabic, language.Arabic},
+}
+
+// persian are letters of the Persian alphabet that Arabic doesn't have
+const persian = "پچژگ"
+
+// byScript tells the language by its script if most letters are in a script
+// that only one of our languages uses
+func byScript(text string) (Guess, bool) {
+	var letters int
+	counts := make([]int, len(scripts))
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		for i, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	for i, s := range scripts {
+		if counts[i] == 0 {
+			continue
+		}
+
+		switch {
+		case s.lang == language.Japanese: // any kana makes it Japanese
+			var n int
+			for j, s := range scripts {
+				if s.lang == language.Japanese || s.lang == language.Chinese {
+					n += counts[j]
+				}
+			}
+			return Guess{Language: s.lang, Confidence: float64(n) / float64(letters)}, true
+		case 2*counts[i] <= letters:
+			continue
+		case s.lang == language.Arabic && strings.ContainsAny(text, persian):
+			return Guess{Language: language.Persian, Confidence: float64(counts[i]) / float64(letters)}, true
+		default:
+			return Guess{Language: s.lang, Confidence: float64(counts[i]) / float64(letters)}, true
+		}
+	}
+
+	return Guess{}, false
+}
+
+func init() {
+	for lang, sample := range samples {
+		counts := map[string]float64{}
+		var total float64
+		for _, g := range grams(sample) {
+			counts[g]++
+			total++
+		}
+
+		p := &profile{
+			lang:   lang,
+			grams:  map[string]float64{},
+			unseen: math.Log(smoothing / (total + smoothing*buckets)),
+			words:  map[string]bool{},
+		}
+
+		for _, w := range strings.Fields(sample) {
+			p.words[w] = true
+		}
+
+		for g, c := range counts {
+			p.grams[g] = math.Log((c + smoothing) / (total + smoothing*buckets))
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	// ties go to the same language every time
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].lang.String() < profiles[j].lang.String() })
+}
@@ -0,0 +1,116 @@
+package langid
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestDetect(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		want language.Tag
+		min  float64 // the least confidence we want
+	}{
+		{"the weather today", language.English, .9},
+		{"bonjour le monde", language.French, .9},
+		{"quel temps fait il", language.French, .9},
+		{"wie wird das wetter morgen", language.German, .9},
+		{"che tempo fa oggi", language.Italian, .9},
+		{"previsão do tempo", language.Portuguese, .9},
+		{"het weer vandaag", language.Dutch, .9},
+		{"pogoda w krakowie", language.Polish, .9},
+		{"hava durumu istanbul", language.Turkish, .9},
+		{"времето в софия", language.Bulgarian, .9},
+		{"погода в москве", language.Russian, .8},
+		{"el tiempo en madrid", language.Spanish, .8},
+		{"東京の天気", language.Japanese, 1},
+		{"北京天气", language.Chinese, 1},
+		{"서울 날씨", language.Korean, 1},
+		{"καιρός αθήνα", language.Greek, 1},
+		{"آب و هوای تهران امروز چطور است", language.Persian, 1},
+	} {
+		t.Run(c.text, func(t *testing.T) {
+			got := Detect(c.text)
+			if got.Language != c.want {
+				t.Fatalf("got %v; want %v", got.Language, c.want)
+			}
+
+			if got.Confidence < c.min {
+				t.Fatalf("got confidence %v; want at least %v", got.Confidence, c.min)
+			}
+		})
+	}
+}
+
+func TestDetectUnsure(t *testing.T) {
+	// names and single words could be in any language
+	for _, text := range []string{"paris", "bob dylan", "wikipedia", "hund"} {
+		if got := Detect(text); got.Confidence >= .75 {
+			t.Errorf("%q: got %v with confidence %v", text, got.Language, got.Confidence)
+		}
+	}
+
+	if got := Detect(" 42 !"); got.Language != language.Und || got.Confidence != 0 {
+		t.Fatalf("got %+v; want und", got)
+	}
+}
+
+// queries that aren't made of the words in our profiles
+func TestDetectHeldOut(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		want language.Tag
+	}{
+		{"donde esta la biblioteca", language.Spanish},
+		{"cuál es la capital de francia", language.Spanish},
+		{"où est la tour eiffel", language.French},
+		{"comment faire une tarte aux pommes", language.French},
+		{"wo ist der bahnhof", language.German},
+		{"come fare la pizza in casa", language.Italian},
+		{"hoe laat is het", language.Dutch},
+	} {
+		t.Run(c.text, func(t *testing.T) {
+			got := Detect(c.text)
+			if got.Language != c.want || got.Confidence < .75 {
+				t.Fatalf("got %v with confidence %v; want %v", got.Language, got.Confidence, c.want)
+			}
+		})
+	}
+
+	// English queries with foreign names are English
+	for _, text := range []string{
+		"le mans 24 hours",
+		"restaurants in le mans",
+		"golang tutorial",
+		"pasta carbonara recipe",
+		"de la soul songs",
+		"hotels near paris de gaulle airport",
+		"el camino real hotel",
+		"buenos aires weather",
+		"los angeles lakers score",
+		"san francisco giants tickets",
+		"je ne regrette rien lyrics",
+	} {
+		t.Run(text, func(t *testing.T) {
+			if got := Detect(text); got.Language != language.English && got.Confidence >= .75 {
+				t.Fatalf("got %v with confidence %v", got.Language, got.Confidence)
+			}
+		})
+	}
+}
+
+func TestGrams(t *testing.T) {
+	got := grams("Le")
+	want := []string{"l", "e", " l", "le", "e ", " le", "le "}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %q; want %q", got, want)
+		}
+	}
+}
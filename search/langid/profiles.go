@@ -0,0 +1,73 @@
+package langid
+
+import "golang.org/x/text/language"
+
+// samples are common words of each language that use the Latin or Cyrillic
+// scripts. Their n-grams make up our profiles. Languages written in their
+// own script are identified by the script alone (see scripts).
+var samples = map[language.Tag]string{
+	language.English: `the of and to in is you that it he was for on are as with his they at be this have
+		from or one had by word but not what all were we when your can said there use an each which she do
+		how their if will up other about out many then them these so some her would make like him into time
+		has look two more write go see number no way could people my than first water been call who its now
+		find long down day did get come made may part new weather news how where best near me free online
+		buy price cheap review recipe song lyrics movie game store today tomorrow what why`,
+	language.French: `le la les de des du un une et est en que qui dans pour pas sur au avec il elle ne se
+		ce cette sont par plus son sa ses nous vous ils elles leur mais ou où comme tout tous être avoir fait
+		faire très bien aussi été peut encore même quand deux entre après avant sans chez moi toi lui notre
+		votre jour temps monde vie bonjour merci recette chanson paroles météo prix acheter pas cher meilleur
+		près de moi gratuit aujourd'hui demain pourquoi comment quel quelle qu'est-ce français maison voiture`,
+	language.German: `der die das und ist nicht ein eine einen dem den des zu mit sich auf für von im in
+		ich du er sie es wir ihr sind war hat haben werden wird auch als an aus bei nach wie noch nur oder
+		aber vor zur zum über so um wenn was wer wo warum mein dein sein unser heute morgen gestern wetter
+		nachrichten preis kaufen günstig bester beste rezept lied liedtext kostenlos straße größe weiß
+		schön frühstück deutsch zeit jahr leben welt haus auto können müssen gibt ohne`,
+	language.Spanish: `el la los las de del un una y es en que por con para no se su sus al lo como más
+		pero este esta está son ser fue ha hay muy también sin sobre entre cuando todo todos donde quién
+		qué cómo por qué porque yo tú él ella nosotros ustedes ellos mi tu hoy mañana ayer tiempo clima
+		noticias precio comprar barato mejor receta canción letra gratis cerca de mí año vida mundo casa
+		coche español niño señor mujer hombre hacer tener`,
+	language.Italian: `il lo la i gli le di del della dei delle un una uno e è che per non con si su al
+		alla come più ma anche sono questo questa quello era ha hanno essere fare molto tutto tutti dove
+		quando perché chi cosa io tu lui lei noi voi loro mio tuo oggi domani ieri tempo meteo notizie
+		prezzo comprare economico migliore ricetta canzone testo gratis vicino a me anno vita mondo casa
+		macchina italiano città bambino uomo donna buongiorno grazie`,
+	language.Portuguese: `o a os as de do da dos das um uma e é que em no na nos nas por para com não se
+		seu sua ao como mais mas este esta isso ser foi tem há muito também sem sobre entre quando tudo
+		todos onde quem qual porque eu você ele ela nós eles meu hoje amanhã ontem tempo previsão notícias
+		preço comprar barato melhor receita música letra grátis perto de mim ano vida mundo casa carro
+		português coração não são então informação`,
+	language.Dutch: `de het een en van in is dat op te zijn met voor niet aan er die om ook als bij maar
+		of door over nog naar uit dan wat wie waar waarom hoe ik jij je hij zij ze wij we mijn jouw ons
+		onze heeft hebben was werd wordt worden kan kunnen moet vandaag morgen gisteren weer nieuws prijs
+		kopen goedkoop beste recept liedje songtekst gratis in de buurt jaar leven wereld huis auto
+		nederlands tijd zijn geen veel`,
+	language.Swedish: `och i att det som en på är av för med till den har de inte om ett han men var jag
+		sig från vi så kan när hade nu där man skulle du hon eller bara efter mot upp vad vem varför hur
+		min din vår idag imorgon igår väder nyheter pris köpa billig bästa recept låt låttext gratis nära
+		mig år liv värld hus bil svenska tid också många över även`,
+	language.Danish: `og i at det er en på af til for med den har de ikke som et han men var jeg sig fra
+		vi så kan når havde nu der man skulle du hun eller kun efter mod op hvad hvem hvorfor hvordan min
+		din vores i dag i morgen i går vejret nyheder pris købe billig bedste opskrift sang sangtekst gratis
+		tæt på mig år liv verden hus bil dansk tid også mange over være blev`,
+	language.Norwegian: `og i det er en på av til for med den har de ikke som et han men var jeg seg fra
+		vi så kan når hadde nå der man skulle du hun eller bare etter mot opp hva hvem hvorfor hvordan min
+		din vår i dag i morgen i går været nyheter pris kjøpe billig beste oppskrift sang sangtekst gratis
+		nær meg år liv verden hus bil norsk tid også mange over være ble ikke`,
+	language.Polish: `i w nie na się z do to że jest jak o co ale po tak od za przez dla czy już tylko
+		jego jej ich był była było są być może będzie gdzie kiedy dlaczego jak ja ty on ona my wy oni mój
+		twój dzisiaj jutro wczoraj pogoda wiadomości cena kupić tanio najlepszy przepis piosenka tekst
+		za darmo blisko mnie rok życie świat dom samochód polski czas więcej bardzo także również`,
+	language.Turkish: `ve bir bu da de için ile ne o ben sen biz siz onlar gibi daha çok var yok ama
+		değil mi mı mu mü olarak kadar sonra önce şey nasıl neden nerede kim hangi benim senin bugün yarın
+		dün hava durumu haberler fiyat satın al ucuz en iyi tarifi şarkı sözleri ücretsiz yakınımda yıl
+		hayat dünya ev araba türkçe zaman ile olan oldu göre şimdi`,
+	language.Russian: `и в не на я что он с как а то это по но она они к у же вы за бы из от так мы
+		его её их был была было были быть когда где почему как кто что мой твой сегодня завтра вчера
+		погода новости цена купить дешево лучший рецепт песня текст песни бесплатно рядом со мной год
+		жизнь мир дом машина русский время очень ещё только или`,
+	language.Bulgarian: `и в не на аз че той с как а това по но тя те към за се да от така ние неговия
+		нейния техния беше бяха бъде когато къде защо как кой какво моят твоят днес утре вчера времето
+		новини цена купи евтино най-добър рецепта песен текст безплатно близо до мен година живот свят
+		къща кола български време много още само или съм сме сте са`,
+}
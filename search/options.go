@@ -0,0 +1,53 @@
+package search
+
+import (
+	"time"
+
+	"github.com/jonesrussell/jivesearch/search/langid"
+	"golang.org/x/text/language"
+)
+
+// PerDomain is how many results of a domain we show per page
+const PerDomain = 2
+
+// How sure we must be of the language of a query to search in it
+const (
+	RouteConfidence = .95 // search its language first
+	BlendConfidence = .75 // search its language after the user's
+)
+
+// NewOptions are the options we search a query with, as the frontend does.
+// langs are the languages to search, in order of preference (see Languages).
+func NewOptions(q string, t TimeRange, langs []language.Tag, now time.Time) Options {
+	opts := Options{
+		Time:      t,
+		Freshness: IsNewsy(q, now),
+		Languages: langs,
+	}
+
+	// a site: search is already limited to a domain
+	if len(ParseQuery(q).Sites) == 0 {
+		opts.PerDomain = PerDomain
+	}
+
+	return opts
+}
+
+// Languages adds the detected language of a query to the languages of the user.
+// m matches it to a language we have an index for.
+func Languages(langs []language.Tag, g langid.Guess, m language.Matcher) []language.Tag {
+	if g.Confidence < BlendConfidence {
+		return langs
+	}
+
+	t, _, c := m.Match(g.Language)
+	if c == language.No {
+		return langs
+	}
+
+	if g.Confidence >= RouteConfidence || len(langs) == 0 {
+		return append([]language.Tag{t}, langs...)
+	}
+
+	return append([]language.Tag{langs[0], t}, langs[1:]...)
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jonesrussell/jivesearch/search/langid"
+	"golang.org/x/text/language"
+)
+
+func TestNewOptions(t *testing.T) {
+	now := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	langs := []language.Tag{language.English}
+
+	for _, c := range []struct {
+		q    string
+		want Options
+	}{
+		{"bob dylan", Options{PerDomain: PerDomain, Languages: langs}},
+		{"bob dylan news", Options{Freshness: true, PerDomain: PerDomain, Languages: langs}},
+		{"bob dylan site:bobdylan.com", Options{Languages: langs}},
+	} {
+		t.Run(c.q, func(t *testing.T) {
+			if got := NewOptions(c.q, TimeRange{}, langs, now); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %+v; want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	m := language.NewMatcher([]language.Tag{language.English, language.French})
+	user := []language.Tag{language.English}
+
+	for _, c := range []struct {
+		name  string
+		langs []language.Tag
+		guess langid.Guess
+		want  []language.Tag
+	}{
+		{"unsure", user, langid.Guess{Language: language.French, Confidence: .5}, user},
+		{"blend", user, langid.Guess{Language: language.French, Confidence: .8}, []language.Tag{language.English, language.French}},
+		{"route", user, langid.Guess{Language: language.French, Confidence: .99}, []language.Tag{language.French, language.English}},
+		{"no languages", nil, langid.Guess{Language: language.French, Confidence: .8}, []language.Tag{language.French}},
+		{"no index", user, langid.Guess{Language: language.Japanese, Confidence: .99}, user},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Languages(c.langs, c.guess, m); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -28,10 +28,15 @@ type Yandex struct {
 // Fetch retrieves search results from the Yandex API.
 // https://tech.yandex.com/xml/doc/dg/concepts/get-request-docpage/
 // https://xml.yandex.com/test/
-func (y *Yandex) Fetch(q string, filter search.Filter, lang language.Tag, region language.Region, number int, offset int) (*search.Results, error) {
+func (y *Yandex) Fetch(q string, filter search.Filter, lang language.Tag, region language.Region, number int, offset int, opts search.Options) (*search.Results, error) {
 	page := (offset / number) + 1
 
-	u, err := y.buildYandexURL(q, filter, region, number, page)
+	query := yandexQuery(search.ParseQuery(q))
+	if d := yandexDate(opts.Time); d != "" {
+		query += " " + d
+	}
+
+	u, err := y.buildYandexURL(query, filter, region, number, page)
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +127,65 @@ func (y *Yandex) buildYandexURL(query string, filter search.Filter, region langu
 	return u, err
 }
 
+// yandexQuery writes the operators of a query in the Yandex syntax.
+// https://yandex.com/support/search/how-to-search/search-operators.html
+func yandexQuery(q *search.Query) string {
+	s := append([]string{}, q.Terms...)
+
+	for _, p := range q.Phrases {
+		s = append(s, fmt.Sprintf("%q", p))
+	}
+
+	for _, e := range q.Exclude {
+		if strings.Contains(e, " ") {
+			e = fmt.Sprintf("%q", e)
+		}
+		s = append(s, "-"+e)
+	}
+
+	for _, site := range q.Sites {
+		s = append(s, "site:"+site)
+	}
+
+	for _, site := range q.ExcludeSites {
+		s = append(s, "-site:"+site)
+	}
+
+	for _, tld := range q.TLDs {
+		s = append(s, "domain:"+tld)
+	}
+
+	for _, ft := range q.FileTypes {
+		s = append(s, "mime:"+ft)
+	}
+
+	for _, t := range q.InTitle {
+		s = append(s, "title:("+t+")")
+	}
+
+	for _, u := range q.InURL {
+		s = append(s, "inurl:"+u)
+	}
+
+	return strings.Join(s, " ")
+}
+
+// yandexDate is the date operator of a time range
+func yandexDate(tr search.TimeRange) string {
+	const layout = "20060102"
+
+	switch {
+	case tr.IsZero():
+		return ""
+	case tr.To.IsZero():
+		return "date:>=" + tr.From.Format(layout)
+	case tr.From.IsZero():
+		return "date:<=" + tr.To.Format(layout)
+	}
+
+	return "date:" + tr.From.Format(layout) + ".." + tr.To.Format(layout)
+}
+
 // YandexResponse is the request and XML response from the Yandex API
 type YandexResponse struct {
 	Attrversion string `xml:"version,attr"  json:",omitempty"`
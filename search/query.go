@@ -0,0 +1,212 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a search query parsed into its operators.
+// "jimi hendrix" -live site:example.com filetype:pdf intitle:guitar
+type Query struct {
+	Terms        []string // plain words
+	Phrases      []string // "exact phrase"
+	Exclude      []string // -word or -"some phrase"
+	Sites        []string // site:example.com or site:www.example.com
+	ExcludeSites []string // -site:example.com
+	TLDs         []string // site:uk or tld:uk
+	FileTypes    []string // filetype:pdf or ext:pdf
+	InTitle      []string // intitle:word
+	InURL        []string // inurl:word
+}
+
+// ParseQuery parses the operators of a raw query. Anything that
+// isn't a valid operator is treated as a plain word.
+func ParseQuery(raw string) *Query {
+	q := &Query{}
+
+	for _, t := range tokenize(raw) {
+		if t.phrase {
+			if t.negate {
+				q.Exclude = append(q.Exclude, t.value)
+				continue
+			}
+			q.Phrases = append(q.Phrases, t.value)
+			continue
+		}
+
+		if q.operator(t) {
+			continue
+		}
+
+		if t.op != "" { // not an operator after all
+			t.value += `"` + t.op + `"`
+		}
+
+		if t.negate {
+			q.Exclude = append(q.Exclude, t.value)
+			continue
+		}
+
+		q.Terms = append(q.Terms, t.value)
+	}
+
+	return q
+}
+
+// operator adds t to q if t is a known operator with a value
+func (q *Query) operator(t token) bool {
+	i := strings.Index(t.value, ":")
+	if i < 1 || t.op == "" && i == len(t.value)-1 {
+		return false
+	}
+
+	op, val := strings.ToLower(t.value[:i]), t.value[i+1:]
+	if t.op != "" {
+		val = t.op
+	}
+
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return false
+	}
+
+	switch op {
+	case "site":
+		val = strings.Trim(strings.ToLower(val), "./")
+		if strings.Contains(val, "/") {
+			return false
+		}
+
+		switch {
+		case t.negate:
+			q.ExcludeSites = append(q.ExcludeSites, val)
+		case !strings.Contains(val, "."):
+			q.TLDs = append(q.TLDs, val)
+		default:
+			q.Sites = append(q.Sites, val)
+		}
+	case "tld":
+		if t.negate {
+			return false
+		}
+		q.TLDs = append(q.TLDs, strings.Trim(strings.ToLower(val), "."))
+	case "filetype", "ext":
+		if t.negate {
+			return false
+		}
+		q.FileTypes = append(q.FileTypes, strings.Trim(strings.ToLower(val), "."))
+	case "intitle":
+		if t.negate {
+			return false
+		}
+		q.InTitle = append(q.InTitle, val)
+	case "inurl":
+		if t.negate {
+			return false
+		}
+		q.InURL = append(q.InURL, val)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Text is the part of the query that is matched against the content
+func (q *Query) Text() string {
+	return strings.Join(append(append([]string{}, q.Terms...), q.Phrases...), " ")
+}
+
+// HasOperators tells us if the query is more than just plain words
+func (q *Query) HasOperators() bool {
+	return len(q.Phrases)+len(q.Exclude)+len(q.Sites)+len(q.ExcludeSites)+
+		len(q.TLDs)+len(q.FileTypes)+len(q.InTitle)+len(q.InURL) > 0
+}
+
+// fileTypes are the MIME types of the filetype: operator.
+// The crawler only indexes what http.DetectContentType recognizes.
+var fileTypes = map[string]string{
+	"htm":  "text/html",
+	"html": "text/html",
+	"txt":  "text/plain",
+	"text": "text/plain",
+	"xml":  "text/xml",
+	"pdf":  "application/pdf",
+	"ps":   "application/postscript",
+	"rss":  "text/xml",
+}
+
+// MIME is the MIME type of a filetype (e.g. "pdf" -> "application/pdf")
+func MIME(filetype string) (string, bool) {
+	m, ok := fileTypes[strings.ToLower(filetype)]
+	return m, ok
+}
+
+type token struct {
+	value  string
+	op     string // quoted value of an operator: intitle:"some words"
+	phrase bool
+	negate bool
+}
+
+// tokenize splits a query on whitespace, keeping quoted phrases together
+func tokenize(raw string) []token {
+	tokens := []token{}
+	rs := []rune(raw)
+
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		t := token{}
+		if rs[i] == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			t.negate = true
+			i++
+		}
+
+		if rs[i] == '"' {
+			end := indexRune(rs, i+1, '"')
+			t.value = strings.Join(strings.Fields(string(rs[i+1:end])), " ")
+			t.phrase = true
+			i = end + 1
+			if t.value != "" {
+				tokens = append(tokens, t)
+			}
+			continue
+		}
+
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) {
+			// op:"quoted value"
+			if rs[i] == '"' && i > start && rs[i-1] == ':' {
+				end := indexRune(rs, i+1, '"')
+				t.op = strings.Join(strings.Fields(string(rs[i+1:end])), " ")
+				t.value = string(rs[start:i])
+				i = end + 1
+				break
+			}
+			i++
+		}
+
+		if t.value == "" {
+			t.value = string(rs[start:i])
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens
+}
+
+// indexRune finds r at or after i. An unclosed quote runs to the end.
+func indexRune(rs []rune, i int, r rune) int {
+	for ; i < len(rs); i++ {
+		if rs[i] == r {
+			return i
+		}
+	}
+
+	return len(rs)
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	for _, c := range []struct {
+		raw  string
+		want *Query
+	}{
+		{
+			raw:  "bob dylan",
+			want: &Query{Terms: []string{"bob", "dylan"}},
+		},
+		{
+			raw: `"jimi  hendrix" -live -"greatest hits" guitar`,
+			want: &Query{
+				Terms:   []string{"guitar"},
+				Phrases: []string{"jimi hendrix"},
+				Exclude: []string{"live", "greatest hits"},
+			},
+		},
+		{
+			raw: "SITE:Example.com site:www.example.org/ -site:spam.com site:uk tld:.de",
+			want: &Query{
+				Sites:        []string{"example.com", "www.example.org"},
+				ExcludeSites: []string{"spam.com"},
+				TLDs:         []string{"uk", "de"},
+			},
+		},
+		{
+			raw: `report filetype:PDF ext:.txt intitle:"annual report" inurl:2018`,
+			want: &Query{
+				Terms:     []string{"report"},
+				FileTypes: []string{"pdf", "txt"},
+				InTitle:   []string{"annual report"},
+				InURL:     []string{"2018"},
+			},
+		},
+		{
+			// not operators
+			raw:  `10:30 https://example.com site: foo:bar - c++ -intitle:"x y"`,
+			want: &Query{Terms: []string{"10:30", "https://example.com", "site:", "foo:bar", "-", "c++"}, Exclude: []string{`intitle:"x y"`}},
+		},
+		{
+			raw:  `unclosed "quote here`,
+			want: &Query{Terms: []string{"unclosed"}, Phrases: []string{"quote here"}},
+		},
+	} {
+		t.Run(c.raw, func(t *testing.T) {
+			got := ParseQuery(c.raw)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %+v; want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestQueryText(t *testing.T) {
+	q := ParseQuery(`"jimi hendrix" guitar -live site:example.com`)
+
+	if got := q.Text(); got != "guitar jimi hendrix" {
+		t.Fatalf("got %q", got)
+	}
+
+	if !q.HasOperators() {
+		t.Fatal("expected operators")
+	}
+
+	if ParseQuery("just words").HasOperators() {
+		t.Fatal("expected no operators")
+	}
+}
+
+func TestMIME(t *testing.T) {
+	if m, ok := MIME("PDF"); !ok || m != "application/pdf" {
+		t.Fatalf("got %q, %v", m, ok)
+	}
+
+	if _, ok := MIME("exe"); ok {
+		t.Fatal("expected unknown filetype")
+	}
+}
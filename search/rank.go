@@ -0,0 +1,107 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// Profile is a set of ranking boosts and signals for the Elasticsearch backend.
+// Profiles can be loaded from JSON so ranking can be tuned without a rebuild.
+type Profile struct {
+	Fields             map[string]float64 `json:"fields"`               // field -> boost of the main cross_fields match
+	MinimumShouldMatch string             `json:"minimum_should_match"` // e.g. "-25%"
+	Shingles           float64            `json:"shingles"`             // boost of bigram matches. 0 to disable.
+	Phrase             float64            `json:"phrase"`               // boost of an exact title match. 0 to disable.
+	TLD                float64            `json:"tld"`                  // boost of the user's country TLD. 0 to disable.
+	Signals            []Signal           `json:"signals"`              // numeric document fields that scale the score
+}
+
+// Signal scales the score of a document by one of its numeric fields
+type Signal struct {
+	Field    string  `json:"field"`
+	Factor   float64 `json:"factor"`
+	Modifier string  `json:"modifier"` // none, log1p, sqrt, etc.
+	Missing  float64 `json:"missing"`
+}
+
+// DefaultProfile is our hand-tuned ranking.
+// We weight the domain > path, path > title, title > description.
+var DefaultProfile = &Profile{
+	Fields: map[string]float64{
+		"domain":           3,
+		"path":             2,
+		"title":            1.5,
+		"title.lang":       1.5,
+		"description":      1,
+		"description.lang": 1,
+	},
+	MinimumShouldMatch: "-25%",
+	Shingles:           1,
+	TLD:                1,
+}
+
+// Profiles are the ranking profiles available by name
+var Profiles = map[string]*Profile{
+	"default": DefaultProfile,
+}
+
+// LoadProfiles reads named profiles from JSON: {"name": {"fields": {"title": 2}}}
+// The built-in Profiles are included unless the JSON overrides them.
+func LoadProfiles(r io.Reader) (map[string]*Profile, error) {
+	profiles := map[string]*Profile{}
+	if err := json.NewDecoder(r).Decode(&profiles); err != nil {
+		return nil, err
+	}
+
+	for name, p := range profiles {
+		if p == nil || len(p.Fields) == 0 {
+			return nil, fmt.Errorf("profile %q has no fields", name)
+		}
+	}
+
+	for name, p := range Profiles {
+		if _, ok := profiles[name]; !ok {
+			profiles[name] = p
+		}
+	}
+
+	return profiles, nil
+}
+
+// fields are the boosted fields of the main match, e.g. "domain^3"
+func (p *Profile) fields() []string {
+	fields := []string{}
+	for f, b := range p.Fields {
+		if b == 1 {
+			fields = append(fields, f)
+			continue
+		}
+		fields = append(fields, f+"^"+strconv.FormatFloat(b, 'f', -1, 64))
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+// score wraps the query so the signals scale its score
+func (p *Profile) score(qu elastic.Query) elastic.Query {
+	if len(p.Signals) == 0 {
+		return qu
+	}
+
+	fs := elastic.NewFunctionScoreQuery().Query(qu).ScoreMode("multiply").BoostMode("multiply")
+	for _, s := range p.Signals {
+		fn := elastic.NewFieldValueFactorFunction().Field(s.Field).Factor(s.Factor).Missing(s.Missing)
+		if s.Modifier != "" {
+			fn = fn.Modifier(s.Modifier)
+		}
+		fs = fs.AddScoreFunc(fn)
+	}
+
+	return fs
+}
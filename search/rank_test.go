@@ -0,0 +1,105 @@
+package search
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		raw  string
+		want []string
+		err  bool
+	}{
+		{
+			name: "basic",
+			raw:  `{"title_heavy": {"fields": {"title": 4, "description": 1}, "phrase": 2}}`,
+			want: []string{"default", "title_heavy"},
+		},
+		{
+			name: "override",
+			raw:  `{"default": {"fields": {"title": 1}}}`,
+			want: []string{"default"},
+		},
+		{
+			name: "no fields",
+			raw:  `{"empty": {"tld": 1}}`,
+			err:  true,
+		},
+		{
+			name: "bad json",
+			raw:  `{"empty":`,
+			err:  true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := LoadProfiles(strings.NewReader(c.raw))
+			if c.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for name := range got {
+				names = append(names, name)
+			}
+
+			if len(names) != len(c.want) {
+				t.Fatalf("got %v; want %v", names, c.want)
+			}
+
+			for _, n := range c.want {
+				if _, ok := got[n]; !ok {
+					t.Fatalf("missing profile %q", n)
+				}
+			}
+		})
+	}
+}
+
+func TestProfileFields(t *testing.T) {
+	want := []string{"description", "description.lang", "domain^3", "path^2", "title.lang^1.5", "title^1.5"}
+
+	if got := DefaultProfile.fields(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestProfileScore(t *testing.T) {
+	p := &Profile{
+		Fields: map[string]float64{"title": 1},
+		Signals: []Signal{
+			{Field: "popularity", Factor: 1.2, Modifier: "log1p", Missing: 1},
+		},
+	}
+
+	src, err := p.score(elastic.NewMatchAllQuery()).Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"function_score":{"boost_mode":"multiply","functions":[{"field_value_factor":{"factor":1.2,"field":"popularity","missing":1,"modifier":"log1p"}}],"query":{"match_all":{}},"score_mode":"multiply"}}`
+	if string(got) != want {
+		t.Fatalf("got %s; want %s", got, want)
+	}
+
+	// no signals leaves the query alone
+	if q := DefaultProfile.score(elastic.NewMatchAllQuery()); !reflect.DeepEqual(q, elastic.NewMatchAllQuery()) {
+		t.Fatalf("got %+v; want the match_all query", q)
+	}
+}
@@ -11,7 +11,15 @@ import (
 
 // Fetcher outlines the methods used to retrieve the core search results
 type Fetcher interface {
-	Fetch(q string, s Filter, lang language.Tag, region language.Region, number int, offset int) (*Results, error)
+	Fetch(q string, s Filter, lang language.Tag, region language.Region, number int, offset int, opts Options) (*Results, error)
+}
+
+// Options are the optional settings of a search. The zero value is a plain search.
+type Options struct {
+	Time      TimeRange      // only documents dated within the range
+	Freshness bool           // favor recently dated documents (e.g. for news)
+	PerDomain int            // at most this many results of a domain per page. 0 for no limit. Providers that cluster by domain then count number and offset in domains.
+	Languages []language.Tag // more languages to search, in order of preference
 }
 
 // Provider is a search provider
@@ -39,6 +47,8 @@ type Results struct {
 	Last       string               `json:"-"`
 	Pagination []string             `json:"-"`
 	Documents  []*document.Document `json:"documents"`
+	More       map[string]string    `json:"more,omitempty"`      // document ID -> its domain that has more results
+	Languages  []string             `json:"languages,omitempty"` // the languages searched, most preferred first
 	Err        error
 }
 
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jonesrussell/jivesearch/search/document"
+	"github.com/olivere/elastic/v7"
+)
+
+// MaxSitelinks is the most sitelinks we show for a site
+const MaxSitelinks = 6
+
+// candidates is how many pages of a site we consider for its sitelinks
+const candidates = 30
+
+// Navigational tells us if a query is looking for the site of a document
+// e.g. "github", "git hub" or "github.com" for https://github.com/.
+// Only the homepage of a site qualifies.
+func Navigational(q string, d *document.Document) bool {
+	if d == nil || d.Domain == "" || d.PathParts != "" {
+		return false
+	}
+
+	q = strings.ToLower(strings.Join(strings.Fields(q), ""))
+	q = strings.TrimPrefix(q, "www.")
+	if q == "" {
+		return false
+	}
+
+	dom := strings.ToLower(d.Domain)
+	name := strings.SplitN(dom, ".", 2)[0] // bbc.co.uk -> bbc
+
+	return q == dom || q == name || q == strings.TrimPrefix(strings.ToLower(d.Host), "www.")
+}
+
+// sitelinks are the most linked to pages of the site of top.
+// Pages already in the results are skipped.
+func (e *ElasticSearch) sitelinks(indices []string, top *document.Document, results []*document.Document) ([]*document.Document, error) {
+	qu := elastic.NewBoolQuery().
+		Filter(
+			elastic.NewTermQuery("index", true),
+			elastic.NewTermQuery("domain.keyword", top.Domain),
+			elastic.NewExistsQuery("path_parts"),
+		)
+
+	for _, d := range results {
+		qu = qu.MustNot(elastic.NewTermQuery("id", d.ID))
+	}
+
+	out, err := e.Client.Search().Index(indices...).Query(qu).
+		IgnoreUnavailable(true).AllowNoIndices(true).
+		SortBy(elastic.NewFieldSort("inlinks").Desc().UnmappedType("integer")).
+		Size(candidates).
+		Do(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []*document.Document{}
+	for _, u := range out.Hits.Hits {
+		doc, err := hitDocument(u)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return pickSitelinks(docs, MaxSitelinks), nil
+}
+
+// pickSitelinks favors shallow pages with many inbound links
+// and takes one page from each section of the site (/about, /blog, etc).
+func pickSitelinks(docs []*document.Document, n int) []*document.Document {
+	type candidate struct {
+		*document.Document
+		section string
+		score   float64
+	}
+
+	cs := []candidate{}
+	for _, d := range docs {
+		if d.Title == "" {
+			continue
+		}
+
+		u, err := url.Parse(d.ID)
+		if err != nil {
+			continue
+		}
+
+		segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+		if len(segments) == 0 || len(segments) > 2 {
+			continue
+		}
+
+		cs = append(cs, candidate{
+			Document: d,
+			section:  strings.ToLower(segments[0]),
+			score:    math.Log1p(float64(d.Inlinks)) / float64(len(segments)),
+		})
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].score > cs[j].score })
+
+	seen := map[string]bool{}
+	links := []*document.Document{}
+	for _, c := range cs {
+		if len(links) == n {
+			break
+		}
+		if seen[c.section] {
+			continue
+		}
+		seen[c.section] = true
+		links = append(links, c.Document)
+	}
+
+	return links
+}
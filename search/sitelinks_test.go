@@ -0,0 +1,122 @@
+package search
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonesrussell/jivesearch/search/document"
+	"golang.org/x/text/language"
+)
+
+func TestNavigational(t *testing.T) {
+	home := &document.Document{ID: "https://www.bbc.co.uk/", Host: "www.bbc.co.uk", Domain: "bbc.co.uk"}
+	page := &document.Document{ID: "https://www.bbc.co.uk/news", Host: "www.bbc.co.uk", Domain: "bbc.co.uk", PathParts: "news"}
+
+	for _, c := range []struct {
+		q    string
+		d    *document.Document
+		want bool
+	}{
+		{"bbc", home, true},
+		{"BBC.co.uk", home, true},
+		{"www.bbc.co.uk", home, true},
+		{"b b c", home, true},
+		{"bbc news", home, false},
+		{"bbc", page, false},
+		{"", home, false},
+		{"bbc", nil, false},
+	} {
+		if got := Navigational(c.q, c.d); got != c.want {
+			t.Errorf("%q: got %v; want %v", c.q, got, c.want)
+		}
+	}
+}
+
+func TestPickSitelinks(t *testing.T) {
+	doc := func(id, title string, inlinks int) *document.Document {
+		return &document.Document{ID: id, Inlinks: inlinks, Content: document.Content{Title: title}}
+	}
+
+	docs := []*document.Document{
+		doc("https://example.com/about", "About", 50),
+		doc("https://example.com/about/team", "Team", 60),
+		doc("https://example.com/blog/2018/01/post", "Post", 500),
+		doc("https://example.com/pricing", "Pricing", 40),
+		doc("https://example.com/untitled", "", 100),
+		doc("https://example.com/docs/start", "Getting Started", 1000),
+		doc("https://example.com/contact", "Contact", 5),
+	}
+
+	got := []string{}
+	for _, d := range pickSitelinks(docs, 3) {
+		got = append(got, d.ID)
+	}
+
+	want := []string{
+		"https://example.com/about",
+		"https://example.com/pricing",
+		"https://example.com/docs/start",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestFetchSitelinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := `{"hits":{"total":1,"hits":[{"_id":"https://www.example.com/","_source":{"domain":"example.com","host":"www.example.com","title":"Example"}}]}}`
+		if strings.Contains(string(b), "inlinks") {
+			resp = `{"hits":{"total":2,"hits":[
+				{"_id":"https://www.example.com/docs","_source":{"domain":"example.com","title":"Docs","inlinks":20}},
+				{"_id":"https://www.example.com/about","_source":{"domain":"example.com","title":"About","inlinks":10}}
+			]}}`
+		}
+
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		q      string
+		offset int
+		want   []string
+	}{
+		{"example", 0, []string{"https://www.example.com/docs", "https://www.example.com/about"}},
+		{"example", 10, []string{}},
+		{"example site:example.com", 0, []string{}},
+		{"example docs", 0, []string{}},
+	} {
+		t.Run(c.q, func(t *testing.T) {
+			res, err := e.Fetch(c.q, Off, language.English, language.Region{}, 10, c.offset, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, d := range res.Documents[0].Sitelinks {
+				got = append(got, d.ID)
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"strings"
+	"time"
+)
+
+// TimeRange limits results to documents dated within it.
+// A zero From or To leaves that end open.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsZero tells us if the range doesn't limit anything
+func (t TimeRange) IsZero() bool {
+	return t.From.IsZero() && t.To.IsZero()
+}
+
+// dateLayout is the layout of custom ranges
+const dateLayout = "2006-01-02"
+
+// ParseTimeRange converts "day", "week", "month" or "year" to a range ending now.
+// Any other period is a custom range of from and to ("2018-01-02").
+// The custom range includes all of the "to" day. Invalid dates are ignored.
+func ParseTimeRange(period, from, to string, now time.Time) TimeRange {
+	switch strings.ToLower(period) {
+	case "day":
+		return TimeRange{From: now.AddDate(0, 0, -1)}
+	case "week":
+		return TimeRange{From: now.AddDate(0, 0, -7)}
+	case "month":
+		return TimeRange{From: now.AddDate(0, -1, 0)}
+	case "year":
+		return TimeRange{From: now.AddDate(-1, 0, 0)}
+	}
+
+	f, ferr := time.Parse(dateLayout, from)
+	t, terr := time.Parse(dateLayout, to)
+	if ferr == nil && terr == nil && t.Before(f) {
+		f, t = t, f
+	}
+
+	tr := TimeRange{}
+	if ferr == nil {
+		tr.From = f
+	}
+
+	if terr == nil {
+		tr.To = t.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	return tr
+}
+
+// newsWords hint that the freshest results are the best
+var newsWords = map[string]bool{
+	"news":      true,
+	"latest":    true,
+	"today":     true,
+	"yesterday": true,
+	"tonight":   true,
+	"breaking":  true,
+	"live":      true,
+	"score":     true,
+	"scores":    true,
+	"election":  true,
+	"weather":   true,
+}
+
+// IsNewsy tells us if a query is likely about current events
+// e.g. "election results today" or "world cup 2018" (in 2018).
+func IsNewsy(q string, now time.Time) bool {
+	year := now.Format("2006")
+
+	for _, w := range strings.Fields(strings.ToLower(q)) {
+		if newsWords[w] || w == year {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2018, 2, 6, 20, 34, 58, 0, time.UTC)
+
+	for _, c := range []struct {
+		period, from, to string
+		want             TimeRange
+	}{
+		{"", "", "", TimeRange{}},
+		{"day", "", "", TimeRange{From: time.Date(2018, 2, 5, 20, 34, 58, 0, time.UTC)}},
+		{"Week", "", "", TimeRange{From: time.Date(2018, 1, 30, 20, 34, 58, 0, time.UTC)}},
+		{"month", "", "", TimeRange{From: time.Date(2018, 1, 6, 20, 34, 58, 0, time.UTC)}},
+		{"year", "", "", TimeRange{From: time.Date(2017, 2, 6, 20, 34, 58, 0, time.UTC)}},
+		{
+			"custom", "2017-01-01", "2017-01-31",
+			TimeRange{
+				From: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2017, 1, 31, 23, 59, 59, 0, time.UTC),
+			},
+		},
+		{
+			"custom", "2017-01-31", "2017-01-01", // reversed
+			TimeRange{
+				From: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2017, 1, 31, 23, 59, 59, 0, time.UTC),
+			},
+		},
+		{"custom", "2017-01-01", "garbage", TimeRange{From: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	} {
+		t.Run(c.period+c.from+c.to, func(t *testing.T) {
+			got := ParseTimeRange(c.period, c.from, c.to, now)
+			if !got.From.Equal(c.want.From) || !got.To.Equal(c.want.To) {
+				t.Fatalf("got %+v; want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsNewsy(t *testing.T) {
+	now := time.Date(2018, 2, 6, 20, 34, 58, 0, time.UTC)
+
+	for _, c := range []struct {
+		q    string
+		want bool
+	}{
+		{"bob dylan", false},
+		{"election results today", true},
+		{"Latest NEWS", true},
+		{"winter olympics 2018", true},
+		{"winter olympics 2014", false},
+	} {
+		if got := IsNewsy(c.q, now); got != c.want {
+			t.Errorf("%q: got %v; want %v", c.q, got, c.want)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jonesrussell/jivesearch/search/alias"
 	"github.com/olivere/elastic/v7"
+	"golang.org/x/text/language"
 )
 
 const completionSuggest = "completion_suggest"
@@ -16,16 +18,36 @@ type ElasticSearch struct {
 	Type   string
 }
 
-// Completion handles autocomplete queries
-func (e *ElasticSearch) Completion(term string, size int) (Results, error) {
-	// Another option is the NewFuzzyCompletionSuggester and
-	// set the "Fuzziness" but we'll start with this for now.
+// Completion handles autocomplete queries.
+// Suggestions allow for typos, can match from any of their words (see infixes)
+// and are limited to the user's language, boosted in their region.
+// We fetch extra to rank them by popularity and how well they match.
+func (e *ElasticSearch) Completion(term string, lang language.Tag, region language.Region, size int) (Results, error) {
 	res := Results{}
 
 	s := elastic.NewCompletionSuggester(completionSuggest).
-		Text(term).
+		Prefix(term).
+		FuzzyOptions(
+			elastic.NewFuzzyCompletionSuggesterOptions().
+				EditDistance("AUTO").
+				Transpositions(true).
+				MinLength(fuzzyMinLength).
+				PrefixLength(1).
+				UnicodeAware(true),
+		).
 		Field(completionSuggest).
-		Size(size)
+		SkipDuplicates(true).
+		Size(size * 3)
+
+	// The region is a boost within the language (see langs).
+	// Queries we don't know the language of match everyone.
+	if l, r := contexts(lang, region); l != "" {
+		q := elastic.NewSuggesterCategoryQuery("lang", l, anyLang)
+		if r != "" {
+			q = q.ValueWithBoost(l+"-"+r, regionBoost)
+		}
+		s = s.ContextQuery(q)
+	}
 
 	result, err := e.Client.
 		Search().
@@ -38,17 +60,23 @@ func (e *ElasticSearch) Completion(term string, size int) (Results, error) {
 		return res, err
 	}
 
+	candidates := []candidate{}
 	if item, ok := result.Suggest[completionSuggest]; ok {
 		for _, sug := range item {
 			for _, opt := range sug.Options {
-				res.Suggestions = append(res.Suggestions, opt.Text)
+				// the text is the input that matched. The id is the whole query.
+				candidates = append(candidates, candidate{opt.Id, opt.Score})
 			}
 		}
 	}
 
+	res.Suggestions = rank(term, candidates, size)
 	return res, nil
 }
 
+// regionBoost is how much more we weight queries made in the user's region
+const regionBoost = 2
+
 // Exists checks if a term is already in our index
 func (e *ElasticSearch) Exists(term string) (bool, error) {
 	return e.Client.Exists().
@@ -59,12 +87,18 @@ func (e *ElasticSearch) Exists(term string) (bool, error) {
 }
 
 // Insert adds a new term to our index
-func (e *ElasticSearch) Insert(term string) error {
+func (e *ElasticSearch) Insert(term string, lang language.Tag, region language.Region) error {
+	sf := elastic.NewSuggestField().Input(infixes(term)...).Weight(0)
+
+	l, r := contexts(lang, region)
+	sf = sf.ContextQuery(elastic.NewSuggesterCategoryIndex("lang", langs(l, r)...))
+	if r != "" {
+		sf = sf.ContextQuery(elastic.NewSuggesterCategoryIndex("region", r))
+	}
+
 	q := struct {
 		Completion *elastic.SuggestField `json:"completion_suggest"`
-	}{
-		elastic.NewSuggestField().Input(term).Weight(0),
-	}
+	}{sf}
 
 	_, err := e.Client.Index().
 		Index(e.Index).
@@ -76,18 +110,71 @@ func (e *ElasticSearch) Insert(term string) error {
 	return err
 }
 
-// Increment increments a term in our index
-func (e *ElasticSearch) Increment(term string) error {
+// increment adds one to the weight and any new contexts.
+// Once we know a language of the query it is no longer suggested in every language.
+const increment = `def c = ctx._source.completion_suggest;
+c.weight += 1;
+if (c.contexts == null) {
+	c.contexts = new HashMap();
+}
+for (def p : params.contexts.entrySet()) {
+	def k = p.getKey();
+	if (c.contexts[k] == null) {
+		c.contexts[k] = new ArrayList();
+	} else if (!(c.contexts[k] instanceof List)) {
+		c.contexts[k] = new ArrayList([c.contexts[k]]);
+	}
+	for (def v : p.getValue()) {
+		if (!c.contexts[k].contains(v)) {
+			c.contexts[k].add(v);
+		}
+	}
+}
+if (c.contexts.lang.size() > 1) {
+	c.contexts.lang.removeIf(l -> l == params.any);
+}`
+
+// Increment increments a term in our index.
+// It is suggested in the language and region too.
+func (e *ElasticSearch) Increment(term string, lang language.Tag, region language.Region) error {
+	l, r := contexts(lang, region)
+	ctxs := map[string][]string{"lang": langs(l, r)}
+	if r != "" {
+		ctxs["region"] = []string{r}
+	}
+
 	_, err := e.Client.
 		Update().
 		Index(e.Index).
 		Id(term).
-		Script(elastic.NewScriptInline("ctx._source.completion_suggest.weight += 1")).
+		Script(elastic.NewScriptInline(increment).Param("contexts", ctxs).Param("any", anyLang)).
+		Do(context.TODO())
+
+	return err
+}
+
+// decay scales down the weights, removing the queries that drop below 1
+const decay = `int w = (int)Math.floor(ctx._source.completion_suggest.weight * params.factor);
+if (w < 1) {
+	ctx.op = 'delete';
+} else {
+	ctx._source.completion_suggest.weight = w;
+}`
+
+// Decay scales the weight of every query by factor (e.g. .9) so stale trends fade.
+// Queries that fade away have to be made by enough people again to come back.
+func (e *ElasticSearch) Decay(factor float64) error {
+	_, err := e.Client.UpdateByQuery(e.Index).
+		Query(elastic.NewMatchAllQuery()).
+		Script(elastic.NewScriptInline(decay).Param("factor", factor)).
+		ProceedOnVersionConflict().
 		Do(context.TODO())
 
 	return err
 }
 
+// mapping of the completion index.
+// Existing indices pick up changes with "crawler migrate queries".
 func (e *ElasticSearch) mapping() string {
 	mapping := fmt.Sprintf(`{
 		"mappings": {
@@ -100,8 +187,18 @@ func (e *ElasticSearch) mapping() string {
 						"search_analyzer" : "simple",
 						"preserve_separators": true,
 						"preserve_position_increments": true,
-						"max_input_length": 50
-					}					
+						"max_input_length": 50,
+						"contexts": [
+							{
+								"name": "lang",
+								"type": "category"
+							},
+							{
+								"name": "region",
+								"type": "category"
+							}
+						]
+					}
 				}
 			}
 		}
@@ -114,8 +211,52 @@ func (e *ElasticSearch) mapping() string {
 
 // Setup creates a completion index
 func (e *ElasticSearch) Setup() error {
-	_, err := e.Client.CreateIndex(e.Index).Body(e.mapping()).Do(context.TODO())
-	return err
+	return alias.Create(e.Client, e.Index, e.mapping())
+}
+
+// backfill gives the queries indexed before langs the lang contexts they would have now.
+// Without them they'd never be suggested again.
+const backfill = `def c = ctx._source.completion_suggest;
+if (c.contexts == null) {
+	c.contexts = new HashMap();
+}
+for (def k : ['lang', 'region']) {
+	if (c.contexts[k] == null) {
+		c.contexts[k] = new ArrayList();
+	} else if (!(c.contexts[k] instanceof List)) {
+		c.contexts[k] = new ArrayList([c.contexts[k]]);
+	}
+}
+def langs = c.contexts.lang;
+for (def l : new ArrayList(langs)) {
+	if (l == params.any || l.contains('-')) {
+		continue;
+	}
+	for (def r : c.contexts.region) {
+		if (!langs.contains(l + '-' + r)) {
+			langs.add(l + '-' + r);
+		}
+	}
+}
+if (langs.isEmpty()) {
+	langs.add(params.any);
+}`
+
+// Migrate moves the queries to a new version of the index with the current mapping
+// and backfills the contexts of the older queries.
+func (e *ElasticSearch) Migrate() (string, error) {
+	idx, err := alias.Migrate(e.Client, e.Index, e.mapping())
+	if err != nil {
+		return idx, err
+	}
+
+	_, err = e.Client.UpdateByQuery(idx).
+		Query(elastic.NewMatchAllQuery()).
+		Script(elastic.NewScriptInline(backfill).Param("any", anyLang)).
+		ProceedOnVersionConflict().
+		Do(context.TODO())
+
+	return idx, err
 }
 
 // IndexExists returns true if the index exists
@@ -1,12 +1,15 @@
 package suggest
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
+	"golang.org/x/text/language"
 )
 
 func TestCompletion(t *testing.T) {
@@ -235,7 +238,7 @@ func TestCompletion(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			got, err := e.Completion(c.term, c.size)
+			got, err := e.Completion(c.term, language.English, language.MustParseRegion("US"), c.size)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -247,6 +250,62 @@ func TestCompletion(t *testing.T) {
 	}
 }
 
+func TestCompletionRequest(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if _, err := w.Write([]byte(`{
+			"suggest": {
+				"completion_suggest": [{
+					"text": "dyl",
+					"options": [
+						{"text": "dylan songs", "_id": "bob dylan songs", "_score": 3},
+						{"text": "dylan thomas", "_id": "dylan thomas", "_score": 2}
+					]
+				}]
+			}
+		}`)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Completion("dyl", language.BritishEnglish, language.MustParseRegion("GB"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Results{Suggestions: []string{"dylan thomas", "bob dylan songs"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+
+	for _, part := range []string{
+		`"prefix":"dyl"`,
+		`"fuzzy":{`,
+		`"skip_duplicates":true`,
+		`"size":15`,
+		`{"context":"en"}`,
+		`{"context":"und"}`,
+		`{"boost":2,"context":"en-GB"}`,
+	} {
+		if !strings.Contains(body, part) {
+			t.Errorf("got %v; want it to contain %v", body, part)
+		}
+	}
+
+	// the region only boosts suggestions in the user's language
+	if strings.Contains(body, `"region"`) {
+		t.Errorf("got %v; want no region context", body)
+	}
+}
+
 func TestExists(t *testing.T) {
 	for _, c := range []struct {
 		term   string
@@ -336,7 +395,7 @@ func TestInsert(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if err := e.Insert(c.term); err != nil {
+			if err := e.Insert(c.term, language.English, language.MustParseRegion("US")); err != nil {
 				t.Fatal(err)
 			}
 		})
@@ -380,7 +439,7 @@ func TestIncrement(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if err := e.Increment(c.term); err != nil {
+			if err := e.Increment(c.term, language.English, language.MustParseRegion("US")); err != nil {
 				t.Fatal(err)
 			}
 		})
@@ -446,8 +505,13 @@ func TestSetup(t *testing.T) {
 			defer ts.Close()
 
 			handler = func(w http.ResponseWriter, r *http.Request) {
+				resp := c.resp
+				if strings.HasSuffix(r.URL.Path, "/_alias") {
+					resp = `{"test-queries-v1": {"aliases": {"test-queries": {}}}}`
+				}
+
 				w.WriteHeader(c.status)
-				if _, err := w.Write([]byte(c.resp)); err != nil {
+				if _, err := w.Write([]byte(resp)); err != nil {
 					t.Fatal(err)
 				}
 			}
@@ -464,6 +528,77 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestMigrate(t *testing.T) {
+	var backfilled, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+
+		switch {
+		case r.URL.Path == "/test-queries/_alias":
+			w.Write([]byte(`{"test-queries-v1": {"aliases": {"test-queries": {}}}}`))
+		case r.URL.Path == "/_reindex":
+			w.Write([]byte(`{"created": 10}`))
+		case strings.HasSuffix(r.URL.Path, "/_update_by_query"):
+			backfilled, body = r.URL.Path, string(b)
+			w.Write([]byte(`{"updated": 10}`))
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := e.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idx != "test-queries-v2" {
+		t.Fatalf("got %v; want test-queries-v2", idx)
+	}
+
+	if backfilled != "/test-queries-v2/_update_by_query" {
+		t.Fatalf("got %q; want the new index to be backfilled", backfilled)
+	}
+
+	if !strings.Contains(body, `"params":{"any":"und"}`) {
+		t.Fatalf("got %v; want the catch-all language as a param", body)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	var path, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(b)
+		if _, err := w.Write([]byte(`{"updated": 10, "deleted": 2}`)); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := MockService(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Decay(.9); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/test-queries/_update_by_query" {
+		t.Fatalf("got %v", path)
+	}
+
+	if !strings.Contains(body, `"params":{"factor":0.9}`) {
+		t.Fatalf("got %v; want the factor as a param", body)
+	}
+}
+
 func MockService(url string) (*ElasticSearch, error) {
 	client, err := elastic.NewSimpleClient(elastic.SetURL(url))
 	if err != nil {
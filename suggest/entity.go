@@ -0,0 +1,18 @@
+package suggest
+
+import "golang.org/x/text/language"
+
+// Entity is the person, place or thing a suggestion is about
+// e.g. "Jimi Hendrix", "American guitarist"
+type Entity struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// EntityFetcher finds the entities of suggestions, keyed by the suggestion.
+// Suggestions that aren't about anything in particular are left out.
+type EntityFetcher interface {
+	Entities(suggestions []string, lang language.Tag) (map[string]*Entity, error)
+}
@@ -0,0 +1,105 @@
+package suggest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sightings count the distinct clients that made a query.
+// The clients are opaque tokens so we never know who they are.
+type Sightings interface {
+	Add(q, token string) (int, error)
+}
+
+// Anonymity holds a query back from autocomplete until
+// K distinct clients made it (k-anonymity).
+// A client's token changes every Window so someone making the
+// same query over K windows counts K times, but tokens can't
+// be linked across windows or back to the client.
+type Anonymity struct {
+	Sightings
+	K      int
+	Window time.Duration
+	secret []byte
+}
+
+// NewAnonymity creates an Anonymity with a secret for the tokens.
+// A blank secret is random so tokens only match within this process.
+func NewAnonymity(s Sightings, k int, window time.Duration, secret string) (*Anonymity, error) {
+	a := &Anonymity{
+		Sightings: s,
+		K:         k,
+		Window:    window,
+		secret:    []byte(secret),
+	}
+
+	if secret == "" {
+		a.secret = make([]byte, 32)
+		if _, err := rand.Read(a.secret); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Token is the opaque token of a client (e.g. its ip) in the window of now
+func (a *Anonymity) Token(client string, now time.Time) string {
+	w := now.UTC().Truncate(a.Window).Unix()
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(strconv.FormatInt(w, 10) + "|" + client))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Public records that the client made the query and
+// tells us if enough clients have for it to be suggested
+func (a *Anonymity) Public(q, client string, now time.Time) (bool, error) {
+	n, err := a.Add(q, a.Token(client, now))
+	if err != nil {
+		return false, err
+	}
+
+	return n >= a.K, nil
+}
+
+// Memory keeps sightings in memory
+type Memory struct {
+	sync.Mutex
+	m map[string]map[string]struct{}
+}
+
+// Add records the token for the query and returns the number of distinct tokens
+func (m *Memory) Add(q, token string) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.m == nil {
+		m.m = map[string]map[string]struct{}{}
+	}
+
+	if _, ok := m.m[q]; !ok {
+		m.m[q] = map[string]struct{}{}
+	}
+
+	m.m[q][token] = struct{}{}
+	return len(m.m[q]), nil
+}
+
+var (
+	email  = regexp.MustCompile(`[[:alnum:]._%+-]+@[[:alnum:].-]+\.[[:alpha:]]{2,}`)
+	phone  = regexp.MustCompile(`(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]\d{4}\b|\+\d{1,3}(?:[\s.-]?\d{2,4}){3,}`)
+	digits = regexp.MustCompile(`\d{7,}`)
+)
+
+// PII indicates if a query looks like it has personal information
+// (an email address, phone number or long run of digits like an account number)
+func PII(s string) bool {
+	return email.MatchString(s) || phone.MatchString(s) || digits.MatchString(s)
+}
@@ -0,0 +1,86 @@
+package suggest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPII(t *testing.T) {
+	for _, c := range []struct {
+		q    string
+		want bool
+	}{
+		{"bob dylan", false},
+		{"iphone 11 pro 256gb", false},
+		{"world series 2018 2019 2020", false},
+		{"90210 weather", false},
+		{"jane.doe@example.com", true},
+		{"email jane_doe+news@mail.example.co.uk password", true},
+		{"555-123-4567", true},
+		{"(555) 123 4567 who called", true},
+		{"+44 20 7946 0958", true},
+		{"account 123456789", true},
+	} {
+		t.Run(c.q, func(t *testing.T) {
+			if got := PII(c.q); got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAnonymity(t *testing.T) {
+	a, err := NewAnonymity(&Memory{}, 3, 24*time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2018, 2, 6, 20, 34, 58, 0, time.UTC)
+	later := now.Add(time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+
+	if a.Token("1.2.3.4 firefox", now) != a.Token("1.2.3.4 firefox", later) {
+		t.Fatal("expected the same token within a window")
+	}
+
+	if a.Token("1.2.3.4 firefox", now) == a.Token("1.2.3.4 firefox", tomorrow) {
+		t.Fatal("expected a new token in the next window")
+	}
+
+	for _, c := range []struct {
+		client string
+		now    time.Time
+		want   bool
+	}{
+		{"1.2.3.4 firefox", now, false},
+		{"1.2.3.4 firefox", later, false}, // same person
+		{"5.6.7.8 chrome", now, false},
+		{"1.2.3.4 firefox", tomorrow, true},
+		{"9.9.9.9 safari", now, true},
+	} {
+		got, err := a.Public("my unique query", c.client, c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.want {
+			t.Fatalf("%v at %v: got %v; want %v", c.client, c.now, got, c.want)
+		}
+	}
+}
+
+func TestAnonymitySecret(t *testing.T) {
+	now := time.Date(2018, 2, 6, 20, 34, 58, 0, time.UTC)
+
+	a, _ := NewAnonymity(&Memory{}, 3, time.Hour, "shared")
+	b, _ := NewAnonymity(&Memory{}, 3, time.Hour, "shared")
+	c, _ := NewAnonymity(&Memory{}, 3, time.Hour, "")
+
+	if a.Token("1.2.3.4", now) != b.Token("1.2.3.4", now) {
+		t.Fatal("expected frontends sharing a secret to share tokens")
+	}
+
+	if a.Token("1.2.3.4", now) == c.Token("1.2.3.4", now) {
+		t.Fatal("expected a random secret")
+	}
+}
@@ -0,0 +1,62 @@
+package suggest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Redis counts sightings with a HyperLogLog per query.
+// A HyperLogLog estimates the distinct tokens without storing them.
+type Redis struct {
+	RedisPool *redis.Pool
+	TTL       time.Duration // a query is forgotten if no one makes it for this long
+}
+
+const (
+	sightings = "jivesearch:suggest:sightings:"
+	leases    = "jivesearch:suggest:leases:"
+)
+
+// Add records the token for the query and returns the (estimated) number of distinct tokens
+func (r *Redis) Add(q, token string) (int, error) {
+	c := r.RedisPool.Get()
+	defer c.Close()
+
+	key := sightings + hash(q)
+
+	if _, err := c.Do("PFADD", key, token); err != nil {
+		return 0, err
+	}
+
+	if _, err := c.Do("EXPIRE", key, int(r.TTL/time.Second)); err != nil {
+		return 0, err
+	}
+
+	return redis.Int(c.Do("PFCOUNT", key))
+}
+
+// Lease claims name for d and returns false if someone else holds it.
+// Frontends sharing Redis use it so only one of them does a chore (e.g. Decay).
+func (r *Redis) Lease(name string, d time.Duration) (bool, error) {
+	c := r.RedisPool.Get()
+	defer c.Close()
+
+	_, err := redis.String(c.Do("SET", leases+name, "1", "PX", int64(d/time.Millisecond), "NX"))
+	switch err {
+	case nil:
+		return true, nil
+	case redis.ErrNil:
+		return false, nil
+	}
+
+	return false, err
+}
+
+// hash keeps the queries (which might be personal) out of the key names
+func hash(q string) string {
+	h := sha256.Sum256([]byte(q))
+	return hex.EncodeToString(h[:16])
+}
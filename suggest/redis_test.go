@@ -0,0 +1,79 @@
+package suggest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+)
+
+func TestRedisAdd(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("PFADD", sightings+hash("bob dylan"), "0a1b2c3d").Expect(int64(1))
+	conn.Command("EXPIRE", sightings+hash("bob dylan"), 3600).Expect(int64(1))
+	conn.Command("PFCOUNT", sightings+hash("bob dylan")).Expect(int64(4))
+
+	r := &Redis{
+		RedisPool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		},
+		TTL: time.Hour,
+	}
+	defer r.RedisPool.Close()
+
+	got, err := r.Add("bob dylan", "0a1b2c3d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != 4 {
+		t.Fatalf("got %d; want 4", got)
+	}
+}
+
+func TestRedisLease(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		reply interface{}
+		want  bool
+	}{
+		{"ours", "OK", true},
+		{"taken", nil, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			conn := redigomock.NewConn()
+			conn.Command("SET", leases+"decay", "1", "PX", int64(3600000), "NX").Expect(c.reply)
+
+			r := &Redis{
+				RedisPool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+			}
+			defer r.RedisPool.Close()
+
+			got, err := r.Lease("decay", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != c.want {
+				t.Fatalf("got %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	if got := hash("bob dylan"); got == "bob dylan" || len(got) != 32 {
+		t.Fatalf("got %q; want a hex hash", got)
+	}
+
+	if hash("bob dylan") == hash("bob dylan songs") {
+		t.Fatal("different queries share a key")
+	}
+}
@@ -2,19 +2,47 @@ package suggest
 
 import (
 	ferret "github.com/argusdusty/Ferret"
+	"golang.org/x/text/language"
 )
 
 // Simple is a simple autocomplete suggester
 type Simple struct {
-	db  *ferret.InvertedSuffix
-	all []string
+	db         *ferret.InvertedSuffix
+	all        []string
+	popularity map[string]int
+	contexts   map[string]map[string]bool // the lang contexts of a term (see langs)
 }
 
-// Completion handles autocomplete queries
-func (s *Simple) Completion(term string, size int) (Results, error) {
-	res := Results{}
-	res.Suggestions, _ = s.db.Query(term, size)
-	return res, nil
+// Completion handles autocomplete queries.
+// Terms match anywhere (not just the start) and, if nothing matches,
+// we try again allowing for a typo.
+func (s *Simple) Completion(term string, lang language.Tag, region language.Region, size int) (Results, error) {
+	n := size * 3
+
+	matches, _ := s.db.Query(term, n)
+	if len(matches) == 0 && len(term) >= fuzzyMinLength {
+		matches, _ = s.db.ErrorCorrectingQuery(term, n, func(b []byte) [][]byte {
+			return ferret.ErrorCorrect(b, ferret.LowercaseLetters)
+		})
+	}
+
+	l, r := contexts(lang, region)
+
+	candidates := []candidate{}
+	for _, m := range matches {
+		ctx := s.contexts[m]
+		if l != "" && len(ctx) > 0 && !ctx[l] && !ctx[anyLang] {
+			continue
+		}
+
+		score := float64(s.popularity[m])
+		if r != "" && ctx[l+"-"+r] {
+			score *= regionBoost
+		}
+		candidates = append(candidates, candidate{m, score})
+	}
+
+	return Results{Suggestions: rank(term, candidates, size)}, nil
 }
 
 // Exists checks if a term is already in our index
@@ -30,20 +58,48 @@ func (s *Simple) Exists(term string) (bool, error) {
 }
 
 // Insert adds a new term to our index
-func (s *Simple) Insert(term string) error {
+func (s *Simple) Insert(term string, lang language.Tag, region language.Region) error {
 	s.all = append(s.all, term)
 	s.db.Insert(term, term, []uint64{uint64(len(term))})
+	s.addContexts(term, lang, region)
 	return nil
 }
 
 // Increment increments a term in our index
-func (s *Simple) Increment(term string) error {
+func (s *Simple) Increment(term string, lang language.Tag, region language.Region) error {
+	s.popularity[term]++
+	s.addContexts(term, lang, region)
+	return nil
+}
+
+func (s *Simple) addContexts(term string, lang language.Tag, region language.Region) {
+	if _, ok := s.contexts[term]; !ok {
+		s.contexts[term] = map[string]bool{}
+	}
+
+	l, r := contexts(lang, region)
+	for _, c := range langs(l, r) {
+		s.contexts[term][c] = true
+	}
+
+	if len(s.contexts[term]) > 1 {
+		delete(s.contexts[term], anyLang)
+	}
+}
+
+// Decay scales the popularity of every term by factor
+func (s *Simple) Decay(factor float64) error {
+	for t, p := range s.popularity {
+		s.popularity[t] = int(float64(p) * factor)
+	}
 	return nil
 }
 
 // Setup creates a completion index
 func (s *Simple) Setup() error {
 	s.db = ferret.New([]string{}, []string{}, []interface{}{}, func(s string) []byte { return []byte(s) })
+	s.popularity = map[string]int{}
+	s.contexts = map[string]map[string]bool{}
 	return nil
 }
 
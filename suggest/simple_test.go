@@ -3,6 +3,8 @@ package suggest
 import (
 	"reflect"
 	"testing"
+
+	"golang.org/x/text/language"
 )
 
 func TestSimpleCompletion(t *testing.T) {
@@ -17,7 +19,7 @@ func TestSimpleCompletion(t *testing.T) {
 			terms:  []string{"zebra", "bros", "brad", "bob", "blondie", "brad pitt", "buster"},
 			number: 3,
 			want: Results{
-				Suggestions: []string{"bob", "blondie", "zebra"},
+				Suggestions: []string{"bob", "blondie", "brad"}, // "zebra" only matches within a word
 			},
 		},
 		{
@@ -49,18 +51,18 @@ func TestSimpleCompletion(t *testing.T) {
 				}
 
 				if !exists {
-					if err := ms.Insert(term); err != nil {
+					if err := ms.Insert(term, language.English, language.MustParseRegion("US")); err != nil {
 						t.Fatal(err)
 					}
 				}
 
-				if err := ms.Increment(term); err != nil {
+				if err := ms.Increment(term, language.English, language.MustParseRegion("US")); err != nil {
 					t.Fatal(err)
 				}
 
 			}
 
-			got, err := ms.Completion(c.query, c.number)
+			got, err := ms.Completion(c.query, language.English, language.MustParseRegion("US"), c.number)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -71,3 +73,62 @@ func TestSimpleCompletion(t *testing.T) {
 		})
 	}
 }
+
+func TestSimpleCompletionContext(t *testing.T) {
+	us, gb, fr := language.MustParseRegion("US"), language.MustParseRegion("GB"), language.MustParseRegion("FR")
+
+	ms := &Simple{}
+	if err := ms.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		term   string
+		lang   language.Tag
+		region language.Region
+		times  int
+	}{
+		{"bob dylan", language.English, us, 1},
+		{"bob dylan songs", language.English, us, 5},
+		{"dylan thomas", language.English, us, 1},
+		{"bob dylan chansons", language.French, fr, 9},
+		{"bob dylan tour", language.English, gb, 3},
+		{"bob dylan lyrics", language.Und, fr, 0},
+	} {
+		if err := ms.Insert(c.term, c.lang, c.region); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < c.times; i++ {
+			if err := ms.Increment(c.term, c.lang, c.region); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		q      string
+		lang   language.Tag
+		region language.Region
+		want   []string
+	}{
+		{"popular first", "bob", language.English, us, []string{"bob dylan songs", "bob dylan tour", "bob dylan", "bob dylan lyrics"}},
+		{"region boost", "bob", language.English, gb, []string{"bob dylan tour", "bob dylan songs", "bob dylan", "bob dylan lyrics"}},
+		{"infix", "dyl", language.English, us, []string{"bob dylan songs", "dylan thomas", "bob dylan tour", "bob dylan", "bob dylan lyrics"}},
+		{"typo", "thomsa", language.English, us, []string{"dylan thomas"}},
+		{"other language", "bob", language.French, fr, []string{"bob dylan chansons", "bob dylan lyrics"}},
+		{"same region", "bob", language.German, fr, []string{"bob dylan lyrics"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ms.Completion(c.q, c.lang, c.region, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.Suggestions, c.want) {
+				t.Fatalf("got %q; want %q", got.Suggestions, c.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,11 @@
 package suggest
 
 import (
-	"bufio"
-	"fmt"
-	"log"
+	"math"
+	"sort"
 	"strings"
 
-	"github.com/spf13/afero"
+	"golang.org/x/text/language"
 )
 
 // Suggester outlines methods to fetch & store Autocomplete & PhraseSuggester results
@@ -15,52 +14,118 @@ type Suggester interface {
 	IndexExists() (bool, error)
 	Setup() error
 	Exists(q string) (bool, error)
-	Insert(q string) error
-	Increment(q string) error
-	Completion(q string, size int) (Results, error)
+	Insert(q string, lang language.Tag, region language.Region) error
+	Increment(q string, lang language.Tag, region language.Region) error
+	Decay(factor float64) error
+	Completion(q string, lang language.Tag, region language.Region, size int) (Results, error)
 	//phrase(q string) Results //  TODO: "Did you mean?"
 }
 
 // Results are the results of an autocomplete query
 type Results struct { // remember top-level arrays = no-no in javascript/json
-	Suggestions []string `json:"suggestions"`
+	Suggestions []string           `json:"suggestions"`
+	Entities    map[string]*Entity `json:"entities,omitempty"` // suggestion -> what it is about
 }
 
-var appFs = afero.NewOsFs()
+// contexts are the language and region a query was made in.
+// Suggestions are limited to the user's language and boosted in their region.
+func contexts(lang language.Tag, region language.Region) (string, string) {
+	var l, r string
+	if b, conf := lang.Base(); conf >= language.High {
+		l = b.String()
+	}
 
-// NewNaughty loads our naughty list
-func NewNaughty(fh string) error {
-	file, err := appFs.Open(fh)
-	if err != nil {
-		log.Fatal(err)
+	if region.IsCountry() {
+		r = region.String()
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		wrd := scanner.Text()
-		if strings.HasPrefix(wrd, "#") { // skip the first line
-			continue
-		}
-		naughty[strings.ToLower(wrd)] = struct{}{}
+	return l, r
+}
+
+// anyLang is the language of queries we don't know the language of.
+// They are suggested in every language.
+const anyLang = "und"
+
+// langs are the lang contexts a query is indexed under.
+// The region only counts together with the language (e.g. "en-GB")
+// so the suggestions of other languages don't leak in from the same region.
+func langs(l, r string) []string {
+	if l == "" {
+		return []string{anyLang}
 	}
 
-	if len(naughty) == 0 {
-		return fmt.Errorf("no naughty words")
+	if r == "" {
+		return []string{l}
 	}
 
-	return scanner.Err()
+	return []string{l, l + "-" + r}
 }
 
-var naughty = make(map[string]struct{})
+// infixes let a suggestion match from any of its words,
+// e.g. "dylan songs" and "songs" for "bob dylan songs"
+func infixes(q string) []string {
+	inputs := []string{q}
 
-// Naughty indicates if a word or phrase is NSFW
-func Naughty(s string) bool {
-	for k := range naughty {
-		if strings.Contains(strings.ToLower(s), k) {
-			return true
+	words := strings.Fields(q)
+	for i := 1; i < len(words) && i < maxInfixes; i++ {
+		inputs = append(inputs, strings.Join(words[i:], " "))
+	}
+
+	return inputs
+}
+
+const maxInfixes = 5
+
+// fuzzyMinLength is the shortest query we allow a typo in
+const fuzzyMinLength = 3
+
+// candidate is a possible suggestion and how popular it is
+type candidate struct {
+	text       string
+	popularity float64
+}
+
+// rank orders the candidates by how popular they are and how well they match
+// what has been typed. Completing the query beats completing one of its later
+// words which beats a match within a word or a typo.
+// Ties keep their order.
+func rank(q string, candidates []candidate, size int) []string {
+	score := func(c candidate) float64 {
+		return match(q, c.text) * math.Log2(2+c.popularity)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return score(candidates[i]) > score(candidates[j])
+	})
+
+	suggestions := []string{}
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if len(suggestions) == size {
+			break
+		}
+
+		if seen[c.text] {
+			continue
 		}
+
+		seen[c.text] = true
+		suggestions = append(suggestions, c.text)
 	}
 
-	return false
+	return suggestions
+}
+
+// match is how well a suggestion matches the query
+func match(q, s string) float64 {
+	q, s = strings.ToLower(q), strings.ToLower(s)
+
+	switch {
+	case strings.HasPrefix(s, q):
+		return 1
+	case strings.Contains(s, " "+q):
+		return .6
+	default: // within a word or fuzzy
+		return .3
+	}
 }
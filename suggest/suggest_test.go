@@ -1,77 +1,161 @@
 package suggest
 
 import (
-	"fmt"
-	"io"
-	"os"
+	"encoding/json"
 	"reflect"
 	"testing"
 
-	"github.com/spf13/afero"
+	"golang.org/x/text/language"
 )
 
-func createMockFile(f string) error {
-	appFs = afero.NewMemMapFs()
-
-	fh, err := appFs.OpenFile(f, os.O_CREATE|os.O_APPEND, 0600)
-	if err != nil {
-		return fmt.Errorf("unable to append")
+func TestInfixes(t *testing.T) {
+	for _, c := range []struct {
+		q    string
+		want []string
+	}{
+		{"bob", []string{"bob"}},
+		{"bob dylan songs", []string{"bob dylan songs", "dylan songs", "songs"}},
+		{"a b c d e f g", []string{"a b c d e f g", "b c d e f g", "c d e f g", "d e f g", "e f g"}},
+	} {
+		t.Run(c.q, func(t *testing.T) {
+			if got := infixes(c.q); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
 	}
+}
 
-	wrds := []string{"# a comment that should be skipped", "this is a bad phrase", "naughty", "really bad", "bad phrase"}
-
-	for _, wrd := range wrds {
-		if _, err := io.WriteString(fh, wrd+"\n"); err != nil {
-			return err
-		}
+func TestContexts(t *testing.T) {
+	for _, c := range []struct {
+		lang   language.Tag
+		region language.Region
+		l, r   string
+	}{
+		{language.BritishEnglish, language.MustParseRegion("GB"), "en", "GB"},
+		{language.MustParse("pt-BR"), language.Region{}, "pt", ""},
+		{language.Und, language.MustParseRegion("ZZ"), "", ""},
+	} {
+		t.Run(c.lang.String(), func(t *testing.T) {
+			l, r := contexts(c.lang, c.region)
+			if l != c.l || r != c.r {
+				t.Fatalf("got %q %q; want %q %q", l, r, c.l, c.r)
+			}
+		})
 	}
+}
 
-	fh.Close()
-
-	_, err = appFs.Stat(f)
-	if os.IsNotExist(err) {
-		return fmt.Errorf("file %q does not exist", f)
+func TestLangs(t *testing.T) {
+	for _, c := range []struct {
+		l, r string
+		want []string
+	}{
+		{"en", "GB", []string{"en", "en-GB"}},
+		{"pt", "", []string{"pt"}},
+		{"", "FR", []string{anyLang}},
+	} {
+		t.Run(c.l+c.r, func(t *testing.T) {
+			if got := langs(c.l, c.r); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
+			}
+		})
 	}
-
-	return err
 }
 
-func TestNaughty(t *testing.T) {
+func TestRank(t *testing.T) {
 	for _, c := range []struct {
-		name string
-		want bool
+		name       string
+		q          string
+		candidates []candidate
+		size       int
+		want       []string
 	}{
 		{
-			"this is a safe phrase",
-			false,
+			name: "prefix beats infix",
+			q:    "dyl",
+			candidates: []candidate{
+				{"bob dylan", 1},
+				{"dylan thomas", 1},
+			},
+			size: 10,
+			want: []string{"dylan thomas", "bob dylan"},
+		},
+		{
+			name: "popularity beats a slightly better match",
+			q:    "dyl",
+			candidates: []candidate{
+				{"dylan thomas", 1},
+				{"bob dylan", 1000},
+			},
+			size: 10,
+			want: []string{"bob dylan", "dylan thomas"},
 		},
 		{
-			"naughty",
-			true,
+			name: "typos last",
+			q:    "dyaln",
+			candidates: []candidate{
+				{"dylan", 5},
+				{"bob dyaln fan club", 1},
+			},
+			size: 1,
+			want: []string{"bob dyaln fan club"},
 		},
 		{
-			"the bad bAD pHrase here",
-			true,
+			name: "ties keep their order and duplicates are dropped",
+			q:    "b",
+			candidates: []candidate{
+				{"brad", 1},
+				{"bros", 1},
+				{"brad", 1},
+				{"bob", 1},
+			},
+			size: 10,
+			want: []string{"brad", "bros", "bob"},
 		},
 		{
-			"really",
-			false,
+			name: "none",
+			q:    "q",
+			size: 10,
+			want: []string{},
 		},
 	} {
 		t.Run(c.name, func(t *testing.T) {
-			f := "naughty.txt"
-			if err := createMockFile(f); err != nil {
-				t.Fatal(err)
+			if got := rank(c.q, c.candidates, c.size); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %q; want %q", got, c.want)
 			}
+		})
+	}
+}
 
-			if err := NewNaughty(f); err != nil {
+func TestResultsJSON(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		res  Results
+		want string
+	}{
+		{
+			name: "plain",
+			res:  Results{Suggestions: []string{"jimi hendrix"}},
+			want: `{"suggestions":["jimi hendrix"]}`,
+		},
+		{
+			name: "entities",
+			res: Results{
+				Suggestions: []string{"jimi hendrix", "jimi hendrix songs"},
+				Entities: map[string]*Entity{
+					"jimi hendrix": {ID: "Q5928", Label: "Jimi Hendrix", Description: "American guitarist"},
+				},
+			},
+			want: `{"suggestions":["jimi hendrix","jimi hendrix songs"],"entities":{"jimi hendrix":{"id":"Q5928","label":"Jimi Hendrix","description":"American guitarist"}}}`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := json.Marshal(c.res)
+			if err != nil {
 				t.Fatal(err)
 			}
 
-			got := Naughty(c.name)
-
-			if !reflect.DeepEqual(got, c.want) {
-				t.Errorf("got %t, want %t", got, c.want)
+			if string(b) != c.want {
+				t.Fatalf("got %s; want %s", b, c.want)
 			}
 		})
 	}